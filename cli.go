@@ -6,16 +6,46 @@ import (
 	"strconv"
 	"strings"
 
-	"regit/regit"
+	"regit/re-git"
 )
 
+// builtinCommands are the commands RunCLI handles itself; aliases cannot
+// replace them.
+var builtinCommands = map[string]bool{
+	"init": true, "add": true, "commit": true, "status": true, "log": true,
+	"remove": true, "show": true, "ls-objects": true, "checkout": true,
+	"diff": true, "list-commits": true, "file-history": true, "reset": true,
+	"istracked": true, "get-file-version": true, "commit-files": true,
+	"remove-object": true, "commit-count": true, "find-commit-by-message": true,
+	"find-file-oids": true, "restore-file-from-commit": true,
+	"purge-unreferenced-objects": true, "get-commit-message": true,
+	"get-commit-date": true, "get-commit-oid-for-file": true,
+	"list-all-tracked-files": true, "push": true, "reflog": true,
+	"rev-parse": true, "gc": true, "help": true, "tag": true, "config": true,
+	"verify-commit": true, "verify-tag": true, "gen-signing-key": true,
+	"stash": true, "stash-save": true, "stash-apply": true, "stash-drop": true,
+	"blame": true, "revert": true, "cherry-pick": true, "branch": true,
+	"checkout-branch": true, "rebase": true, "rename": true, "move": true,
+	"show-commit-files": true, "show-commit-diff": true, "bisect": true,
+	"grep": true, "shortlog": true, "stats": true, "archive": true,
+}
+
 func RunCLI() {
 	if len(os.Args) < 2 {
 		fmt.Println("Usage: re-git <command> [args]")
 		return
 	}
-	cmd := os.Args[1]
-	args := os.Args[2:]
+	isBuiltin := func(name string) bool { return builtinCommands[name] }
+	argv, err := regit.ExpandAlias(os.Args[1:], isBuiltin)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if code, ok := regit.RunShellAlias(argv, isBuiltin); ok {
+		os.Exit(code)
+	}
+	cmd := argv[0]
+	args := argv[1:]
 
 	switch cmd {
 	case "init":
@@ -25,22 +55,168 @@ func RunCLI() {
 			regit.Add(file)
 		}
 	case "commit":
-		if len(args) < 1 {
-			fmt.Println("Commit message required")
-			return
+		var opts regit.CommitOptions
+		var messages, paths []string
+		for i := 0; i < len(args); i++ {
+			if v, ok := flagValue(args, &i, "-m", "--message"); ok {
+				messages = append(messages, v)
+			} else if v, ok := flagValue(args, &i, "-F", "--file"); ok {
+				opts.MessageFile = v
+			} else if v, ok := flagValue(args, &i, "--author"); ok {
+				opts.Author = v
+			} else if v, ok := flagValue(args, &i, "--date"); ok {
+				opts.Date = v
+			} else if v, ok := flagValue(args, &i, "--fixup"); ok {
+				opts.Fixup = v
+			} else if v, ok := flagValue(args, &i, "--squash"); ok {
+				opts.Squash = v
+			} else {
+				switch args[i] {
+				case "-S", "--sign":
+					opts.Sign = true
+				case "-n", "--no-verify":
+					opts.NoVerify = true
+				case "-e", "--edit":
+					opts.Edit = true
+				case "--no-edit":
+					opts.NoEdit = true
+				case "-a", "--all":
+					opts.All = true
+				case "--amend":
+					opts.Amend = true
+				case "--":
+					paths = append(paths, args[i+1:]...)
+					i = len(args)
+				default:
+					paths = append(paths, args[i])
+				}
+			}
 		}
-		regit.Commit(strings.Join(args, " "))
+		regit.Commit(strings.Join(messages, "\n\n"), paths, opts)
 	case "status":
 		regit.Status()
 	case "log":
-		regit.Log()
+		var opts regit.LogOptions
+		noDecorate := false
+		for i := 0; i < len(args); i++ {
+			if v, ok := flagValue(args, &i, "-n", "--max-count"); ok {
+				opts.MaxCount, _ = strconv.Atoi(v)
+			} else if v, ok := flagValue(args, &i, "--since", "--after"); ok {
+				opts.Since = v
+			} else if v, ok := flagValue(args, &i, "--until", "--before"); ok {
+				opts.Until = v
+			} else if v, ok := flagValue(args, &i, "--author"); ok {
+				opts.Authors = append(opts.Authors, v)
+			} else if v, ok := flagValue(args, &i, "--grep"); ok {
+				opts.Greps = append(opts.Greps, v)
+			} else if v, ok := flagValue(args, &i, "--format", "--pretty"); ok {
+				opts.Format = v
+			} else if v, ok := flagValue(args, &i, "-L"); ok {
+				opts.LineRanges = append(opts.LineRanges, v)
+			} else if v, ok := flagValue(args, &i, "-S"); ok {
+				opts.Pickaxe = v
+			} else if v, ok := flagValue(args, &i, "-G"); ok {
+				opts.DiffRegex = v
+			} else if n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(args[i], "-"), "n")); err == nil && strings.HasPrefix(args[i], "-") {
+				// -<n> and -n<n>
+				opts.MaxCount = n
+			} else {
+				switch args[i] {
+				case "-i", "--regexp-ignore-case":
+					opts.IgnoreCase = true
+				case "--all-match":
+					opts.AllMatch = true
+				case "--reverse":
+					opts.Reverse = true
+				case "--oneline":
+					opts.Oneline = true
+				case "--show-signature":
+					opts.ShowSignature = true
+				case "--graph":
+					opts.Graph = true
+				case "--all":
+					opts.All = true
+				case "--decorate":
+					opts.Decorate = true
+				case "--no-decorate":
+					noDecorate = true
+				case "--":
+					opts.Paths = append(opts.Paths, args[i+1:]...)
+					i = len(args)
+				default:
+					opts.Revs = append(opts.Revs, args[i])
+				}
+			}
+		}
+		// The graph is hard to follow without knowing which lane is which.
+		opts.Decorate = (opts.Decorate || opts.Graph) && !noDecorate
+		regit.Log(opts)
+	case "shortlog":
+		var opts regit.ShortlogOptions
+		for i := 0; i < len(args); i++ {
+			if v, ok := flagValue(args, &i, "--since", "--after"); ok {
+				opts.Since = v
+			} else if v, ok := flagValue(args, &i, "--until", "--before"); ok {
+				opts.Until = v
+			} else {
+				switch args[i] {
+				case "-s", "--summary":
+					opts.Summary = true
+				case "-n", "--numbered":
+					opts.Numbered = true
+				case "-e", "--email":
+					opts.Email = true
+				case "-sn", "-ns":
+					opts.Summary, opts.Numbered = true, true
+				case "--all":
+					opts.All = true
+				case "--":
+					opts.Paths = append(opts.Paths, args[i+1:]...)
+					i = len(args)
+				default:
+					opts.Revs = append(opts.Revs, args[i])
+				}
+			}
+		}
+		regit.Shortlog(opts)
+	case "stats":
+		opts := regit.StatsOptions{Top: 10}
+		for i := 0; i < len(args); i++ {
+			if v, ok := flagValue(args, &i, "--since", "--after"); ok {
+				opts.Since = v
+			} else if v, ok := flagValue(args, &i, "--until", "--before"); ok {
+				opts.Until = v
+			} else if v, ok := flagValue(args, &i, "--top"); ok {
+				n, err := strconv.Atoi(v)
+				if err != nil || n <= 0 {
+					fmt.Println("Invalid --top:", v)
+					return
+				}
+				opts.Top = n
+			} else {
+				switch args[i] {
+				case "--all":
+					opts.All = true
+				case "--":
+					opts.Paths = append(opts.Paths, args[i+1:]...)
+					i = len(args)
+				default:
+					opts.Revs = append(opts.Revs, args[i])
+				}
+			}
+		}
+		regit.Stats(opts)
 	case "remove":
 		for _, file := range args {
 			regit.Remove(file)
 		}
 	case "show":
-		for _, file := range args {
-			regit.Show(file)
+		for _, arg := range args {
+			if regit.TagExists(arg) {
+				regit.ShowTag(arg)
+			} else {
+				regit.Show(arg)
+			}
 		}
 	case "ls-objects":
 		regit.ListFiles()
@@ -55,7 +231,26 @@ func RunCLI() {
 			regit.FileHistory(file)
 		}
 	case "reset":
-		regit.Reset()
+		mode, rev, paths := "mixed", "HEAD", []string(nil)
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--soft", "--mixed", "--hard", "--keep":
+				mode = strings.TrimPrefix(args[i], "--")
+			case "--":
+				paths = args[i+1:]
+				i = len(args)
+			default:
+				rev = args[i]
+			}
+		}
+		switch {
+		case paths != nil:
+			regit.ResetPaths(rev, paths)
+		case len(args) == 0:
+			regit.Reset()
+		default:
+			regit.ResetTo(rev, mode)
+		}
 	case "istracked":
 		for _, file := range args {
 			fmt.Println(file, regit.IsTracked(file))
@@ -144,26 +339,58 @@ func RunCLI() {
 			fmt.Println(f)
 		}
 	case "push":
+		noVerify := false
+		if len(args) > 0 && args[0] == "--no-verify" {
+			noVerify, args = true, args[1:]
+		}
 		if len(args) < 1 {
-			fmt.Println("Usage: push <remote_path>")
+			fmt.Println("Usage: push [--no-verify] <remote_path>")
 			return
 		}
-		regit.Push(args[0])
+		regit.Push(args[0], noVerify)
+	case "reflog":
+		if len(args) > 0 && args[0] == "show" {
+			args = args[1:]
+		}
+		ref := "HEAD"
+		if len(args) > 0 {
+			ref = args[0]
+		}
+		regit.ReflogShow(ref)
+	case "rev-parse":
+		for _, rev := range args {
+			regit.RevParse(rev)
+		}
+	case "gc":
+		regit.GC()
 	case "help":
+		if len(args) > 0 && !builtinCommands[args[0]] {
+			if !regit.DescribeAlias(args[0]) {
+				fmt.Println("No such command or alias:", args[0])
+			}
+			return
+		}
 		fmt.Println(`Available commands:
 			init
 			add <file>
-			commit "<message>"
+			commit [-m <msg>]... [-F <file>] [-e] [-S] [-n|--no-verify] [--author="Name <email>"] [--date=<date>]
+			       [-a] [--amend [--no-edit]] [--fixup=<rev>|--squash=<rev>] [[--] <path>...]
 			status
-			log
+			log [-n <num>] [--since=<date>] [--until=<date>] [--author=<re>]... [--grep=<re>]... [-i] [--all-match]
+			    [--reverse] [--oneline] [--format=<fmt>] [--show-signature] [--graph] [--all]
+			    [--[no-]decorate] [-S <string>] [-G <regex>] [<rev>...] [-- <path>...]
+			log -L <start>,<end>:<file> | -L :<funcname>:<file> [<rev>]
+			shortlog [-s] [-n] [-e] [--since=<date>] [--until=<date>] [--all] [<rev>...] [-- <path>...]
+			stats [--top=<n>] [--since=<date>] [--until=<date>] [--all] [<rev>...] [-- <path>...]
 			remove <file>
-			show <file>
+			show <file> | <tag>
 			ls-objects
 			checkout
 			diff
 			list-commits
 			file-history <file>
-			reset
+			blame [-L <start>,<end>] [-w] [-M] [-C] [--ignore-rev <rev>]... [--ignore-revs-file <file>] [--porcelain] [<rev>] [--] <file>
+			reset [--soft|--mixed|--hard|--keep] [<rev>] [-- <paths>]
 			istracked <file>
 			get-file-version <file> <commitIdx>
 			commit-files <commitIdx>
@@ -177,41 +404,491 @@ func RunCLI() {
 			get-commit-date <commitIdx>
 			get-commit-oid-for-file <file> <commitIdx>
 			list-all-tracked-files
-			push <remote_path>
+			push [--no-verify] <remote_path>
 			pull <remote_path>
 			clone <remote_path> <target_path>
 			fetch <remote_path>
 			merge <remote_path>
 			merge-to-remote <remote_path>
-			help`)
+			reflog [show] [<ref>]
+			rev-parse <rev>
+			gc
+			revert [--no-commit] <rev>... | --continue | --skip | --abort
+			cherry-pick [-x] [--no-commit] <rev>... | --continue | --skip | --abort
+			branch [<name>]
+			checkout-branch <branch>
+			rebase [-i] [--autosquash] [--exec <cmd>] <upstream> [--onto <newbase>] | --continue | --skip | --abort
+			grep [-i] [-w] [-n] [-l] [-c] [-A <n>] [-B <n>] [-C <n>] [--cached] [-e] <pattern> [<rev>...] [-- <path>...]
+			archive [--format=tar|tar.gz|zip] [--prefix=<dir>/] [-o <file>] [<rev>] [[--] <path>...]
+			bisect start [<bad> [<good>...]] [-- <path>...]
+			bisect good | bad | skip [<rev>...]
+			bisect reset [<commit>] | log | replay <file> | run <cmd> [<arg>...]
+			tag [-a] [-s] [-m <msg>] [-f] <name> [<rev>]
+			tag [-l] [-n[<num>]] [<pattern>...]
+			tag -d <name>...
+			config [--system|--global|--local|--worktree] [--type=bool|int|path] <key> [<value>]
+			config --get | --get-all | --unset | --unset-all <key>
+			config --add <key> <value>
+			config --list [--show-origin]
+			verify-commit <rev>...
+			verify-tag <tag>...
+			gen-signing-key <file>
+			stash [push] [-m <msg>] [-u | -a] [-- <paths>]
+			stash list | clear
+			stash show [-p] [stash@{n}]
+			stash apply | pop [--index] [stash@{n}]
+			stash drop [stash@{n}]
+			help [<alias>]`)
 		return
+	case "tag":
+		list, lines := len(args) == 0, 0
+		var opts regit.TagOptions
+		var rest, deleted []string
+		for i := 0; i < len(args); i++ {
+			arg := args[i]
+			switch {
+			case arg == "-l" || arg == "--list":
+				list = true
+			case arg == "-d" || arg == "--delete":
+				deleted = args[i+1:]
+				i = len(args)
+			case arg == "-a" || arg == "--annotate":
+				opts.Annotate = true
+			case arg == "-s" || arg == "--sign":
+				opts.Sign = true
+			case arg == "-f" || arg == "--force":
+				opts.Force = true
+			case arg == "-m" || arg == "--message":
+				if i+1 < len(args) {
+					opts.Message = args[i+1]
+					i++
+				}
+			case strings.HasPrefix(arg, "-n"):
+				list, lines = true, 1
+				if n, err := strconv.Atoi(arg[2:]); err == nil {
+					lines = n
+				}
+			default:
+				rest = append(rest, arg)
+			}
+		}
+		switch {
+		case deleted != nil:
+			for _, name := range deleted {
+				regit.DeleteTag(name)
+			}
+		case list:
+			regit.ListTags(rest, lines)
+		case len(rest) == 0:
+			fmt.Println("Usage: tag [-a] [-s] [-m <msg>] [-f] <name> [<rev>]")
+		default:
+			rev := ""
+			if len(rest) > 1 {
+				rev = rest[1]
+			}
+			regit.Tag(rest[0], rev, opts)
+		}
+	case "config":
+		var opts regit.ConfigOptions
+		action := ""
+		var rest []string
+		for _, arg := range args {
+			switch arg {
+			case "--system", "--global", "--local", "--worktree":
+				opts.Scope = strings.TrimPrefix(arg, "--")
+			case "--bool", "--int", "--path":
+				opts.Type = strings.TrimPrefix(arg, "--")
+			case "--show-origin":
+				opts.ShowOrigin = true
+			case "--get", "--get-all", "--unset", "--unset-all", "--add", "--list", "-l":
+				action = arg
+			default:
+				if strings.HasPrefix(arg, "--type=") {
+					opts.Type = strings.TrimPrefix(arg, "--type=")
+				} else {
+					rest = append(rest, arg)
+				}
+			}
+		}
+		if action == "" {
+			action = "--get"
+			if len(rest) == 2 {
+				action = "--set"
+			}
+		}
+		ok := true
+		switch {
+		case action == "--list" || action == "-l":
+			regit.ConfigList(opts)
+		case action == "--get" && len(rest) == 1:
+			ok = regit.ConfigGet(rest[0], opts)
+		case action == "--get-all" && len(rest) == 1:
+			ok = regit.ConfigGetAll(rest[0], opts)
+		case (action == "--set" || action == "--add") && len(rest) == 2:
+			ok = regit.ConfigSet(rest[0], rest[1], action == "--add", opts)
+		case (action == "--unset" || action == "--unset-all") && len(rest) == 1:
+			ok = regit.ConfigUnset(rest[0], action == "--unset-all", opts)
+		default:
+			fmt.Println("Usage: config [<scope>] [--type=<type>] <key> [<value>] | --get | --get-all | --add | --unset | --unset-all | --list [--show-origin]")
+			ok = false
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	case "verify-commit", "verify-tag":
+		if len(args) == 0 {
+			fmt.Printf("Usage: %s <name>...\n", cmd)
+			return
+		}
+		ok := true
+		for _, name := range args {
+			if cmd == "verify-commit" {
+				ok = regit.VerifyCommit(name) && ok
+			} else {
+				ok = regit.VerifyTag(name) && ok
+			}
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	case "gen-signing-key":
+		if len(args) < 1 {
+			fmt.Println("Usage: gen-signing-key <file>")
+			return
+		}
+		regit.GenerateSigningKey(args[0])
+	case "stash":
+		sub := "push"
+		if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+			sub, args = args[0], args[1:]
+		}
+		rev, restoreIndex, patch := "", false, false
+		switch sub {
+		case "push", "save":
+			message, includeUntracked, includeIgnored := "", false, false
+			var paths []string
+			for i := 0; i < len(args); i++ {
+				switch args[i] {
+				case "-m", "--message":
+					if i+1 < len(args) {
+						message = args[i+1]
+						i++
+					}
+				case "-u", "--include-untracked":
+					includeUntracked = true
+				case "-a", "--all":
+					includeUntracked, includeIgnored = true, true
+				case "--":
+					paths = append(paths, args[i+1:]...)
+					i = len(args)
+				default:
+					if sub == "save" {
+						message = strings.Join(args[i:], " ")
+						i = len(args)
+					} else {
+						paths = append(paths, args[i])
+					}
+				}
+			}
+			regit.StashPush(message, paths, includeUntracked, includeIgnored)
+			return
+		case "list":
+			regit.StashList()
+			return
+		case "clear":
+			regit.StashClear()
+			return
+		}
+		for _, arg := range args {
+			switch arg {
+			case "--index":
+				restoreIndex = true
+			case "-p", "--patch":
+				patch = true
+			default:
+				rev = arg
+			}
+		}
+		switch sub {
+		case "show":
+			regit.StashShow(rev, patch)
+		case "apply":
+			regit.StashApply(rev, restoreIndex)
+		case "pop":
+			regit.StashPop(rev, restoreIndex)
+		case "drop":
+			regit.StashDrop(rev)
+		default:
+			fmt.Println("Unknown stash subcommand:", sub)
+		}
 	case "stash-save":
-		regit.StashSave()
+		regit.StashPush("", nil, false, false)
 	case "stash-apply":
-		regit.StashApply()
+		regit.StashApply("", false)
 	case "stash-drop":
-		regit.StashDrop()
+		regit.StashDrop("")
 	case "blame":
-		for _, file := range args {
-			regit.Blame(file)
+		var opts regit.BlameOptions
+		var rest []string
+		for i := 0; i < len(args); i++ {
+			v, ok := flagValue(args, &i, "-L")
+			if !ok && strings.HasPrefix(args[i], "-L") {
+				v, ok = args[i][2:], true
+			}
+			if ok {
+				start, end, err := regit.ParseLineRange(v)
+				if err != nil {
+					fmt.Println(err)
+					return
+				}
+				opts.Start, opts.End = start, end
+			} else if v, ok := flagValue(args, &i, "--ignore-rev"); ok {
+				opts.IgnoreRevs = append(opts.IgnoreRevs, v)
+			} else if v, ok := flagValue(args, &i, "--ignore-revs-file"); ok {
+				opts.IgnoreRevsFile = v
+			} else {
+				switch args[i] {
+				case "--porcelain", "-p":
+					opts.Porcelain = true
+				case "-w":
+					opts.IgnoreWhitespace = true
+				case "-M":
+					opts.Moves = true
+				case "-C":
+					opts.Copies = true
+				case "--":
+					rest = append(rest, args[i+1:]...)
+					i = len(args)
+				default:
+					rest = append(rest, args[i])
+				}
+			}
+		}
+		switch len(rest) {
+		case 1:
+			regit.Blame("", rest[0], opts)
+		case 2:
+			regit.Blame(rest[0], rest[1], opts)
+		default:
+			fmt.Println("Usage: blame [-L <start>,<end>] [-w] [-M] [-C] [--ignore-rev <rev>]... [--ignore-revs-file <file>] [--porcelain] [<rev>] [--] <file>")
 		}
-	case "revert":
+	case "revert", "cherry-pick":
+		noCommit, recordOrigin := false, false
+		var revs []string
 		for _, arg := range args {
-			idx, err := strconv.Atoi(arg)
-			if err != nil {
-				fmt.Println("Invalid commit index")
-				continue
+			switch arg {
+			case "--continue":
+				regit.SequencerContinue()
+				return
+			case "--skip":
+				regit.SequencerSkip()
+				return
+			case "--abort":
+				regit.SequencerAbort()
+				return
+			case "--no-commit", "-n":
+				noCommit = true
+			case "-x":
+				recordOrigin = true
+			default:
+				revs = append(revs, arg)
 			}
-			regit.Revert(idx)
 		}
-	case "cherry-pick":
-		for _, arg := range args {
-			idx, err := strconv.Atoi(arg)
-			if err != nil {
-				fmt.Println("Invalid commit index")
-				continue
+		if len(revs) == 0 {
+			fmt.Printf("Usage: %s [--no-commit] <rev>... | --continue | --skip | --abort\n", cmd)
+			return
+		}
+		if cmd == "revert" {
+			regit.Revert(revs, noCommit)
+		} else {
+			regit.CherryPick(revs, noCommit, recordOrigin)
+		}
+	case "branch":
+		if len(args) == 0 {
+			regit.ListBranches()
+			return
+		}
+		regit.CreateBranch(args[0])
+	case "checkout-branch":
+		if len(args) < 1 {
+			fmt.Println("Usage: checkout-branch <branch>")
+			return
+		}
+		regit.CheckoutBranch(args[0])
+	case "rebase":
+		var upstream, onto string
+		var opts regit.RebaseOptions
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--continue":
+				regit.SequencerContinue()
+				return
+			case "--skip":
+				regit.SequencerSkip()
+				return
+			case "--abort":
+				regit.SequencerAbort()
+				return
+			case "--onto":
+				if i+1 < len(args) {
+					i++
+					onto = args[i]
+				}
+			case "-i", "--interactive":
+				opts.Interactive = true
+			case "--autosquash":
+				opts.Autosquash = true
+			case "--exec":
+				if i+1 < len(args) {
+					i++
+					opts.Exec = args[i]
+				}
+			default:
+				upstream = args[i]
 			}
-			regit.CherryPick(idx)
+		}
+		if upstream == "" {
+			fmt.Println("Usage: rebase [-i] [--autosquash] [--exec <cmd>] <upstream> [--onto <newbase>] | --continue | --skip | --abort")
+			return
+		}
+		regit.Rebase(upstream, onto, opts)
+	case "grep":
+		var opts regit.GrepOptions
+		var rest []string
+		pattern, havePattern := "", false
+		for i := 0; i < len(args); i++ {
+			// -A, -B and -C take a count, also attached as in -C3.
+			flag, v, ok := "", "", false
+			for _, names := range [][]string{{"-A", "--after-context"}, {"-B", "--before-context"}, {"-C", "--context"}} {
+				if v, ok = flagValue(args, &i, names...); !ok && len(args[i]) > 2 && strings.HasPrefix(args[i], names[0]) {
+					v, ok = args[i][2:], true
+				}
+				if ok {
+					flag = names[0]
+					break
+				}
+			}
+			if ok {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					fmt.Println("Invalid number of context lines:", v)
+					return
+				}
+				switch flag {
+				case "-A":
+					opts.After = n
+				case "-B":
+					opts.Before = n
+				default:
+					opts.Before, opts.After = n, n
+				}
+			} else if v, ok := flagValue(args, &i, "-e"); ok {
+				pattern, havePattern = v, true
+			} else {
+				switch args[i] {
+				case "-i", "--ignore-case":
+					opts.IgnoreCase = true
+				case "-w", "--word-regexp":
+					opts.WordRegexp = true
+				case "-n", "--line-number":
+					opts.LineNumber = true
+				case "-l", "--files-with-matches":
+					opts.FilesWithMatches = true
+				case "-c", "--count":
+					opts.Count = true
+				case "--cached":
+					opts.Cached = true
+				case "--":
+					opts.Paths = append(opts.Paths, args[i+1:]...)
+					i = len(args)
+				default:
+					if !havePattern {
+						pattern, havePattern = args[i], true
+					} else {
+						rest = append(rest, args[i])
+					}
+				}
+			}
+		}
+		if !havePattern {
+			fmt.Println("Usage: grep [-i] [-w] [-n] [-l] [-c] [-A <n>] [-B <n>] [-C <n>] [--cached] [-e] <pattern> [<rev>...] [-- <path>...]")
+			return
+		}
+		opts.Revs = rest
+		if !regit.Grep(pattern, opts) {
+			os.Exit(1)
+		}
+	case "archive":
+		var opts regit.ArchiveOptions
+		var rest []string
+		for i := 0; i < len(args); i++ {
+			if v, ok := flagValue(args, &i, "--format"); ok {
+				opts.Format = v
+			} else if v, ok := flagValue(args, &i, "--prefix"); ok {
+				opts.Prefix = v
+			} else if v, ok := flagValue(args, &i, "-o", "--output"); ok {
+				opts.Output = v
+			} else if args[i] == "--" {
+				if len(rest) == 0 {
+					rest = append(rest, "")
+				}
+				rest = append(rest, args[i+1:]...)
+				i = len(args)
+			} else {
+				rest = append(rest, args[i])
+			}
+		}
+		rev := ""
+		if len(rest) > 0 {
+			rev, rest = rest[0], rest[1:]
+		}
+		regit.Archive(rev, rest, opts)
+	case "bisect":
+		usage := "Usage: bisect start [<bad> [<good>...]] [-- <path>...] | good | bad | skip [<rev>...] | reset [<commit>] | log | replay <file> | run <cmd> [<arg>...]"
+		if len(args) == 0 {
+			fmt.Println(usage)
+			return
+		}
+		rest := args[1:]
+		switch args[0] {
+		case "start":
+			var revs, paths []string
+			for i, arg := range rest {
+				if arg == "--" {
+					paths = rest[i+1:]
+					break
+				}
+				revs = append(revs, arg)
+			}
+			bad := ""
+			if len(revs) > 0 {
+				bad, revs = revs[0], revs[1:]
+			}
+			regit.BisectStart(bad, revs, paths)
+		case "good", "bad", "skip":
+			regit.BisectMark(args[0], rest)
+		case "reset":
+			commit := ""
+			if len(rest) > 0 {
+				commit = rest[0]
+			}
+			regit.BisectReset(commit)
+		case "log":
+			regit.BisectLog()
+		case "replay":
+			if len(rest) < 1 {
+				fmt.Println("Usage: bisect replay <file>")
+				return
+			}
+			regit.BisectReplay(rest[0])
+		case "run":
+			if len(rest) < 1 {
+				fmt.Println("Usage: bisect run <cmd> [<arg>...]")
+				return
+			}
+			regit.BisectRun(rest)
+		default:
+			fmt.Println(usage)
 		}
 	case "rename":
 		if len(args) < 2 {
@@ -250,3 +927,18 @@ func RunCLI() {
 		fmt.Println("Unknown command:", cmd)
 	}
 }
+
+// flagValue returns the value of a flag given as "<name>=<value>" or as
+// "<name> <value>", moving *i past a separate value.
+func flagValue(args []string, i *int, names ...string) (string, bool) {
+	for _, name := range names {
+		if args[*i] == name && *i+1 < len(args) {
+			*i++
+			return args[*i], true
+		}
+		if strings.HasPrefix(args[*i], name+"=") {
+			return strings.TrimPrefix(args[*i], name+"="), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,132 @@
+package regit
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// An alias.<name> config entry defines a new command. Its value is either
+// a command line for another re-git command, to which the alias's
+// arguments are appended, or a shell command prefixed with "!" that gets
+// them as "$@". Aliases never shadow built-in commands.
+
+func lookupAlias(name string) (string, bool) {
+	values := configValues("alias."+name, "")
+	if len(values) == 0 || strings.Contains(name, ".") {
+		return "", false
+	}
+	return values[len(values)-1].Value, true
+}
+
+// splitWords splits an alias value into words, honouring single and double
+// quotes and backslash escapes.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, quote := false, byte(0)
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote == '\'' && ch != '\'':
+			word.WriteByte(ch)
+		case ch == '\\' && quote != '\'' && i+1 < len(s):
+			i++
+			word.WriteByte(s[i])
+			inWord = true
+		case ch == '\'' || ch == '"':
+			if quote == 0 {
+				quote, inWord = ch, true
+			} else if quote == ch {
+				quote = 0
+			} else {
+				word.WriteByte(ch)
+			}
+		case quote == 0 && (ch == ' ' || ch == '\t' || ch == '\n'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(ch)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed quote in %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// ExpandAlias replaces a leading alias in argv by its expansion, repeating
+// while the result starts with another alias. It stops at a built-in
+// command, a shell alias or a name that is neither.
+func ExpandAlias(argv []string, isBuiltin func(string) bool) ([]string, error) {
+	var chain []string
+	for len(argv) > 0 && !isBuiltin(argv[0]) {
+		value, ok := lookupAlias(argv[0])
+		if !ok || strings.HasPrefix(value, "!") {
+			break
+		}
+		for _, name := range chain {
+			if name == argv[0] {
+				return nil, fmt.Errorf("recursive alias: %s", strings.Join(append(chain, argv[0]), " -> "))
+			}
+		}
+		chain = append(chain, argv[0])
+		words, err := splitWords(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad alias.%s: %v", argv[0], err)
+		}
+		if len(words) == 0 {
+			return nil, fmt.Errorf("empty alias for %s", argv[0])
+		}
+		argv = append(words, argv[1:]...)
+	}
+	return argv, nil
+}
+
+// RunShellAlias runs argv[0] if it is a "!" alias, passing the remaining
+// arguments to the shell command. It reports the command's exit status and
+// whether argv named a shell alias at all; as with ExpandAlias, aliases
+// named after builtin commands are ignored.
+func RunShellAlias(argv []string, isBuiltin func(string) bool) (int, bool) {
+	if len(argv) == 0 || isBuiltin(argv[0]) {
+		return 0, false
+	}
+	value, ok := lookupAlias(argv[0])
+	if !ok || !strings.HasPrefix(value, "!") {
+		return 0, false
+	}
+	script := strings.TrimPrefix(value, "!")
+	if len(argv) > 1 {
+		script += ` "$@"`
+	}
+	cmd := exec.Command("sh", append([]string{"-c", script, argv[0]}, argv[1:]...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			return exit.ExitCode(), true
+		}
+		fmt.Printf("Error running alias %s: %v\n", argv[0], err)
+		return 1, true
+	}
+	return 0, true
+}
+
+// DescribeAlias prints what an alias expands to, reporting whether name is
+// an alias.
+func DescribeAlias(name string) bool {
+	value, ok := lookupAlias(name)
+	if ok {
+		fmt.Printf("'%s' is aliased to '%s'\n", name, value)
+	}
+	return ok
+}
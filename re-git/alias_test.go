@@ -0,0 +1,129 @@
+package regit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "log --oneline -n 5", want: []string{"log", "--oneline", "-n", "5"}},
+		{in: "  spaced\t\tout \n", want: []string{"spaced", "out"}},
+		{in: `commit -m "two words"`, want: []string{"commit", "-m", "two words"}},
+		{in: `grep 'a "b" c'`, want: []string{"grep", `a "b" c`}},
+		{in: `grep "it's"`, want: []string{"grep", "it's"}},
+		{in: `a\ b c`, want: []string{"a b", "c"}},
+		{in: `'back\slash'`, want: []string{`back\slash`}},
+		{in: `"esc\"aped"`, want: []string{`esc"aped`}},
+		{in: `x"y"z`, want: []string{"xyz"}},
+		{in: `""`, want: []string{""}},
+		{in: "", want: nil},
+		{in: `log "open`, wantErr: true},
+		{in: `log 'open`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := splitWords(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitWords(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitWords(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandAlias(t *testing.T) {
+	testRepo(t)
+	captureOutput(t, func() {
+		ConfigSet("alias.lg", "log --oneline", false, ConfigOptions{})
+		ConfigSet("alias.last", "lg -n 1", false, ConfigOptions{})
+		ConfigSet("alias.ci", `commit -m "quick fix"`, false, ConfigOptions{})
+		ConfigSet("alias.status", "log", false, ConfigOptions{})
+		ConfigSet("alias.loop1", "loop2 x", false, ConfigOptions{})
+		ConfigSet("alias.loop2", "loop1 y", false, ConfigOptions{})
+		ConfigSet("alias.sh", "!echo hi", false, ConfigOptions{})
+		ConfigSet("alias.bad", `log "open`, false, ConfigOptions{})
+		ConfigSet("alias.empty", "", false, ConfigOptions{})
+	})
+	builtin := func(name string) bool { return name == "log" || name == "commit" || name == "status" }
+
+	tests := []struct {
+		name    string
+		argv    []string
+		want    []string
+		wantErr string
+	}{
+		{name: "no args", argv: nil, want: nil},
+		{name: "builtin", argv: []string{"log", "-n", "2"}, want: []string{"log", "-n", "2"}},
+		{name: "simple", argv: []string{"lg", "main"}, want: []string{"log", "--oneline", "main"}},
+		{name: "chained", argv: []string{"last", "main"}, want: []string{"log", "--oneline", "-n", "1", "main"}},
+		{name: "quoted", argv: []string{"ci"}, want: []string{"commit", "-m", "quick fix"}},
+		{name: "builtins win", argv: []string{"status"}, want: []string{"status"}},
+		{name: "shell alias", argv: []string{"sh", "x"}, want: []string{"sh", "x"}},
+		{name: "unknown", argv: []string{"nope"}, want: []string{"nope"}},
+		{name: "recursive", argv: []string{"loop1"}, wantErr: "recursive alias: loop1 -> loop2 -> loop1"},
+		{name: "bad quoting", argv: []string{"bad"}, wantErr: `bad alias.bad: unclosed quote in "log \"open"`},
+		{name: "empty", argv: []string{"empty"}, wantErr: "empty alias for empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandAlias(tt.argv, builtin)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandAlias(%q) = %q, %v, want %q", tt.argv, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunShellAlias(t *testing.T) {
+	testRepo(t)
+	captureOutput(t, func() {
+		ConfigSet("alias.hi", "!echo hello", false, ConfigOptions{})
+		ConfigSet("alias.args", `!f() { echo "[$1]" "[$2]"; }; f`, false, ConfigOptions{})
+		ConfigSet("alias.fail", "!exit 3", false, ConfigOptions{})
+		ConfigSet("alias.lg", "log --oneline", false, ConfigOptions{})
+		ConfigSet("alias.log", "!echo shadowed", false, ConfigOptions{})
+	})
+	builtin := func(name string) bool { return name == "log" }
+
+	tests := []struct {
+		name     string
+		argv     []string
+		wantOut  string
+		wantCode int
+		wantRan  bool
+	}{
+		{name: "no arguments", argv: []string{"hi"}, wantOut: "hello\n", wantRan: true},
+		{name: "arguments appended", argv: []string{"hi", "there"}, wantOut: "hello there\n", wantRan: true},
+		{name: "arguments with spaces", argv: []string{"args", "a b", "c"}, wantOut: "[a b] [c]\n", wantRan: true},
+		{name: "exit status", argv: []string{"fail"}, wantCode: 3, wantRan: true},
+		{name: "not a shell alias", argv: []string{"lg"}},
+		{name: "not an alias", argv: []string{"nope"}},
+		{name: "builtins win", argv: []string{"log"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code int
+			var ran bool
+			out := captureOutput(t, func() { code, ran = RunShellAlias(tt.argv, builtin) })
+			if out != tt.wantOut || code != tt.wantCode || ran != tt.wantRan {
+				t.Errorf("RunShellAlias(%q) = %d, %v printing %q, want %d, %v printing %q", tt.argv, code, ran, out, tt.wantCode, tt.wantRan, tt.wantOut)
+			}
+		})
+	}
+
+	if out := captureOutput(t, func() { DescribeAlias("lg") }); out != "'lg' is aliased to 'log --oneline'\n" {
+		t.Errorf("DescribeAlias printed %q", out)
+	}
+}
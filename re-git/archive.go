@@ -0,0 +1,277 @@
+package regit
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArchiveOptions controls Archive. Format is "tar", "tar.gz" (or "tgz") or
+// "zip"; when empty it follows Output's extension, defaulting to tar.
+// Prefix is put in front of every path, so "name/" puts everything in a
+// directory. Without Output the archive goes to standard output.
+type ArchiveOptions struct {
+	Format string
+	Prefix string
+	Output string
+}
+
+// attrRule is a line of a .gitattributes file: a pattern followed by the
+// attributes it sets ("attr", giving "set"), unsets ("-attr", "unset"),
+// gives a value ("attr=value") or leaves unspecified ("!attr", "").
+type attrRule struct {
+	pattern ignorePattern
+	attrs   map[string]string
+}
+
+func parseAttributes(data []byte, base string) []attrRule {
+	var rules []attrRule
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		// Negative patterns are not allowed in attribute files.
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "!") {
+			continue
+		}
+		p, ok := parsePattern(fields[0], base)
+		if !ok {
+			continue
+		}
+		r := attrRule{pattern: p, attrs: map[string]string{}}
+		for _, attr := range fields[1:] {
+			switch {
+			case strings.HasPrefix(attr, "-"):
+				r.attrs[attr[1:]] = "unset"
+			case strings.HasPrefix(attr, "!"):
+				r.attrs[attr[1:]] = ""
+			case strings.Contains(attr, "="):
+				name, value, _ := strings.Cut(attr, "=")
+				r.attrs[name] = value
+			default:
+				r.attrs[attr] = "set"
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// attributeValue is the state of attr for name, from the last rule that
+// matches name and mentions attr.
+func attributeValue(rules []attrRule, name, attr string, isDir bool) string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if v, ok := rules[i].attrs[attr]; ok && rules[i].pattern.matches(name, isDir) {
+			return v
+		}
+	}
+	return ""
+}
+
+// treeAttributes collects the attribute rules that apply to a tree: those
+// of the .gitattributes files in it, deeper ones taking precedence, then
+// .git/info/attributes over all of them.
+func treeAttributes(tree map[string]string) []attrRule {
+	var files []string
+	for f := range tree {
+		if filepath.Base(f) == ".gitattributes" {
+			files = append(files, f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return strings.Count(files[i], "/") < strings.Count(files[j], "/")
+	})
+	var rules []attrRule
+	for _, f := range files {
+		data, _ := readObject(tree[f])
+		base := filepath.ToSlash(filepath.Dir(f))
+		if base == "." {
+			base = ""
+		}
+		rules = append(rules, parseAttributes(data, base)...)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(repoDir, "info", "attributes"))
+	return append(rules, parseAttributes(data, "")...)
+}
+
+// exportIgnored reports whether file, or a directory it is in, has the
+// export-ignore attribute.
+func exportIgnored(rules []attrRule, file string) bool {
+	for i, c := range file {
+		if c == '/' && attributeValue(rules, file[:i], "export-ignore", true) == "set" {
+			return true
+		}
+	}
+	return attributeValue(rules, file, "export-ignore", false) == "set"
+}
+
+// archiveEntry is a file or, with Dir set, a directory in an archive.
+type archiveEntry struct {
+	Name string
+	Dir  bool
+	Data []byte
+}
+
+// Files are archived with the mode checkout gives them, as the object
+// store does not record modes.
+const (
+	archiveFileMode = 0644
+	archiveDirMode  = 0755
+)
+
+func writeTar(w io.Writer, entries []archiveEntry, id string, mtime time.Time) error {
+	tw := tar.NewWriter(w)
+	// Like git, record the commit in a global header for get-tar-commit-id.
+	global := &tar.Header{Typeflag: tar.TypeXGlobalHeader, PAXRecords: map[string]string{"comment": id}}
+	if err := tw.WriteHeader(global); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.Name, Mode: archiveFileMode, Size: int64(len(e.Data)), ModTime: mtime, Typeflag: tar.TypeReg}
+		if e.Dir {
+			hdr.Mode, hdr.Typeflag = archiveDirMode, tar.TypeDir
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(e.Data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeZip(w io.Writer, entries []archiveEntry, id string, mtime time.Time) error {
+	zw := zip.NewWriter(w)
+	if err := zw.SetComment(id); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.Name, Method: zip.Deflate, Modified: mtime}
+		hdr.SetMode(archiveFileMode)
+		if e.Dir {
+			hdr.Method = zip.Store
+			hdr.SetMode(os.ModeDir | archiveDirMode)
+		}
+		f, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(e.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// archiveFormat settles the format to write, guessing from the name of
+// the output file when none is given.
+func archiveFormat(format, output string) (string, error) {
+	if format == "" {
+		switch {
+		case strings.HasSuffix(output, ".zip"):
+			format = "zip"
+		case strings.HasSuffix(output, ".tar.gz"), strings.HasSuffix(output, ".tgz"):
+			format = "tar.gz"
+		default:
+			format = "tar"
+		}
+	}
+	switch format {
+	case "tar", "zip", "tar.gz":
+		return format, nil
+	case "tgz":
+		return "tar.gz", nil
+	}
+	return "", fmt.Errorf("unknown archive format '%s'", format)
+}
+
+// Archive writes the files of rev (HEAD if empty), or those under paths,
+// as a tar, gzipped tar or zip archive. Every entry gets the commit date as
+// its modification time, and files with the export-ignore attribute are
+// left out. Errors go to stderr so they never end up inside an archive
+// written to stdout.
+func Archive(rev string, paths []string, opts ArchiveOptions) {
+	format, err := archiveFormat(opts.Format, opts.Output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	id, err := resolveRevision(rev)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	c, ok := loadCommit(id)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Commit not found:", id)
+		return
+	}
+	mtime := commitTime(c)
+	rules := treeAttributes(c.Files)
+	match := pathMatcher(paths)
+
+	var entries []archiveEntry
+	dirs := map[string]bool{}
+	for _, f := range sortedKeys(c.Files) {
+		if !match(f) || exportIgnored(rules, f) {
+			continue
+		}
+		data, err := readObject(c.Files[f])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Missing object %s for %s\n", c.Files[f], f)
+			return
+		}
+		name := opts.Prefix + f
+		for i, ch := range name {
+			if ch == '/' && !dirs[name[:i+1]] {
+				dirs[name[:i+1]] = true
+				entries = append(entries, archiveEntry{Name: name[:i+1], Dir: true})
+			}
+		}
+		entries = append(entries, archiveEntry{Name: name, Data: data})
+	}
+	if len(paths) > 0 && len(entries) == 0 {
+		fmt.Fprintf(os.Stderr, "pathspec '%s' did not match any files\n", strings.Join(paths, " "))
+		return
+	}
+
+	out := os.Stdout
+	if opts.Output != "" {
+		if out, err = os.Create(opts.Output); err != nil {
+			fmt.Fprintln(os.Stderr, "Error creating archive:", err)
+			return
+		}
+	}
+	switch format {
+	case "zip":
+		err = writeZip(out, entries, id, mtime)
+	case "tar.gz":
+		gz := gzip.NewWriter(out)
+		if err = writeTar(gz, entries, id, mtime); err == nil {
+			err = gz.Close()
+		}
+	default:
+		err = writeTar(out, entries, id, mtime)
+	}
+	if opts.Output != "" {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing archive:", err)
+		if opts.Output != "" {
+			os.Remove(opts.Output)
+		}
+	}
+}
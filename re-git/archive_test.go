@@ -0,0 +1,302 @@
+package regit
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAttributes(t *testing.T) {
+	data := "# comment\n" +
+		"*.bin binary -diff !merge eol=lf\n" +
+		"\n" +
+		"!negated export-ignore\n" +
+		"lonely\n" +
+		"  docs/   export-ignore  \n"
+	rules := parseAttributes([]byte(data), "sub")
+	type rule struct {
+		re       string
+		base     string
+		anchored bool
+		dirOnly  bool
+		attrs    map[string]string
+	}
+	want := []rule{
+		{`^[^/]*\.bin$`, "sub", false, false, map[string]string{"binary": "set", "diff": "unset", "merge": "", "eol": "lf"}},
+		{`^docs$`, "sub", false, true, map[string]string{"export-ignore": "set"}},
+	}
+	var got []rule
+	for _, r := range rules {
+		p := r.pattern
+		got = append(got, rule{p.re.String(), p.base, p.anchored, p.dirOnly, r.attrs})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAttributes = %+v, want %+v", got, want)
+	}
+}
+
+func TestExportIgnored(t *testing.T) {
+	rules := parseAttributes([]byte(
+		"*.secret export-ignore\n"+
+			"internal export-ignore\n"+
+			"keep.secret -export-ignore\n"+
+			"tools/ export-ignore\n"+
+			"/top.txt export-ignore\n"+
+			"maybe.secret !export-ignore\n"+
+			"valued.txt export-ignore=yes\n"), "")
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"a.secret", true},
+		{"deep/dir/a.secret", true},
+		{"keep.secret", false},
+		{"maybe.secret", false},
+		{"internal", true},
+		{"internal/notes.md", true},
+		{"src/internal/x.go", true},
+		{"tools/build.sh", true},
+		{"tools", false},
+		{"top.txt", true},
+		{"sub/top.txt", false},
+		{"valued.txt", false},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		if got := exportIgnored(rules, tt.file); got != tt.want {
+			t.Errorf("exportIgnored(%q) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestArchiveFormat(t *testing.T) {
+	tests := []struct {
+		format, output string
+		want           string
+		wantErr        bool
+	}{
+		{"", "", "tar", false},
+		{"", "out.tar", "tar", false},
+		{"", "out.zip", "zip", false},
+		{"", "out.tar.gz", "tar.gz", false},
+		{"", "out.tgz", "tar.gz", false},
+		{"", "out.rar", "tar", false},
+		{"zip", "out.tar", "zip", false},
+		{"tgz", "", "tar.gz", false},
+		{"rar", "", "", true},
+	}
+	for _, tt := range tests {
+		got, err := archiveFormat(tt.format, tt.output)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("archiveFormat(%q, %q) = %q, %v, want %q", tt.format, tt.output, got, err, tt.want)
+		}
+	}
+}
+
+// archiveFile is what an archive holds for one entry.
+type archiveFile struct {
+	Mode os.FileMode
+	Data string
+}
+
+// readArchive lists the entries of a tar, gzipped tar or zip archive, the
+// commit ID it records and the modification times it gives.
+func readArchive(t *testing.T, format string, data []byte) (map[string]archiveFile, string, []time.Time) {
+	t.Helper()
+	files := map[string]archiveFile{}
+	var comment string
+	var times []time.Time
+	if format == "zip" {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, _ := ioutil.ReadAll(rc)
+			rc.Close()
+			files[f.Name] = archiveFile{f.Mode(), string(content)}
+			times = append(times, f.Modified)
+		}
+		return files, zr.Comment, times
+	}
+	var r io.Reader = bytes.NewReader(data)
+	if format == "tar.gz" {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			comment = hdr.PAXRecords["comment"]
+			continue
+		}
+		content, _ := ioutil.ReadAll(tr)
+		files[hdr.Name] = archiveFile{hdr.FileInfo().Mode(), string(content)}
+		times = append(times, hdr.ModTime)
+	}
+	return files, comment, times
+}
+
+func TestArchive(t *testing.T) {
+	testRepo(t)
+	t.Setenv("REGIT_COMMITTER_DATE", "2024-05-06T07:08:09Z")
+	when := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	head := commitFiles(t, "snapshot", map[string]string{
+		".gitattributes":     "*.secret export-ignore\ninternal/ export-ignore\n",
+		"main.go":            "package main\n",
+		"a.secret":           "hidden\n",
+		"internal/notes.md":  "hidden\n",
+		"lib/lib.go":         "package lib\n",
+		"lib/.gitattributes": "keep.secret -export-ignore\n",
+		"lib/keep.secret":    "shown\n",
+		"lib/drop.secret":    "hidden\n",
+		"lib/local.txt":      "local\n",
+	})
+	writeFiles(t, map[string]string{".git/info/attributes": "local.txt export-ignore\n"})
+	dir := archiveFile{os.ModeDir | 0755, ""}
+	file := func(data string) archiveFile { return archiveFile{0644, data} }
+	tests := []struct {
+		name   string
+		rev    string
+		paths  []string
+		opts   ArchiveOptions
+		format string
+		want   map[string]archiveFile
+	}{
+		{
+			name:   "tar",
+			opts:   ArchiveOptions{Output: "out.tar"},
+			format: "tar",
+			want: map[string]archiveFile{
+				".gitattributes":     file("*.secret export-ignore\ninternal/ export-ignore\n"),
+				"main.go":            file("package main\n"),
+				"lib/":               dir,
+				"lib/.gitattributes": file("keep.secret -export-ignore\n"),
+				"lib/lib.go":         file("package lib\n"),
+				"lib/keep.secret":    file("shown\n"),
+			},
+		},
+		{
+			name:   "zip with prefix and paths",
+			rev:    "HEAD",
+			paths:  []string{"lib"},
+			opts:   ArchiveOptions{Output: "out.zip", Prefix: "snap/"},
+			format: "zip",
+			want: map[string]archiveFile{
+				"snap/":                   dir,
+				"snap/lib/":               dir,
+				"snap/lib/.gitattributes": file("keep.secret -export-ignore\n"),
+				"snap/lib/lib.go":         file("package lib\n"),
+				"snap/lib/keep.secret":    file("shown\n"),
+			},
+		},
+		{
+			name:   "gzipped tar by format",
+			paths:  []string{"main.go"},
+			opts:   ArchiveOptions{Output: "out.bin", Format: "tgz"},
+			format: "tar.gz",
+			want:   map[string]archiveFile{"main.go": file("package main\n")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out := captureOutput(t, func() { Archive(tt.rev, tt.paths, tt.opts) }); out != "" {
+				t.Fatalf("Archive printed %q", out)
+			}
+			data, err := ioutil.ReadFile(tt.opts.Output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files, comment, times := readArchive(t, tt.format, data)
+			if !reflect.DeepEqual(files, tt.want) {
+				t.Errorf("archive holds %v, want %v", files, tt.want)
+			}
+			if comment != head {
+				t.Errorf("archive records commit %q, want %q", comment, head)
+			}
+			for _, m := range times {
+				if !m.Equal(when) {
+					t.Errorf("entry time is %v, want %v", m, when)
+				}
+			}
+		})
+	}
+}
+
+func TestArchiveErrors(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "snapshot", map[string]string{"main.go": "package main\n"})
+	tests := []struct {
+		name  string
+		rev   string
+		paths []string
+		opts  ArchiveOptions
+		want  string
+	}{
+		{name: "bad format", opts: ArchiveOptions{Format: "rar", Output: "out"}, want: "unknown archive format 'rar'\n"},
+		{name: "bad revision", rev: "nope", opts: ArchiveOptions{Output: "out"}, want: "unknown revision: nope\n"},
+		{name: "no matching paths", paths: []string{"missing", "gone"}, opts: ArchiveOptions{Output: "out"}, want: "pathspec 'missing gone' did not match any files\n"},
+		{name: "unwritable output", opts: ArchiveOptions{Output: filepath.Join("no", "such", "dir.tar")}, want: "Error creating archive: open no/such/dir.tar: no such file or directory\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr string
+			stdout := captureOutput(t, func() {
+				stderr = captureStderr(t, func() { Archive(tt.rev, tt.paths, tt.opts) })
+			})
+			if stdout != "" || stderr != tt.want {
+				t.Errorf("Archive printed %q to stdout and %q to stderr, want %q on stderr", stdout, stderr, tt.want)
+			}
+			if _, err := os.Stat("out"); err == nil {
+				t.Error("an archive was written")
+			}
+		})
+	}
+}
+
+func TestArchiveWriteError(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "snapshot", map[string]string{"main.go": "package main\n"})
+	if err := ioutil.WriteFile("stdout", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Standard output opened read-only makes every write fail.
+	f, err := os.Open("stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	saved := os.Stdout
+	os.Stdout = f
+	got := captureStderr(t, func() { Archive("", nil, ArchiveOptions{}) })
+	os.Stdout = saved
+	if !strings.HasPrefix(got, "Error writing archive: ") {
+		t.Errorf("Archive printed %q to stderr, want a write error", got)
+	}
+	if data, _ := ioutil.ReadFile("stdout"); len(data) != 0 {
+		t.Errorf("the error went to stdout: %q", data)
+	}
+}
@@ -0,0 +1,422 @@
+package regit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/bits"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// A bisection keeps its state in the repository like git does:
+//
+//	BISECT_START  the branch (or commit) to go back to on reset
+//	BISECT_NAMES  the paths the search is limited to, one per line
+//	BISECT_LOG    the commands so far, in a form bisect replay accepts
+//	refs/bisect/  "bad", plus "good-<id>" and "skip-<id>" for each mark
+//
+// The commits still in question are those the bad commit reaches and no
+// good one does. Each step checks out the one that splits them most evenly.
+const (
+	bisectStartFile = ".git/BISECT_START"
+	bisectNamesFile = ".git/BISECT_NAMES"
+	bisectLogFile   = ".git/BISECT_LOG"
+	bisectRefsDir   = ".git/refs/bisect"
+)
+
+func bisecting() bool {
+	_, err := os.Stat(bisectStartFile)
+	return err == nil
+}
+
+func appendBisectLog(line string) {
+	f, err := os.OpenFile(bisectLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(line + "\n")
+}
+
+// bisectMarks returns the bad commit and the good and skipped ones.
+func bisectMarks() (bad string, good, skip []string) {
+	bad = readRef("refs/bisect/bad")
+	files, _ := ioutil.ReadDir(bisectRefsDir)
+	for _, f := range files {
+		if id := strings.TrimPrefix(f.Name(), "good-"); id != f.Name() {
+			good = append(good, id)
+		} else if id := strings.TrimPrefix(f.Name(), "skip-"); id != f.Name() {
+			skip = append(skip, id)
+		}
+	}
+	return bad, good, skip
+}
+
+// markBisect records a verdict on a commit.
+func markBisect(term, id string) error {
+	os.MkdirAll(bisectRefsDir, 0755)
+	name := term + "-" + id
+	if term == "bad" {
+		name = "bad"
+	}
+	if err := ioutil.WriteFile(filepath.Join(bisectRefsDir, name), []byte(id+"\n"), 0644); err != nil {
+		return err
+	}
+	appendBisectLog(fmt.Sprintf("# %s: [%s] %s", term, id, commitSubject(id)))
+	appendBisectLog(fmt.Sprintf("re-git bisect %s %s", term, id))
+	return nil
+}
+
+// bisectCheckout detaches HEAD at id and updates the working tree.
+func bisectCheckout(id string) error {
+	old := headCommitID()
+	from := currentBranch()
+	if from == "" {
+		from = shortID(old)
+	}
+	if err := setHead(id, "checkout: moving from "+from+" to "+shortID(id)); err != nil {
+		return err
+	}
+	if err := checkoutTree(commitTree(old), commitTree(id)); err != nil {
+		return err
+	}
+	runHook("post-checkout", "", old, id, "1")
+	return nil
+}
+
+// bisectNext checks out the next commit to test, or reports the first bad
+// commit once it is known. It returns true when the search is over.
+func bisectNext() (bool, error) {
+	bad, good, skip := bisectMarks()
+	if bad == "" || len(good) == 0 {
+		switch {
+		case bad == "" && len(good) == 0:
+			fmt.Println("status: waiting for both good and bad commits")
+		case bad == "":
+			fmt.Println("status: waiting for bad commit, 1 good commit known")
+		default:
+			fmt.Println("status: waiting for good commit(s), bad commit known")
+		}
+		return false, nil
+	}
+	commits, err := readCommits()
+	if err != nil {
+		return false, err
+	}
+	byID := make(map[string]commitEntry, len(commits))
+	for _, c := range commits {
+		byID[c.ID] = c
+	}
+	paths, _ := ioutil.ReadFile(bisectNamesFile)
+	names := strings.Fields(string(paths))
+	skipped := map[string]bool{}
+	for _, id := range skip {
+		skipped[id] = true
+	}
+	// Commits that do not touch the paths cannot have broken them.
+	var candidates []string
+	for _, id := range revList([]string{bad}, good) {
+		if id == bad || len(names) == 0 || touchesPaths(byID[id], byID, names) {
+			candidates = append(candidates, id)
+		}
+	}
+	inSet := map[string]bool{}
+	for _, id := range candidates {
+		inSet[id] = true
+	}
+	var testable []string
+	for _, id := range candidates {
+		if id != bad && !skipped[id] {
+			testable = append(testable, id)
+		}
+	}
+	if len(testable) == 0 {
+		if len(candidates) > 1 {
+			fmt.Println("There are only 'skip'ped commits left to test.")
+			fmt.Println("The first bad commit could be any of:")
+			for _, id := range candidates {
+				fmt.Println(id)
+			}
+			fmt.Println("We cannot bisect more!")
+			return true, nil
+		}
+		fmt.Println(bad, "is the first bad commit")
+		fmt.Println(strings.Join(commitText(byID[bad], false, nil), "\n"))
+		appendBisectLog(fmt.Sprintf("# first bad commit: [%s] %s", bad, commitSubject(bad)))
+		return true, nil
+	}
+	// Pick the commit whose ancestors among the candidates come closest to
+	// half of them.
+	best, bestScore := "", -1
+	for _, id := range testable {
+		below := 0
+		for anc := range reachable(commits, []string{id}) {
+			if inSet[anc] {
+				below++
+			}
+		}
+		if score := min(below, len(candidates)-below); score > bestScore {
+			best, bestScore = id, score
+		}
+	}
+	left := len(candidates) / 2
+	steps := max(bits.Len(uint(left))-1, 0)
+	fmt.Printf("Bisecting: %d revision%s left to test after this (roughly %d step%s)\n",
+		left, plural(left), steps, plural(steps))
+	if err := bisectCheckout(best); err != nil {
+		return false, err
+	}
+	fmt.Printf("[%s] %s\n", best, commitSubject(best))
+	return false, nil
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// clearBisect removes the bisection state, apart from the start point.
+func clearBisect() {
+	os.RemoveAll(bisectRefsDir)
+	os.Remove(bisectLogFile)
+	os.Remove(bisectNamesFile)
+}
+
+// BisectStart begins a search, optionally with a known bad revision and
+// good ones, limited to commits touching paths if any are given.
+func BisectStart(bad string, good, paths []string) {
+	if files := localChanges(); len(files) > 0 {
+		fmt.Println("Cannot bisect: you have local changes in")
+		for _, f := range files {
+			fmt.Println(" ", f)
+		}
+		return
+	}
+	var badID string
+	var goodIDs []string
+	if bad != "" {
+		id, err := resolveRevision(bad)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		badID = id
+	}
+	for _, rev := range good {
+		id, err := resolveRevision(rev)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		goodIDs = append(goodIDs, id)
+	}
+	if bisecting() {
+		clearBisect()
+	} else {
+		start := currentBranch()
+		if start == "" {
+			start = headCommitID()
+		}
+		if err := ioutil.WriteFile(bisectStartFile, []byte(start+"\n"), 0644); err != nil {
+			fmt.Println("Error starting bisect:", err)
+			return
+		}
+	}
+	if len(paths) > 0 {
+		ioutil.WriteFile(bisectNamesFile, []byte(strings.Join(paths, "\n")+"\n"), 0644)
+		appendBisectLog("re-git bisect start -- " + strings.Join(paths, " "))
+	} else {
+		appendBisectLog("re-git bisect start")
+	}
+	if badID != "" {
+		markBisect("bad", badID)
+	}
+	for _, id := range goodIDs {
+		markBisect("good", id)
+	}
+	if _, err := bisectNext(); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+// BisectMark records revs (HEAD if none) as good, bad or skipped and moves
+// on to the next commit to test. It reports whether the search is over.
+func BisectMark(term string, revs []string) bool {
+	done, err := bisectMark(term, revs)
+	if err != nil {
+		fmt.Println(err)
+	}
+	return done
+}
+
+// bisectMark records revs and checks out the next commit to test. It
+// returns an error, and records nothing, when a revision cannot be
+// resolved.
+func bisectMark(term string, revs []string) (bool, error) {
+	if !bisecting() {
+		return false, fmt.Errorf("You need to start by \"re-git bisect start\"")
+	}
+	if len(revs) == 0 {
+		revs = []string{"HEAD"}
+	}
+	if term == "bad" && len(revs) > 1 {
+		return false, fmt.Errorf("'bisect bad' can take only one argument")
+	}
+	ids := make([]string, len(revs))
+	for i, rev := range revs {
+		id, err := resolveRevision(rev)
+		if err != nil {
+			return false, err
+		}
+		ids[i] = id
+	}
+	for _, id := range ids {
+		if err := markBisect(term, id); err != nil {
+			return false, fmt.Errorf("Error: %v", err)
+		}
+	}
+	done, err := bisectNext()
+	if err != nil {
+		return false, fmt.Errorf("Error: %v", err)
+	}
+	return done, nil
+}
+
+// BisectReset ends the search and checks out the branch it started from,
+// or commit if one is given.
+func BisectReset(commit string) {
+	if !bisecting() {
+		fmt.Println("We are not bisecting.")
+		return
+	}
+	data, _ := ioutil.ReadFile(bisectStartFile)
+	start := strings.TrimSpace(string(data))
+	if commit != "" {
+		id, err := resolveRevision(commit)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		start = id
+	}
+	old := headCommitID()
+	ref := start
+	if !isObjectID(start) {
+		ref = "refs/heads/" + start
+	}
+	if err := setHead(ref, "checkout: moving from "+shortID(old)+" to "+start); err != nil {
+		fmt.Println("Error updating HEAD")
+		return
+	}
+	if err := checkoutTree(commitTree(old), commitTree(headCommitID())); err != nil {
+		fmt.Println("Error updating working tree:", err)
+		return
+	}
+	clearBisect()
+	os.Remove(bisectStartFile)
+	if isObjectID(start) {
+		fmt.Println("HEAD is now at", shortID(start))
+	} else {
+		fmt.Println("Switched to branch", start)
+	}
+}
+
+// BisectLog prints the commands of the current search.
+func BisectLog() {
+	data, err := ioutil.ReadFile(bisectLogFile)
+	if err != nil {
+		fmt.Println("We are not bisecting.")
+		return
+	}
+	fmt.Print(string(data))
+}
+
+// BisectReplay starts over and redoes the commands in a file written by
+// bisect log.
+func BisectReplay(file string) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Println("Cannot read file:", file)
+		return
+	}
+	if bisecting() {
+		BisectReset("")
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		words := strings.Fields(line)
+		if len(words) > 0 && (words[0] == "git" || words[0] == "re-git") {
+			words = words[1:]
+		}
+		if len(words) < 2 || words[0] != "bisect" {
+			continue
+		}
+		args := words[2:]
+		switch words[1] {
+		case "start":
+			var paths []string
+			for i, arg := range args {
+				if arg == "--" {
+					paths = args[i+1:]
+					break
+				}
+			}
+			BisectStart("", nil, paths)
+		case "good", "bad", "skip":
+			BisectMark(words[1], args)
+		}
+	}
+}
+
+// BisectRun automates the search by running command, without a shell, on
+// each commit to test: exit status 0 marks it good, 125 skips it, 1 to 127
+// mark it bad and anything else stops the search.
+func BisectRun(command []string) {
+	if !bisecting() {
+		fmt.Println("You need to start by \"re-git bisect start\"")
+		return
+	}
+	if bad, good, _ := bisectMarks(); bad == "" || len(good) == 0 {
+		fmt.Println("bisect run needs a good and a bad commit to start from")
+		return
+	}
+	for {
+		fmt.Println("running", strings.Join(command, " "))
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		code := 0
+		if err := cmd.Run(); err != nil {
+			exit, ok := err.(*exec.ExitError)
+			if !ok {
+				fmt.Println("bisect run failed:", err)
+				return
+			}
+			code = exit.ExitCode()
+		}
+		term := "good"
+		switch {
+		case code == 125:
+			term = "skip"
+		case code < 0 || code >= 128:
+			fmt.Printf("bisect run failed: exit code %d from '%s' is < 0 or >= 128\n", code, strings.Join(command, " "))
+			return
+		case code != 0:
+			term = "bad"
+		}
+		// Stop unless the result was recorded, or the same commit would
+		// be tested forever.
+		done, err := bisectMark(term, nil)
+		if err != nil {
+			fmt.Println("bisect run failed:", err)
+			return
+		}
+		if done {
+			return
+		}
+	}
+}
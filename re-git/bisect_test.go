@@ -0,0 +1,265 @@
+package regit
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBisectRun(t *testing.T) {
+	// Commit N sets v to N; commits 0 and 7 start out good and bad. Each
+	// script is a single argument with spaces, which must reach sh intact.
+	tests := []struct {
+		name     string
+		script   string
+		wantBad  int
+		skipOnly []int // if set, some commits must be skipped, all of them among these
+	}{
+		{
+			name:    "good and bad",
+			script:  `test "$(cat v)" -lt 3`,
+			wantBad: 3,
+		},
+		{
+			name:    "other bad exit codes",
+			script:  `test "$(cat v)" -lt 5 || exit 42`,
+			wantBad: 5,
+		},
+		{
+			name:     "125 skips",
+			script:   `n=$(cat v); if [ $n = 4 ] || [ $n = 5 ]; then exit 125; fi; [ $n -lt 3 ]`,
+			wantBad:  3,
+			skipOnly: []int{4, 5},
+		},
+		{
+			name:    "exit 128 or more stops",
+			script:  `exit 129`,
+			wantBad: 7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			var ids []string
+			for n := 0; n < 8; n++ {
+				ids = append(ids, commitFiles(t, "v"+strconv.Itoa(n), map[string]string{"v": strconv.Itoa(n) + "\n"}))
+			}
+			captureOutput(t, func() { BisectStart(ids[7], []string{ids[0]}, nil) })
+
+			out := captureOutput(t, func() { BisectRun([]string{"sh", "-c", tt.script}) })
+			bad, _, skip := bisectMarks()
+			if bad != ids[tt.wantBad] {
+				t.Errorf("bad is %s, want v%d:\n%s", commitSubject(bad), tt.wantBad, out)
+			}
+			if tt.wantBad != 7 && !strings.Contains(out, ids[tt.wantBad]+" is the first bad commit") {
+				t.Errorf("run did not report the first bad commit:\n%s", out)
+			}
+			if tt.skipOnly != nil && len(skip) == 0 {
+				t.Errorf("nothing was skipped:\n%s", out)
+			}
+			for _, id := range skip {
+				found := false
+				for _, n := range tt.skipOnly {
+					found = found || id == ids[n]
+				}
+				if !found {
+					t.Errorf("%s was skipped:\n%s", commitSubject(id), out)
+				}
+			}
+		})
+	}
+}
+
+func TestBisectRunStopsWhenMarkFails(t *testing.T) {
+	testRepo(t)
+	good := commitFiles(t, "good", map[string]string{"v": "0\n"})
+	commitFiles(t, "middle", map[string]string{"v": "1\n"})
+	bad := commitFiles(t, "bad", map[string]string{"v": "2\n"})
+	captureOutput(t, func() { BisectStart(bad, []string{good}, nil) })
+
+	// Leave HEAD unresolvable so the result cannot be recorded.
+	out := captureOutput(t, func() { BisectRun([]string{"sh", "-c", "echo 'ref: refs/heads/gone' > .git/HEAD"}) })
+	if n := strings.Count(out, "running"); n != 1 {
+		t.Errorf("command ran %d times, want 1:\n%s", n, out)
+	}
+	if !strings.Contains(out, "bisect run failed") {
+		t.Errorf("run did not report the failure:\n%s", out)
+	}
+}
+
+// bisectRepo makes commits v0..v7, each setting v to its number, and
+// returns their IDs.
+func bisectRepo(t *testing.T) []string {
+	t.Helper()
+	testRepo(t)
+	var ids []string
+	for n := 0; n < 8; n++ {
+		ids = append(ids, commitFiles(t, "v"+strconv.Itoa(n), map[string]string{"v": strconv.Itoa(n) + "\n"}))
+	}
+	return ids
+}
+
+// bisectByHand marks each commit bisect checks out as good or bad until
+// the search ends, returning the output and how many commits were tested.
+func bisectByHand(t *testing.T, firstBad int) (string, int) {
+	t.Helper()
+	var out strings.Builder
+	for steps := 1; steps <= 8; steps++ {
+		data, err := ioutil.ReadFile("v")
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+		term := "good"
+		if n >= firstBad {
+			term = "bad"
+		}
+		var done bool
+		out.WriteString(captureOutput(t, func() { done = BisectMark(term, nil) }))
+		if done {
+			return out.String(), steps
+		}
+	}
+	t.Fatalf("bisect did not finish:\n%s", out.String())
+	return "", 0
+}
+
+func TestBisectMark(t *testing.T) {
+	for _, firstBad := range []int{1, 3, 4, 6, 7} {
+		t.Run("v"+strconv.Itoa(firstBad), func(t *testing.T) {
+			ids := bisectRepo(t)
+			captureOutput(t, func() { BisectStart(ids[7], []string{ids[0]}, nil) })
+			out, steps := bisectByHand(t, firstBad)
+			if !strings.Contains(out, ids[firstBad]+" is the first bad commit") {
+				t.Errorf("bisect did not find v%d:\n%s", firstBad, out)
+			}
+			if steps > 3 {
+				t.Errorf("bisect took %d steps for 6 candidates", steps)
+			}
+		})
+	}
+}
+
+func TestBisectStates(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(ids []string)
+		wantOut string
+	}{
+		{
+			name:    "no bounds",
+			run:     func([]string) { BisectStart("", nil, nil) },
+			wantOut: "status: waiting for both good and bad commits",
+		},
+		{
+			name:    "bad only",
+			run:     func(ids []string) { BisectStart(ids[7], nil, nil) },
+			wantOut: "status: waiting for good commit(s), bad commit known",
+		},
+		{
+			name:    "good only",
+			run:     func(ids []string) { BisectStart("", []string{ids[0]}, nil) },
+			wantOut: "status: waiting for bad commit, 1 good commit known",
+		},
+		{
+			name:    "mark without start",
+			run:     func([]string) { BisectMark("good", nil) },
+			wantOut: `You need to start by "re-git bisect start"`,
+		},
+		{
+			name: "two bad revisions",
+			run: func(ids []string) {
+				BisectStart("", nil, nil)
+				BisectMark("bad", []string{ids[6], ids[7]})
+			},
+			wantOut: "'bisect bad' can take only one argument",
+		},
+		{
+			name: "only skipped commits left",
+			run: func(ids []string) {
+				BisectStart(ids[3], []string{ids[0]}, nil)
+				BisectMark("skip", []string{ids[1], ids[2]})
+			},
+			wantOut: "There are only 'skip'ped commits left to test.",
+		},
+		{
+			name: "limited to paths",
+			run: func(ids []string) {
+				BisectStart(ids[7], []string{ids[0]}, []string{"w"})
+			},
+			wantOut: "is the first bad commit",
+		},
+		{
+			name:    "reset without start",
+			run:     func([]string) { BisectReset("") },
+			wantOut: "We are not bisecting.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := bisectRepo(t)
+			if out := captureOutput(t, func() { tt.run(ids) }); !strings.Contains(out, tt.wantOut) {
+				t.Errorf("output does not mention %q:\n%s", tt.wantOut, out)
+			}
+		})
+	}
+}
+
+func TestBisectPaths(t *testing.T) {
+	testRepo(t)
+	var ids []string
+	for n := 0; n < 8; n++ {
+		file := "v"
+		if n == 2 || n == 5 {
+			file = "w"
+		}
+		ids = append(ids, commitFiles(t, "c"+strconv.Itoa(n), map[string]string{file: strconv.Itoa(n) + "\n"}))
+	}
+	// c5 broke w; only c2 and c5 are worth testing.
+	out := captureOutput(t, func() { BisectStart(ids[7], []string{ids[0]}, []string{"w"}) })
+	for step := 0; step < 3 && !strings.Contains(out, "is the first bad commit"); step++ {
+		head := headCommitID()
+		if head != ids[2] && head != ids[5] {
+			t.Fatalf("bisect tested %s, which does not touch w:\n%s", commitSubject(head), out)
+		}
+		term := "good"
+		if head == ids[5] {
+			term = "bad"
+		}
+		out += captureOutput(t, func() { BisectMark(term, nil) })
+	}
+	if !strings.Contains(out, ids[5]+" is the first bad commit") {
+		t.Errorf("bisect limited to w did not settle on c5:\n%s", out)
+	}
+}
+
+func TestBisectLogReplayReset(t *testing.T) {
+	ids := bisectRepo(t)
+	captureOutput(t, func() { BisectStart(ids[7], []string{ids[0]}, nil) })
+	bisectByHand(t, 4)
+	log := captureOutput(t, BisectLog)
+	if !strings.HasPrefix(log, "re-git bisect start\n") || !strings.Contains(log, "# first bad commit: ["+ids[4]+"] v4") {
+		t.Errorf("bisect log is\n%s", log)
+	}
+
+	if out := captureOutput(t, func() { BisectReset("") }); out != "Switched to branch master\n" {
+		t.Errorf("reset printed %q", out)
+	}
+	if headRef() != "refs/heads/master" || headCommitID() != ids[7] || bisecting() {
+		t.Errorf("reset left HEAD at %s (%q)", commitSubject(headCommitID()), headRef())
+	}
+
+	// The log starts with no bounds, which the replay reads back as marks.
+	if err := ioutil.WriteFile("saved-log", []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := captureOutput(t, func() { BisectReplay("saved-log") })
+	if bad, _, _ := bisectMarks(); bad != ids[4] || !strings.Contains(out, ids[4]+" is the first bad commit") {
+		t.Errorf("replay ended with bad %s:\n%s", commitSubject(bad), out)
+	}
+	captureOutput(t, func() { BisectReset(ids[2]) })
+	if headCommitID() != ids[2] || headRef() != "" {
+		t.Errorf("reset to a commit left HEAD at %s (%q)", commitSubject(headCommitID()), headRef())
+	}
+}
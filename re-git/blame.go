@@ -0,0 +1,448 @@
+package regit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// BlameOptions limit blame to the lines Start..End (1-based, inclusive;
+// zero means the start or end of the file), choose porcelain output and
+// make whitespace-only changes keep a line's earlier author. Moves follows
+// lines moved within the file and Copies lines taken from other files.
+// Commits in IgnoreRevs, in IgnoreRevsFile and in the file named by
+// blame.ignoreRevsFile pass the lines they changed on to their parent.
+type BlameOptions struct {
+	Start, End       int
+	Porcelain        bool
+	IgnoreWhitespace bool
+	Moves            bool
+	Copies           bool
+	IgnoreRevs       []string
+	IgnoreRevsFile   string
+}
+
+// blameMoveScore is how many letters and digits a block of lines must have
+// before it counts as moved or copied rather than rewritten.
+const blameMoveScore = 20
+
+// blameLine is a line of the blamed file: Final is its index in the file
+// as blamed and Orig its index in the version of the commit it is charged
+// to.
+type blameLine struct {
+	Final, Orig int
+}
+
+// blameResult is a line with the commit that introduced it and the file it
+// was in there.
+type blameResult struct {
+	Commit, File string
+	blameLine
+}
+
+// blameKey is what lines are compared by when deciding whether a commit
+// changed them.
+func blameKey(line string, ignoreWhitespace bool) string {
+	if ignoreWhitespace {
+		return strings.Join(strings.Fields(line), "")
+	}
+	return strings.TrimSuffix(line, "\n")
+}
+
+func blameKeys(lines []string, ignoreWhitespace bool) []string {
+	keys := make([]string, len(lines))
+	for i, line := range lines {
+		keys[i] = blameKey(line, ignoreWhitespace)
+	}
+	return keys
+}
+
+// fileLines returns the lines of file in a commit's tree, or nil if the
+// commit does not have it.
+func fileLines(c commitEntry, file string) []string {
+	oid, ok := c.Files[file]
+	if !ok {
+		return nil
+	}
+	data, err := readObject(oid)
+	if err != nil {
+		return nil
+	}
+	return splitLines(data)
+}
+
+// blameFile charges each of the lines of file in commit id to the commit
+// that introduced it. History is walked from the newest commit back: every
+// line a commit shares with one of its parents is passed on to that parent
+// and the ones left over are the commit's own.
+func blameFile(id, file string, lines []blameLine, ignored map[string]bool, opts BlameOptions) ([]blameResult, error) {
+	commits, err := readCommits()
+	if err != nil {
+		return nil, err
+	}
+	// The log is append-only, so parents always come before their children
+	// and walking it backwards visits a commit after everything built on it.
+	byID := map[string]int{}
+	for i, c := range commits {
+		byID[c.ID] = i
+	}
+	start, ok := byID[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown commit %s", id)
+	}
+	// suspects holds, per commit and file, the lines still to be charged.
+	suspects := map[string]map[string][]blameLine{id: {file: lines}}
+	pass := func(pending []blameLine, match []int, parent, path string) []blameLine {
+		var rest []blameLine
+		for _, l := range pending {
+			if j := match[l.Orig]; j >= 0 {
+				if suspects[parent] == nil {
+					suspects[parent] = map[string][]blameLine{}
+				}
+				suspects[parent][path] = append(suspects[parent][path], blameLine{l.Final, j})
+			} else {
+				rest = append(rest, l)
+			}
+		}
+		return rest
+	}
+	var results []blameResult
+	for i := start; i >= 0 && len(suspects) > 0; i-- {
+		c := commits[i]
+		files, ok := suspects[c.ID]
+		if !ok {
+			continue
+		}
+		delete(suspects, c.ID)
+		var parents []commitEntry
+		for _, p := range c.Parents {
+			if pi, ok := byID[p]; ok {
+				parents = append(parents, commits[pi])
+			}
+		}
+		for _, path := range sortedKeys(files) {
+			pending := files[path]
+			sort.Slice(pending, func(a, b int) bool { return pending[a].Orig < pending[b].Orig })
+			current := blameKeys(fileLines(c, path), opts.IgnoreWhitespace)
+			for _, p := range parents {
+				if parent := fileLines(p, path); parent != nil && len(pending) > 0 {
+					pending = pass(pending, matchLines(current, blameKeys(parent, opts.IgnoreWhitespace)), p.ID, path)
+				}
+			}
+			if opts.Moves || opts.Copies {
+				for _, p := range parents {
+					if parent := fileLines(p, path); parent != nil && len(pending) > 0 {
+						pending = pass(pending, copiedLines(current, pending, blameKeys(parent, opts.IgnoreWhitespace)), p.ID, path)
+					}
+				}
+			}
+			if opts.Copies {
+				for _, p := range parents {
+					for _, other := range sortedKeys(p.Files) {
+						if other != path && len(pending) > 0 {
+							pending = pass(pending, copiedLines(current, pending, blameKeys(fileLines(p, other), opts.IgnoreWhitespace)), p.ID, other)
+						}
+					}
+				}
+			}
+			if ignored[c.ID] && len(parents) > 0 && len(pending) > 0 {
+				if parent := fileLines(parents[0], path); parent != nil {
+					pending = pass(pending, guessLines(current, blameKeys(parent, opts.IgnoreWhitespace)), parents[0].ID, path)
+				}
+			}
+			for _, l := range pending {
+				results = append(results, blameResult{c.ID, path, l})
+			}
+		}
+	}
+	return results, nil
+}
+
+// copiedLines finds the pending lines of current that are part of a block
+// of lines also found in source, returning for each line of current its
+// index in source or -1. Blocks with fewer than blameMoveScore letters and
+// digits are ignored so that braces and blank lines are not taken for
+// copies.
+func copiedLines(current []string, pending []blameLine, source []string) []int {
+	match := make([]int, len(current))
+	for i := range match {
+		match[i] = -1
+	}
+	positions := map[string][]int{}
+	for j, line := range source {
+		positions[line] = append(positions[line], j)
+	}
+	for _, l := range pending {
+		if match[l.Orig] >= 0 {
+			continue
+		}
+		best, bestStart, bestJ := 0, 0, 0
+		for _, j := range positions[current[l.Orig]] {
+			start, end := 0, 1
+			for l.Orig-start > 0 && j-start > 0 && current[l.Orig-start-1] == source[j-start-1] {
+				start++
+			}
+			for l.Orig+end < len(current) && j+end < len(source) && current[l.Orig+end] == source[j+end] {
+				end++
+			}
+			if score := alnumCount(current[l.Orig-start : l.Orig+end]); score > best {
+				best, bestStart, bestJ = score, l.Orig-start, j-start
+			}
+		}
+		if best < blameMoveScore {
+			continue
+		}
+		for n := 0; bestStart+n < len(current) && bestJ+n < len(source) && current[bestStart+n] == source[bestJ+n]; n++ {
+			match[bestStart+n] = bestJ + n
+		}
+	}
+	return match
+}
+
+func alnumCount(lines []string) int {
+	n := 0
+	for _, line := range lines {
+		for _, r := range line {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// guessLines maps lines of current to parent like matchLines, but also
+// pairs lines a change rewrote with the old lines at the same position in
+// the hunk, so that an ignored commit's lines go to whoever wrote the lines
+// it replaced.
+func guessLines(current, parent []string) []int {
+	match := make([]int, len(current))
+	i, j := 0, 0
+	var removed []int
+	added := 0
+	for _, op := range diffLines(parent, current) {
+		switch op.Kind {
+		case ' ':
+			match[j] = i
+			i++
+			j++
+			removed, added = nil, 0
+		case '-':
+			removed = append(removed, i)
+			i++
+		case '+':
+			match[j] = -1
+			if len(removed) > 0 {
+				match[j] = removed[min(added, len(removed)-1)]
+			}
+			added++
+			j++
+		}
+	}
+	return match
+}
+
+// ignoredRevisions resolves the commits blame should look through.
+func ignoredRevisions(opts BlameOptions) (map[string]bool, error) {
+	revs := append([]string(nil), opts.IgnoreRevs...)
+	for _, path := range []string{configPath("blame.ignoreRevsFile"), opts.IgnoreRevsFile} {
+		if path == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ignore revisions file: %v", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				revs = append(revs, line)
+			}
+		}
+	}
+	ignored := map[string]bool{}
+	for _, rev := range revs {
+		id, err := resolveRevision(rev)
+		if err != nil {
+			return nil, err
+		}
+		ignored[id] = true
+	}
+	return ignored, nil
+}
+
+// ParseLineRange parses the argument of -L: "start,end", "start,+count",
+// "start," or ",end". A bound of zero stands for the start or end of the
+// file.
+func ParseLineRange(s string) (int, int, error) {
+	from, to, _ := strings.Cut(s, ",")
+	start, end := 0, 0
+	var err error
+	if from != "" {
+		if start, err = strconv.Atoi(from); err != nil || start < 1 {
+			return 0, 0, fmt.Errorf("invalid line range %q", s)
+		}
+	}
+	switch {
+	case strings.HasPrefix(to, "+"):
+		count, err := strconv.Atoi(to[1:])
+		if err != nil || count < 1 {
+			return 0, 0, fmt.Errorf("invalid line range %q", s)
+		}
+		end = max(start, 1) + count - 1
+	case to != "":
+		if end, err = strconv.Atoi(to); err != nil || end < 1 {
+			return 0, 0, fmt.Errorf("invalid line range %q", s)
+		}
+	}
+	return start, end, nil
+}
+
+// blameAuthor splits a commit's author into identity, mapped through the
+// mailmap, and time.
+func blameAuthor(c commitEntry) (identity, time.Time) {
+	author, err := parseIdentity(c.Author)
+	if err != nil {
+		author = identity{Name: "unknown", Email: "unknown"}
+	}
+	author = canonicalIdentity(author)
+	date, _ := time.Parse(time.RFC3339, c.Date)
+	return author, date
+}
+
+// Blame shows, for each line of file as of rev (HEAD if empty), the commit
+// that last changed it with its author and date.
+func Blame(rev, file string, opts BlameOptions) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	id, err := resolveRevision(rev)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	c, _ := loadCommit(id)
+	content := fileLines(c, file)
+	if content == nil {
+		fmt.Printf("no such path %s in %s\n", file, rev)
+		return
+	}
+	start, end := opts.Start, opts.End
+	if start == 0 {
+		start = 1
+	}
+	if end == 0 || end > len(content) {
+		end = len(content)
+	}
+	if start > len(content) || start > end {
+		fmt.Printf("file %s has only %d lines\n", file, len(content))
+		return
+	}
+	var lines []blameLine
+	for i := start - 1; i < end; i++ {
+		lines = append(lines, blameLine{i, i})
+	}
+	ignored, err := ignoredRevisions(opts)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	results, err := blameFile(id, file, lines, ignored, opts)
+	if err != nil {
+		fmt.Println("Error reading log:", err)
+		return
+	}
+	byLine := make([]blameResult, len(content))
+	for _, r := range results {
+		byLine[r.Final] = r
+	}
+	commits := map[string]commitEntry{}
+	for _, r := range results {
+		if _, ok := commits[r.Commit]; !ok {
+			commits[r.Commit], _ = loadCommit(r.Commit)
+		}
+	}
+	if opts.Porcelain {
+		printBlamePorcelain(content, byLine[start-1:end], commits)
+		return
+	}
+	width, fileWidth := 0, 0
+	for _, c := range commits {
+		author, _ := blameAuthor(c)
+		width = max(width, len(author.Name))
+	}
+	for _, r := range results {
+		if r.File != file {
+			// Some lines came from other files; name the file on every line.
+			for _, r := range results {
+				fileWidth = max(fileWidth, len(r.File))
+			}
+			break
+		}
+	}
+	numWidth := len(strconv.Itoa(end))
+	for _, r := range byLine[start-1 : end] {
+		c := commits[r.Commit]
+		author, date := blameAuthor(c)
+		id := shortID(c.ID)
+		if len(c.Parents) == 0 {
+			// Root commits are marked, keeping the column width.
+			id = "^" + id[:len(id)-1]
+		}
+		if fileWidth > 0 {
+			id += fmt.Sprintf(" %-*s", fileWidth, r.File)
+		}
+		fmt.Printf("%s (%-*s %s %*d) %s\n", id, width, author.Name,
+			date.Format("2006-01-02 15:04:05 -0700"), numWidth, r.Final+1,
+			strings.TrimSuffix(content[r.Final], "\n"))
+	}
+}
+
+// printBlamePorcelain prints blame results in the machine-readable format:
+// a "<id> <orig line> <final line> [<lines in group>]" header per line,
+// the commit's details the first time it appears and the file name at the
+// start of each group, and the line itself after a tab.
+func printBlamePorcelain(content []string, results []blameResult, commits map[string]commitEntry) {
+	seen := map[string]bool{}
+	for i, r := range results {
+		sameGroup := func(j int) bool {
+			return results[j].Commit == r.Commit && results[j].File == r.File && results[j].Orig == r.Orig+j-i
+		}
+		fmt.Printf("%s %d %d", r.Commit, r.Orig+1, r.Final+1)
+		if i > 0 && sameGroup(i-1) {
+			fmt.Printf("\n\t%s\n", strings.TrimSuffix(content[r.Final], "\n"))
+			continue
+		}
+		group := 1
+		for j := i + 1; j < len(results) && sameGroup(j); j++ {
+			group++
+		}
+		fmt.Printf(" %d\n", group)
+		if !seen[r.Commit] {
+			seen[r.Commit] = true
+			c := commits[r.Commit]
+			author, date := blameAuthor(c)
+			committer, err := parseIdentity(c.Committer)
+			if err != nil {
+				committer = author
+			}
+			commitDate, err := time.Parse(time.RFC3339, c.CommitDate)
+			if err != nil {
+				commitDate = date
+			}
+			fmt.Printf("author %s\nauthor-mail <%s>\nauthor-time %d\nauthor-tz %s\n",
+				author.Name, author.Email, date.Unix(), date.Format("-0700"))
+			fmt.Printf("committer %s\ncommitter-mail <%s>\ncommitter-time %d\ncommitter-tz %s\n",
+				committer.Name, committer.Email, commitDate.Unix(), commitDate.Format("-0700"))
+			fmt.Printf("summary %s\n", subject(c.Message))
+			if len(c.Parents) == 0 {
+				fmt.Println("boundary")
+			}
+		}
+		fmt.Printf("filename %s\n", r.File)
+		fmt.Printf("\t%s\n", strings.TrimSuffix(content[r.Final], "\n"))
+	}
+}
@@ -0,0 +1,419 @@
+package regit
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLineRange(t *testing.T) {
+	tests := []struct {
+		in         string
+		start, end int
+		wantErr    bool
+	}{
+		{in: "3,7", start: 3, end: 7},
+		{in: "3,+2", start: 3, end: 4},
+		{in: ",+2", start: 0, end: 2},
+		{in: "5,", start: 5, end: 0},
+		{in: ",9", start: 0, end: 9},
+		{in: "4", start: 4, end: 0},
+		{in: "0,3", wantErr: true},
+		{in: "a,3", wantErr: true},
+		{in: "3,b", wantErr: true},
+		{in: "3,+0", wantErr: true},
+		{in: "3,-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		start, end, err := ParseLineRange(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLineRange(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (start != tt.start || end != tt.end) {
+			t.Errorf("ParseLineRange(%q) = %d, %d, want %d, %d", tt.in, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+// blameSubjects blames every line of file at HEAD and returns the subject
+// of the commit each was charged to, followed by the file it came from
+// when that is not file. Unless commits are ignored, each line must match
+// the line it was charged to.
+func blameSubjects(t *testing.T, file string, opts BlameOptions) []string {
+	t.Helper()
+	head := headCommitID()
+	c, _ := loadCommit(head)
+	content := fileLines(c, file)
+	var lines []blameLine
+	for i := range content {
+		lines = append(lines, blameLine{i, i})
+	}
+	ignored, err := ignoredRevisions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := blameFile(head, file, lines, ignored, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(content))
+	for _, r := range results {
+		got[r.Final] = commitSubject(r.Commit)
+		if r.File != file {
+			got[r.Final] += " (" + r.File + ")"
+		}
+		if len(ignored) > 0 {
+			continue
+		}
+		if want := fileLines(commits(t, r.Commit), r.File); r.Orig >= len(want) || blameKey(want[r.Orig], opts.IgnoreWhitespace) != blameKey(content[r.Final], opts.IgnoreWhitespace) {
+			t.Errorf("line %d is charged to line %d of %s in %q, which differs", r.Final+1, r.Orig+1, r.File, got[r.Final])
+		}
+	}
+	return got
+}
+
+func commits(t *testing.T, id string) commitEntry {
+	t.Helper()
+	c, ok := loadCommit(id)
+	if !ok {
+		t.Fatalf("no commit %s", id)
+	}
+	return c
+}
+
+func lines(s ...string) string {
+	return strings.Join(s, "\n") + "\n"
+}
+
+func TestBlame(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T)
+		opts  BlameOptions
+		want  []string
+	}{
+		{
+			name: "one line edited",
+			setup: func(t *testing.T) {
+				commitFiles(t, "first", map[string]string{"f": lines("a", "b", "c")})
+				commitFiles(t, "second", map[string]string{"f": lines("a", "B", "c")})
+			},
+			want: []string{"first", "second", "first"},
+		},
+		{
+			name: "insert and delete",
+			setup: func(t *testing.T) {
+				commitFiles(t, "first", map[string]string{"f": lines("a", "b", "c")})
+				commitFiles(t, "second", map[string]string{"f": lines("new", "a", "c")})
+				commitFiles(t, "third", map[string]string{"f": lines("new", "a", "c", "end")})
+			},
+			want: []string{"second", "first", "first", "third"},
+		},
+		{
+			name: "unrelated commits skipped",
+			setup: func(t *testing.T) {
+				commitFiles(t, "first", map[string]string{"f": lines("a", "b")})
+				commitFiles(t, "other file", map[string]string{"g": lines("x")})
+				commitFiles(t, "second", map[string]string{"f": lines("a", "b", "c")})
+			},
+			want: []string{"first", "first", "second"},
+		},
+		{
+			name: "whitespace change",
+			setup: func(t *testing.T) {
+				commitFiles(t, "first", map[string]string{"f": lines("x = 1", "y = 2")})
+				commitFiles(t, "reindent", map[string]string{"f": lines("x  =  1", "\ty = 3")})
+			},
+			want: []string{"reindent", "reindent"},
+		},
+		{
+			name: "whitespace change with -w",
+			setup: func(t *testing.T) {
+				commitFiles(t, "first", map[string]string{"f": lines("x = 1", "y = 2")})
+				commitFiles(t, "reindent", map[string]string{"f": lines("x  =  1", "\ty = 3")})
+			},
+			opts: BlameOptions{IgnoreWhitespace: true},
+			want: []string{"first", "reindent"},
+		},
+		{
+			name: "merge",
+			setup: func(t *testing.T) {
+				commitFiles(t, "base", map[string]string{"f": lines("a", "b", "c")})
+				captureOutput(t, func() { CreateBranch("side") })
+				main := commitFiles(t, "main", map[string]string{"f": lines("A", "b", "c")})
+				captureOutput(t, func() { CheckoutBranch("side") })
+				side := commitFiles(t, "side", map[string]string{"f": lines("a", "b", "C")})
+				oid, err := writeObject([]byte(lines("A", "b", "C")))
+				if err != nil {
+					t.Fatal(err)
+				}
+				merge := commitEntry{Parents: []string{main, side}, Message: "merge", Files: map[string]string{"f": oid}}
+				if err := fillIdentity(&merge); err != nil {
+					t.Fatal(err)
+				}
+				if err := writeCommit(&merge); err != nil {
+					t.Fatal(err)
+				}
+				if err := updateRef("refs/heads/side", merge.ID, "merge"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"main", "base", "side"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			tt.setup(t)
+			if got := blameSubjects(t, "f", tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blame charged lines to %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlameOutput(t *testing.T) {
+	testRepo(t)
+	t.Setenv("REGIT_AUTHOR_DATE", "2024-01-02T03:04:05+01:00")
+	t.Setenv("REGIT_COMMITTER_DATE", "2024-01-02T03:04:05+01:00")
+	first := commitFiles(t, "first", map[string]string{"f": lines("a", "b", "c")})
+	t.Setenv("REGIT_AUTHOR_NAME", "Al")
+	second := commitFiles(t, "second", map[string]string{"f": lines("a", "B", "c")})
+	root := "^" + shortID(first)[:len(shortID(first))-1]
+
+	porcelain := fmt.Sprintf("%s 1 1 1\n"+
+		"author Test User\nauthor-mail <test@example.com>\nauthor-time 1704161045\nauthor-tz +0100\n"+
+		"committer Test User\ncommitter-mail <test@example.com>\ncommitter-time 1704161045\ncommitter-tz +0100\n"+
+		"summary first\nboundary\nfilename f\n\ta\n"+
+		"%s 2 2 1\n"+
+		"author Al\nauthor-mail <test@example.com>\nauthor-time 1704161045\nauthor-tz +0100\n"+
+		"committer Test User\ncommitter-mail <test@example.com>\ncommitter-time 1704161045\ncommitter-tz +0100\n"+
+		"summary second\nfilename f\n\tB\n", first, second)
+	tests := []struct {
+		name string
+		opts BlameOptions
+		want string
+	}{
+		{
+			name: "default",
+			want: root + " (Test User 2024-01-02 03:04:05 +0100 1) a\n" +
+				shortID(second) + " (Al        2024-01-02 03:04:05 +0100 2) B\n" +
+				root + " (Test User 2024-01-02 03:04:05 +0100 3) c\n",
+		},
+		{
+			name: "range",
+			opts: BlameOptions{Start: 2, End: 2},
+			want: shortID(second) + " (Al 2024-01-02 03:04:05 +0100 2) B\n",
+		},
+		{
+			name: "porcelain",
+			opts: BlameOptions{Start: 1, End: 2, Porcelain: true},
+			want: porcelain,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := captureOutput(t, func() { Blame("", "f", tt.opts) }); got != tt.want {
+				t.Errorf("blame printed\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		rev, file string
+		opts      BlameOptions
+		want      string
+	}{
+		{"", "missing", BlameOptions{}, "no such path missing in HEAD\n"},
+		{"", "f", BlameOptions{Start: 4}, "file f has only 3 lines\n"},
+		{"nope", "f", BlameOptions{}, ""},
+	} {
+		got := captureOutput(t, func() { Blame(tt.rev, tt.file, tt.opts) })
+		if tt.want != "" && got != tt.want || tt.want == "" && !strings.Contains(got, "nope") {
+			t.Errorf("Blame(%q, %q) printed %q, want %q", tt.rev, tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestBlamePorcelainGroups(t *testing.T) {
+	testRepo(t)
+	first := commitFiles(t, "first", map[string]string{"f": lines("a", "b", "c")})
+	out := captureOutput(t, func() { Blame("", "f", BlameOptions{Porcelain: true}) })
+	var headers []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, first) {
+			headers = append(headers, line)
+		}
+	}
+	want := []string{first + " 1 1 3", first + " 2 2", first + " 3 3"}
+	if !reflect.DeepEqual(headers, want) {
+		t.Errorf("porcelain headers are %q, want %q", headers, want)
+	}
+	if n := strings.Count(out, "author-mail"); n != 1 {
+		t.Errorf("commit details printed %d times, want once", n)
+	}
+}
+
+func TestBlameMovesAndCopies(t *testing.T) {
+	blockA := []string{"func alpha() {", "\treturn computeAlphaValue(input)", "}"}
+	blockB := []string{"func beta() {", "\treturn computeBetaValue(input)", "}"}
+	moved := func(t *testing.T) {
+		commitFiles(t, "first", map[string]string{"f": lines(append(append([]string{}, blockA...), blockB...)...)})
+		commitFiles(t, "swap", map[string]string{"f": lines(append(append([]string{}, blockB...), blockA...)...)})
+	}
+	copied := func(t *testing.T) {
+		commitFiles(t, "helpers", map[string]string{"g": lines(blockA...)})
+		commitFiles(t, "unrelated", map[string]string{"h": lines("x")})
+		commitFiles(t, "copy", map[string]string{"f": lines(append([]string{"// copied"}, blockA...)...)})
+	}
+	tests := []struct {
+		name  string
+		setup func(t *testing.T)
+		opts  BlameOptions
+		want  []string
+	}{
+		{
+			name:  "moved block",
+			setup: moved,
+			want:  []string{"swap", "swap", "swap", "first", "first", "first"},
+		},
+		{
+			name:  "moved block with -M",
+			setup: moved,
+			opts:  BlameOptions{Moves: true},
+			want:  []string{"first", "first", "first", "first", "first", "first"},
+		},
+		{
+			name:  "copied block",
+			setup: copied,
+			want:  []string{"copy", "copy", "copy", "copy"},
+		},
+		{
+			name:  "copied block with -M",
+			setup: copied,
+			opts:  BlameOptions{Moves: true},
+			want:  []string{"copy", "copy", "copy", "copy"},
+		},
+		{
+			name:  "copied block with -C",
+			setup: copied,
+			opts:  BlameOptions{Copies: true},
+			want:  []string{"copy", "helpers (g)", "helpers (g)", "helpers (g)"},
+		},
+		{
+			name: "short lines are not copies",
+			setup: func(t *testing.T) {
+				commitFiles(t, "braces", map[string]string{"g": lines("}", "", "}")})
+				commitFiles(t, "new", map[string]string{"f": lines("}", "", "}")})
+			},
+			opts: BlameOptions{Copies: true},
+			want: []string{"new", "new", "new"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			tt.setup(t)
+			if got := blameSubjects(t, "f", tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blame charged lines to %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlameIgnoreRevs(t *testing.T) {
+	// "reformat" rewrites every line; "fix" then changes the second.
+	setup := func(t *testing.T) (reformat string) {
+		commitFiles(t, "first", map[string]string{"f": lines("a=1", "b=2", "c=3")})
+		reformat = commitFiles(t, "reformat", map[string]string{"f": lines("a = 1", "b = 2", "c = 3")})
+		commitFiles(t, "fix", map[string]string{"f": lines("a = 1", "b = 20", "c = 3")})
+		return reformat
+	}
+	tests := []struct {
+		name    string
+		opts    func(t *testing.T, reformat string) BlameOptions
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "not ignored",
+			opts: func(*testing.T, string) BlameOptions { return BlameOptions{} },
+			want: []string{"reformat", "fix", "reformat"},
+		},
+		{
+			name: "--ignore-rev",
+			opts: func(t *testing.T, reformat string) BlameOptions {
+				return BlameOptions{IgnoreRevs: []string{shortID(reformat)}}
+			},
+			want: []string{"first", "fix", "first"},
+		},
+		{
+			name: "ignore revisions file",
+			opts: func(t *testing.T, reformat string) BlameOptions {
+				writeFiles(t, map[string]string{".ignore-revs": "# reformatting\n\n" + reformat + "\n"})
+				return BlameOptions{IgnoreRevsFile: ".ignore-revs"}
+			},
+			want: []string{"first", "fix", "first"},
+		},
+		{
+			name: "blame.ignoreRevsFile",
+			opts: func(t *testing.T, reformat string) BlameOptions {
+				writeFiles(t, map[string]string{".ignore-revs": reformat + "\n"})
+				captureOutput(t, func() { ConfigSet("blame.ignoreRevsFile", ".ignore-revs", false, ConfigOptions{}) })
+				return BlameOptions{}
+			},
+			want: []string{"first", "fix", "first"},
+		},
+		{
+			name: "missing file",
+			opts: func(*testing.T, string) BlameOptions {
+				return BlameOptions{IgnoreRevsFile: "missing"}
+			},
+			wantErr: "cannot read ignore revisions file",
+		},
+		{
+			name: "unknown revision",
+			opts: func(*testing.T, string) BlameOptions {
+				return BlameOptions{IgnoreRevs: []string{"nope"}}
+			},
+			wantErr: "nope",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			opts := tt.opts(t, setup(t))
+			if tt.wantErr != "" {
+				_, err := ignoredRevisions(opts)
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ignoredRevisions error = %v, want %q", err, tt.wantErr)
+				}
+				if out := captureOutput(t, func() { Blame("", "f", opts) }); !strings.Contains(out, tt.wantErr) {
+					t.Errorf("blame printed %q, want it to mention %q", out, tt.wantErr)
+				}
+				return
+			}
+			if got := blameSubjects(t, "f", opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blame charged lines to %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlameCopyOutputNamesFiles(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "helpers", map[string]string{"g": lines("func alpha() {", "\treturn computeAlphaValue(input)", "}")})
+	commitFiles(t, "copy", map[string]string{"longer": lines("// copied", "func alpha() {", "\treturn computeAlphaValue(input)", "}")})
+	out := captureOutput(t, func() { Blame("", "longer", BlameOptions{Copies: true}) })
+	for i, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		file := "g     "
+		if i == 0 {
+			file = "longer"
+		}
+		if _, rest, _ := strings.Cut(line, " "); !strings.HasPrefix(rest, file+" (") {
+			t.Errorf("line %d does not name %q:\n%s", i+1, file, out)
+		}
+	}
+}
@@ -8,24 +8,12 @@ import (
 )
 
 func Checkout() {
-	commit := HeadCommit()
-	if commit == "" {
+	c, ok := loadCommit(headCommitID())
+	if !ok {
 		fmt.Println("No commits found")
 		return
 	}
-	lines := strings.Split(commit, "\n")
-	files := []string{}
-	for _, line := range lines {
-		if strings.Contains(line, " ") && !strings.HasPrefix(line, "commit") && !strings.HasPrefix(line, "Date:") {
-			files = append(files, line)
-		}
-	}
-	for _, entry := range files {
-		parts := strings.Split(entry, " ")
-		if len(parts) != 2 {
-			continue
-		}
-		file, oid := parts[0], parts[1]
+	for file, oid := range c.Files {
 		objPath := filepath.Join(objectsDir, oid)
 		data, err := ioutil.ReadFile(objPath)
 		if err != nil {
@@ -35,6 +23,7 @@ func Checkout() {
 		ioutil.WriteFile(file, data, 0644)
 		fmt.Println("Restored", file)
 	}
+	runHook("post-checkout", "", c.ID, c.ID, "0")
 }
 
 func Diff() {
@@ -0,0 +1,219 @@
+package regit
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cherryPickRepo makes master with "base" (f=1) and "main change" (g=1),
+// and a side branch off base with "side one" (f=2, by Orig Author) and
+// "side two" (h=1). It leaves master checked out and returns the IDs of
+// the side commits.
+func cherryPickRepo(t *testing.T) (one, two string) {
+	t.Helper()
+	testRepo(t)
+	commitFiles(t, "base", map[string]string{"f": "1\n"})
+	captureOutput(t, func() { CreateBranch("side") })
+	commitFiles(t, "main change", map[string]string{"g": "1\n"})
+	captureOutput(t, func() { CheckoutBranch("side") })
+	t.Setenv("REGIT_AUTHOR_NAME", "Orig Author")
+	t.Setenv("REGIT_AUTHOR_EMAIL", "orig@example.com")
+	t.Setenv("REGIT_AUTHOR_DATE", "2020-05-06T07:08:09Z")
+	one = commitFiles(t, "side one", map[string]string{"f": "2\n"})
+	for _, v := range []string{"NAME", "EMAIL", "DATE"} {
+		t.Setenv("REGIT_AUTHOR_"+v, "")
+	}
+	two = commitFiles(t, "side two", map[string]string{"h": "1\n"})
+	captureOutput(t, func() { CheckoutBranch("master") })
+	return one, two
+}
+
+func TestCherryPick(t *testing.T) {
+	tests := []struct {
+		name      string
+		revs      func(one, two string) []string
+		noCommit  bool
+		wantLog   []string
+		wantFiles map[string]string
+	}{
+		{
+			name:      "one commit",
+			revs:      func(one, two string) []string { return []string{one} },
+			wantLog:   []string{"side one", "main change", "base"},
+			wantFiles: map[string]string{"f": "2\n", "g": "1\n"},
+		},
+		{
+			name:      "a range, oldest first",
+			revs:      func(one, two string) []string { return []string{"master..side"} },
+			wantLog:   []string{"side two", "side one", "main change", "base"},
+			wantFiles: map[string]string{"f": "2\n", "g": "1\n", "h": "1\n"},
+		},
+		{
+			name:      "no commit",
+			revs:      func(one, two string) []string { return []string{two} },
+			noCommit:  true,
+			wantLog:   []string{"main change", "base"},
+			wantFiles: map[string]string{"f": "1\n", "h": "1\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			one, two := cherryPickRepo(t)
+			out := captureOutput(t, func() { CherryPick(tt.revs(one, two), tt.noCommit, false) })
+			if got := firstParentSubjects(headCommitID()); !reflect.DeepEqual(got, tt.wantLog) {
+				t.Errorf("history is %q, want %q:\n%s", got, tt.wantLog, out)
+			}
+			for f, want := range tt.wantFiles {
+				if got, _ := ioutil.ReadFile(f); string(got) != want {
+					t.Errorf("%s is %q, want %q", f, got, want)
+				}
+			}
+			if sequencerInProgress() {
+				t.Errorf("cherry-pick did not finish:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestCherryPickKeepsAuthor(t *testing.T) {
+	tests := []struct {
+		recordOrigin bool
+		wantMessage  func(one string) string
+	}{
+		{false, func(string) string { return "side one" }},
+		{true, func(one string) string { return "side one\n\n(cherry picked from commit " + one + ")" }},
+	}
+	for _, tt := range tests {
+		one, _ := cherryPickRepo(t)
+		captureOutput(t, func() { CherryPick([]string{one}, false, tt.recordOrigin) })
+		c, _ := loadCommit(headCommitID())
+		orig, _ := loadCommit(one)
+		if c.ID == one || c.Author != orig.Author || c.Date != orig.Date {
+			t.Errorf("picked commit has author %q at %s, want %q at %s", c.Author, c.Date, orig.Author, orig.Date)
+		}
+		if !strings.HasPrefix(c.Committer, "Test User ") {
+			t.Errorf("picked commit has committer %q, want the current user", c.Committer)
+		}
+		if want := tt.wantMessage(one); c.Message != want {
+			t.Errorf("-x=%v: message is %q, want %q", tt.recordOrigin, c.Message, want)
+		}
+	}
+}
+
+func TestCherryPickConflictContinue(t *testing.T) {
+	one, _ := cherryPickRepo(t)
+	commitFiles(t, "main f", map[string]string{"f": "main\n"})
+
+	out := captureOutput(t, func() { CherryPick([]string{one}, false, false) })
+	if !sequencerInProgress() || !strings.Contains(out, "CONFLICT: f") {
+		t.Fatalf("cherry-pick did not stop on the conflict:\n%s", out)
+	}
+	data, _ := ioutil.ReadFile("f")
+	if !strings.Contains(string(data), "<<<<<<< HEAD\nmain\n=======\n2\n>>>>>>> ") {
+		t.Errorf("f has no conflict markers:\n%s", data)
+	}
+	out = captureOutput(t, SequencerContinue)
+	if !strings.Contains(out, "Conflicts remain in f") || !sequencerInProgress() {
+		t.Errorf("--continue went ahead with conflict markers left:\n%s", out)
+	}
+
+	writeFiles(t, map[string]string{"f": "resolved\n"})
+	out = captureOutput(t, SequencerContinue)
+	if sequencerInProgress() {
+		t.Fatalf("--continue did not finish:\n%s", out)
+	}
+	c, _ := loadCommit(headCommitID())
+	if c.Message != "side one" || commitTree(c.ID)["f"] != hashObject([]byte("resolved\n")) {
+		t.Errorf("HEAD is %q with f=%s after resolving", c.Message, commitTree(c.ID)["f"])
+	}
+}
+
+func TestCherryPickEmpty(t *testing.T) {
+	tests := []struct {
+		name    string
+		resume  func()
+		wantLog []string
+	}{
+		{"continue commits it", SequencerContinue, []string{"side one", "same as side one", "main change", "base"}},
+		{"skip leaves it out", SequencerSkip, []string{"same as side one", "main change", "base"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			one, _ := cherryPickRepo(t)
+			commitFiles(t, "same as side one", map[string]string{"f": "2\n"})
+
+			out := captureOutput(t, func() { CherryPick([]string{one}, false, false) })
+			if !strings.Contains(out, "The previous cherry-pick is now empty") || !sequencerInProgress() {
+				t.Fatalf("cherry-pick did not stop on the empty commit:\n%s", out)
+			}
+			out = captureOutput(t, tt.resume)
+			if sequencerInProgress() {
+				t.Errorf("the sequence did not finish:\n%s", out)
+			}
+			if got := firstParentSubjects(headCommitID()); !reflect.DeepEqual(got, tt.wantLog) {
+				t.Errorf("history is %q, want %q:\n%s", got, tt.wantLog, out)
+			}
+		})
+	}
+}
+
+func TestSequencerStopKeepsLocalChanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		resume     func()
+		editK      bool
+		wantLog    []string
+		wantF      string
+		wantRefuse bool
+	}{
+		{name: "abort", resume: SequencerAbort, wantLog: []string{"main f", "main change", "base"}, wantF: "main\n"},
+		{name: "skip", resume: SequencerSkip, wantLog: []string{"side two", "main f", "main change", "base"}, wantF: "main\n"},
+		{name: "abort over an edited step file", resume: SequencerAbort, editK: true, wantRefuse: true},
+		{name: "skip over an edited step file", resume: SequencerSkip, editK: true, wantRefuse: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, two := cherryPickRepo(t)
+			captureOutput(t, func() { CheckoutBranch("side") })
+			both := commitFiles(t, "side both", map[string]string{"f": "3\n", "k": "1\n"})
+			captureOutput(t, func() { CheckoutBranch("master") })
+			commitFiles(t, "main f", map[string]string{"f": "main\n"})
+			writeFiles(t, map[string]string{"g": "local edit\n"})
+
+			out := captureOutput(t, func() { CherryPick([]string{both, two}, false, false) })
+			if !sequencerInProgress() || !strings.Contains(out, "CONFLICT: f") {
+				t.Fatalf("cherry-pick did not stop on the conflict:\n%s", out)
+			}
+			if tt.editK {
+				writeFiles(t, map[string]string{"k": "edited after the stop\n"})
+			}
+			out = captureOutput(t, tt.resume)
+
+			if tt.wantRefuse {
+				if !strings.Contains(out, "your local changes to k would be overwritten") || !sequencerInProgress() {
+					t.Errorf("an edited file of the step was overwritten:\n%s", out)
+				}
+				if data, _ := ioutil.ReadFile("k"); string(data) != "edited after the stop\n" {
+					t.Errorf("k holds %q", data)
+				}
+				return
+			}
+			if sequencerInProgress() {
+				t.Fatalf("the sequence did not finish:\n%s", out)
+			}
+			if got := firstParentSubjects(headCommitID()); !reflect.DeepEqual(got, tt.wantLog) {
+				t.Errorf("history is %q, want %q:\n%s", got, tt.wantLog, out)
+			}
+			for file, want := range map[string]string{"g": "local edit\n", "f": tt.wantF, "k": ""} {
+				if data, _ := ioutil.ReadFile(file); string(data) != want {
+					t.Errorf("%s holds %q, want %q", file, data, want)
+				}
+			}
+			if len(readIndex()) != 0 {
+				t.Errorf("index is %v, want it empty", readIndex())
+			}
+		})
+	}
+}
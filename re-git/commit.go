@@ -0,0 +1,241 @@
+package regit
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// commitEntry is a parsed entry of the log. The log is append-only and holds
+// every commit ever created; branches and HEAD select which ones are current.
+type commitEntry struct {
+	ID         string
+	Parents    []string
+	Author     string
+	Date       string // when the change was authored
+	Committer  string
+	CommitDate string
+	Signature  string
+	Message    string
+	Files      map[string]string
+}
+
+func isObjectID(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+func shortID(id string) string {
+	if len(id) > 7 {
+		return id[:7]
+	}
+	return id
+}
+
+// commitBody renders everything below the "commit <id>" line. The ID of a
+// commit is the SHA-1 of this text.
+func commitBody(c *commitEntry) string {
+	var b strings.Builder
+	for _, p := range c.Parents {
+		fmt.Fprintf(&b, "Parent: %s\n", p)
+	}
+	if c.Author != "" {
+		fmt.Fprintf(&b, "Author: %s\n", c.Author)
+	}
+	fmt.Fprintf(&b, "Date: %s\n", c.Date)
+	if c.Committer != "" {
+		fmt.Fprintf(&b, "Committer: %s\n", c.Committer)
+		fmt.Fprintf(&b, "CommitDate: %s\n", c.CommitDate)
+	}
+	if c.Signature != "" {
+		fmt.Fprintf(&b, "Signature: %s\n", c.Signature)
+	}
+	b.WriteString("\n")
+	for _, line := range strings.Split(c.Message, "\n") {
+		fmt.Fprintf(&b, "    %s\n", line)
+	}
+	files := make([]string, 0, len(c.Files))
+	for f := range c.Files {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		fmt.Fprintf(&b, "%s %s\n", f, c.Files[f])
+	}
+	return b.String()
+}
+
+// parseCommit parses a single log entry. Entries written before commits had
+// IDs start with "commit <timestamp>" and only list the files staged for
+// them; they get an ID derived from their text, are chained onto the entry
+// before them and inherit the rest of its tree.
+func parseCommit(entry string, prev *commitEntry) commitEntry {
+	c := commitEntry{Files: map[string]string{}}
+	lines := strings.Split(strings.TrimLeft(entry, "\n"), "\n")
+	i := 0
+	if i < len(lines) && strings.HasPrefix(lines[i], "commit ") {
+		c.ID = strings.TrimPrefix(lines[i], "commit ")
+		i++
+	}
+	legacy := !isObjectID(c.ID)
+	for ; i < len(lines) && lines[i] != ""; i++ {
+		switch {
+		case strings.HasPrefix(lines[i], "Parent: "):
+			c.Parents = append(c.Parents, strings.TrimPrefix(lines[i], "Parent: "))
+		case strings.HasPrefix(lines[i], "Author: "):
+			c.Author = strings.TrimPrefix(lines[i], "Author: ")
+		case strings.HasPrefix(lines[i], "Date: "):
+			c.Date = strings.TrimPrefix(lines[i], "Date: ")
+		case strings.HasPrefix(lines[i], "Committer: "):
+			c.Committer = strings.TrimPrefix(lines[i], "Committer: ")
+		case strings.HasPrefix(lines[i], "CommitDate: "):
+			c.CommitDate = strings.TrimPrefix(lines[i], "CommitDate: ")
+		case strings.HasPrefix(lines[i], "Signature: "):
+			c.Signature = strings.TrimPrefix(lines[i], "Signature: ")
+		}
+	}
+	i++
+	// Message lines are indented by four spaces; older entries have a
+	// single unindented line.
+	var message []string
+	for ; i < len(lines) && strings.HasPrefix(lines[i], "    "); i++ {
+		message = append(message, strings.TrimPrefix(lines[i], "    "))
+	}
+	if len(message) == 0 && i < len(lines) {
+		message = append(message, lines[i])
+		i++
+	}
+	c.Message = strings.Join(message, "\n")
+	for ; i < len(lines); i++ {
+		parts := strings.Split(lines[i], " ")
+		if len(parts) == 2 {
+			c.Files[parts[0]] = parts[1]
+		}
+	}
+	if legacy {
+		c.ID = hashObject([]byte(entry))
+		if prev != nil {
+			c.Parents = []string{prev.ID}
+			for f, oid := range prev.Files {
+				if _, ok := c.Files[f]; !ok {
+					c.Files[f] = oid
+				}
+			}
+		}
+	}
+	return c
+}
+
+func readCommits() ([]commitEntry, error) {
+	log, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		return nil, err
+	}
+	entries := strings.Split(string(log), "---\n")
+	commits := make([]commitEntry, 0, len(entries))
+	var prev *commitEntry
+	for _, entry := range entries[:len(entries)-1] {
+		c := parseCommit(entry, prev)
+		commits = append(commits, c)
+		prev = &commits[len(commits)-1]
+	}
+	return commits, nil
+}
+
+func loadCommit(id string) (commitEntry, bool) {
+	commits, err := readCommits()
+	if err != nil {
+		return commitEntry{}, false
+	}
+	for _, c := range commits {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return commitEntry{}, false
+}
+
+// writeCommit assigns the commit its ID and appends it to the log.
+func writeCommit(c *commitEntry) error {
+	body := commitBody(c)
+	c.ID = hashObject([]byte(body))
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString("commit " + c.ID + "\n" + body + "---\n")
+	return err
+}
+
+// createCommit writes c as a new commit and moves HEAD to it, logging reason
+// in the reflog. The author and date are filled in unless the caller is
+// preserving those of an existing commit, and the commit is signed when
+// commit.sign is set. It fails when no identity is configured.
+func createCommit(c commitEntry, reason string) (string, error) {
+	if c.Committer == "" {
+		if err := fillIdentity(&c); err != nil {
+			return "", err
+		}
+	}
+	if c.Signature == "" && configBool("commit.sign", false) {
+		if err := signCommit(&c); err != nil {
+			return "", err
+		}
+	}
+	if err := writeCommit(&c); err != nil {
+		return "", err
+	}
+	return c.ID, updateRef("HEAD", c.ID, reason)
+}
+
+// amendHead replaces the HEAD commit with one recording tree and message,
+// keeping its parents, author and date.
+func amendHead(tree map[string]string, message, reason string) (string, error) {
+	head, ok := loadCommit(headCommitID())
+	if !ok {
+		return "", fmt.Errorf("no commit to amend")
+	}
+	return createCommit(commitEntry{Parents: head.Parents, Author: head.Author, Date: head.Date, Message: message, Files: tree}, reason)
+}
+
+// subject returns the first line of a commit message.
+func subject(message string) string {
+	first, _, _ := strings.Cut(message, "\n")
+	return first
+}
+
+func commitSubject(id string) string {
+	c, _ := loadCommit(id)
+	return subject(c.Message)
+}
+
+// mergeCommitLog appends the commits of srcLog that dstLog does not already
+// contain, so commits are never lost when logs are combined.
+func mergeCommitLog(dstLog, srcLog string) error {
+	srcData, err := ioutil.ReadFile(srcLog)
+	if err != nil {
+		return err
+	}
+	dstData, _ := ioutil.ReadFile(dstLog)
+	known := map[string]bool{}
+	for _, entry := range strings.Split(string(dstData), "---\n") {
+		known[strings.TrimSpace(entry)] = true
+	}
+	var b strings.Builder
+	b.Write(dstData)
+	for _, entry := range strings.Split(string(srcData), "---\n") {
+		if strings.TrimSpace(entry) == "" || known[strings.TrimSpace(entry)] {
+			continue
+		}
+		known[strings.TrimSpace(entry)] = true
+		b.WriteString(entry + "---\n")
+	}
+	return ioutil.WriteFile(dstLog, []byte(b.String()), 0644)
+}
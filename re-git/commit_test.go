@@ -0,0 +1,267 @@
+package regit
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCommit(t *testing.T) {
+	id := strings.Repeat("a", 40)
+	p1, p2 := strings.Repeat("1", 40), strings.Repeat("2", 40)
+	legacy := "commit 2024-01-02T03:04:05Z\nDate: 2024-01-02T03:04:05Z\n\nold message\nb.txt oid-b2\n"
+	tests := []struct {
+		name  string
+		entry string
+		prev  *commitEntry
+		want  commitEntry
+	}{
+		{
+			name: "all headers",
+			entry: "commit " + id + "\nParent: " + p1 + "\nAuthor: A U Thor <a@example.com>\nDate: 2024-01-02T03:04:05Z\n" +
+				"Committer: C O Mitter <c@example.com>\nCommitDate: 2024-01-03T00:00:00Z\nSignature: c2ln\n\n" +
+				"    subject line\n    \n    body text\na.txt oid-a\ndir/b.txt oid-b\n",
+			want: commitEntry{
+				ID: id, Parents: []string{p1},
+				Author: "A U Thor <a@example.com>", Date: "2024-01-02T03:04:05Z",
+				Committer: "C O Mitter <c@example.com>", CommitDate: "2024-01-03T00:00:00Z",
+				Signature: "c2ln", Message: "subject line\n\nbody text",
+				Files: map[string]string{"a.txt": "oid-a", "dir/b.txt": "oid-b"},
+			},
+		},
+		{
+			name:  "merge with two-word message",
+			entry: "commit " + id + "\nParent: " + p1 + "\nParent: " + p2 + "\nDate: d\n\n    fix bug\nf oid-f\n",
+			want: commitEntry{
+				ID: id, Parents: []string{p1, p2}, Date: "d", Message: "fix bug",
+				Files: map[string]string{"f": "oid-f"},
+			},
+		},
+		{
+			name:  "no files",
+			entry: "commit " + id + "\nDate: d\n\n    empty\n",
+			want:  commitEntry{ID: id, Date: "d", Message: "empty", Files: map[string]string{}},
+		},
+		{
+			name:  "legacy entry on its own",
+			entry: legacy,
+			want: commitEntry{
+				ID: hashObject([]byte(legacy)), Date: "2024-01-02T03:04:05Z", Message: "old message",
+				Files: map[string]string{"b.txt": "oid-b2"},
+			},
+		},
+		{
+			name:  "legacy entry inherits the tree before it",
+			entry: legacy,
+			prev:  &commitEntry{ID: p1, Files: map[string]string{"a.txt": "oid-a", "b.txt": "oid-b1"}},
+			want: commitEntry{
+				ID: hashObject([]byte(legacy)), Parents: []string{p1}, Date: "2024-01-02T03:04:05Z", Message: "old message",
+				Files: map[string]string{"a.txt": "oid-a", "b.txt": "oid-b2"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCommit(tt.entry, tt.prev); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCommit() = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitBodyRoundTrip(t *testing.T) {
+	c := commitEntry{
+		Parents: []string{strings.Repeat("1", 40)},
+		Author:  "A <a@example.com>", Date: "2024-01-02T03:04:05Z",
+		Committer: "C <c@example.com>", CommitDate: "2024-01-02T03:04:06Z",
+		Message: "subject\n\n  indented body", Files: map[string]string{"f": "oid-f", "dir/g": "oid-g"},
+	}
+	body := commitBody(&c)
+	c.ID = hashObject([]byte(body))
+	got := parseCommit("commit "+c.ID+"\n"+body, nil)
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("parseCommit(commitBody(c)) = %+v\nwant %+v", got, c)
+	}
+}
+
+func TestCommitOptions(t *testing.T) {
+	// base: a=1 b=1; second (by Orig Author): a=2. Then a is changed to 3
+	// without staging it and b is changed to 2 and staged.
+	tests := []struct {
+		name        string
+		message     string
+		paths       []string
+		opts        CommitOptions
+		wantOut     string
+		wantLog     []string // nil when no commit should be made
+		wantMessage string
+		wantAuthor  string
+		wantTree    map[string]string
+		wantStaged  []string
+	}{
+		{
+			name:        "staged changes",
+			message:     "third",
+			wantLog:     []string{"third", "second", "base"},
+			wantMessage: "third",
+			wantAuthor:  "Test User <test@example.com>",
+			wantTree:    map[string]string{"a": "2\n", "b": "2\n"},
+		},
+		{
+			name:        "all",
+			message:     "third",
+			opts:        CommitOptions{All: true},
+			wantLog:     []string{"third", "second", "base"},
+			wantMessage: "third",
+			wantAuthor:  "Test User <test@example.com>",
+			wantTree:    map[string]string{"a": "3\n", "b": "2\n"},
+		},
+		{
+			name:        "paths",
+			message:     "only a",
+			paths:       []string{"a"},
+			wantLog:     []string{"only a", "second", "base"},
+			wantMessage: "only a",
+			wantAuthor:  "Test User <test@example.com>",
+			wantTree:    map[string]string{"a": "3\n", "b": "1\n"},
+			wantStaged:  []string{"b"},
+		},
+		{
+			name:    "paths with all",
+			message: "x",
+			paths:   []string{"a"},
+			opts:    CommitOptions{All: true},
+			wantOut: "Paths cannot be used with -a",
+		},
+		{
+			name:    "unknown path",
+			message: "x",
+			paths:   []string{"nope"},
+			wantOut: "pathspec 'nope' did not match any file known to re-git",
+		},
+		{
+			name:        "amend with a message",
+			message:     "second, fixed",
+			opts:        CommitOptions{Amend: true},
+			wantLog:     []string{"second, fixed", "base"},
+			wantMessage: "second, fixed",
+			wantAuthor:  "Orig Author <orig@example.com>",
+			wantTree:    map[string]string{"a": "2\n", "b": "2\n"},
+		},
+		{
+			name:        "amend keeping the message",
+			opts:        CommitOptions{Amend: true, NoEdit: true},
+			wantLog:     []string{"second", "base"},
+			wantMessage: "second\n\nbody",
+			wantAuthor:  "Orig Author <orig@example.com>",
+			wantTree:    map[string]string{"a": "2\n", "b": "2\n"},
+		},
+		{
+			name:        "amend with a new author",
+			opts:        CommitOptions{Amend: true, NoEdit: true, Author: "New <new@example.com>"},
+			wantLog:     []string{"second", "base"},
+			wantMessage: "second\n\nbody",
+			wantAuthor:  "New <new@example.com>",
+			wantTree:    map[string]string{"a": "2\n", "b": "2\n"},
+		},
+		{
+			name:        "amend all",
+			opts:        CommitOptions{Amend: true, NoEdit: true, All: true},
+			wantLog:     []string{"second", "base"},
+			wantMessage: "second\n\nbody",
+			wantAuthor:  "Orig Author <orig@example.com>",
+			wantTree:    map[string]string{"a": "3\n", "b": "2\n"},
+		},
+		{
+			name:        "fixup",
+			opts:        CommitOptions{Fixup: "HEAD~1"},
+			wantLog:     []string{"fixup! base", "second", "base"},
+			wantMessage: "fixup! base",
+			wantAuthor:  "Test User <test@example.com>",
+			wantTree:    map[string]string{"a": "2\n", "b": "2\n"},
+		},
+		{
+			name:        "squash with a message",
+			message:     "more detail",
+			opts:        CommitOptions{Squash: "HEAD"},
+			wantLog:     []string{"squash! second", "second", "base"},
+			wantMessage: "squash! second\n\nmore detail",
+			wantAuthor:  "Test User <test@example.com>",
+			wantTree:    map[string]string{"a": "2\n", "b": "2\n"},
+		},
+		{
+			name:    "fixup of an unknown revision",
+			opts:    CommitOptions{Fixup: "nope"},
+			wantOut: "nope",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			commitFiles(t, "base", map[string]string{"a": "1\n", "b": "1\n"})
+			writeFiles(t, map[string]string{"a": "2\n"})
+			captureOutput(t, func() {
+				Add("a")
+				Commit("second\n\nbody", nil, CommitOptions{Author: "Orig Author <orig@example.com>"})
+			})
+			before := headCommitID()
+			writeFiles(t, map[string]string{"a": "3\n", "b": "2\n"})
+			captureOutput(t, func() { Add("b") })
+
+			out := captureOutput(t, func() { Commit(tt.message, tt.paths, tt.opts) })
+			if !strings.Contains(out, tt.wantOut) {
+				t.Errorf("output does not mention %q:\n%s", tt.wantOut, out)
+			}
+			head := headCommitID()
+			if tt.wantLog == nil {
+				if head != before {
+					t.Errorf("a commit was made:\n%s", out)
+				}
+				return
+			}
+			if got := firstParentSubjects(head); !reflect.DeepEqual(got, tt.wantLog) {
+				t.Fatalf("history is %q, want %q:\n%s", got, tt.wantLog, out)
+			}
+			c, _ := loadCommit(head)
+			if c.Message != tt.wantMessage || c.Author != tt.wantAuthor {
+				t.Errorf("commit has message %q by %q, want %q by %q", c.Message, c.Author, tt.wantMessage, tt.wantAuthor)
+			}
+			got := map[string]string{}
+			for f, oid := range c.Files {
+				data, _ := readObject(oid)
+				got[f] = string(data)
+			}
+			if !reflect.DeepEqual(got, tt.wantTree) {
+				t.Errorf("commit tree is %q, want %q", got, tt.wantTree)
+			}
+			if staged := changedFiles(c.Files, stagedTree()); !reflect.DeepEqual(staged, tt.wantStaged) {
+				t.Errorf("still staged: %q, want %q", staged, tt.wantStaged)
+			}
+			if data, _ := ioutil.ReadFile("a"); string(data) != "3\n" {
+				t.Errorf("working copy of a is %q, want it untouched", data)
+			}
+		})
+	}
+}
+
+func TestAmendWithoutCommits(t *testing.T) {
+	testRepo(t)
+	writeFiles(t, map[string]string{"f": "1\n"})
+	captureOutput(t, func() { Add("f") })
+	out := captureOutput(t, func() { Commit("x", nil, CommitOptions{Amend: true}) })
+	if out != "No commit to amend\n" || headCommitID() != "" {
+		t.Errorf("amend with no commits printed %q", out)
+	}
+}
+
+func TestAmendReflog(t *testing.T) {
+	testRepo(t)
+	first := commitFiles(t, "first", map[string]string{"f": "1\n"})
+	captureOutput(t, func() { Commit("first, reworded", nil, CommitOptions{Amend: true}) })
+	entries := readReflog("HEAD")
+	last := entries[len(entries)-1]
+	if last.Old != first || last.Reason != "commit (amend): first, reworded" {
+		t.Errorf("last reflog entry is %+v", last)
+	}
+}
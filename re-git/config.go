@@ -0,0 +1,510 @@
+package regit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Configuration is read from up to four INI-style files, later scopes
+// overriding earlier ones:
+//
+//	system    $REGIT_CONFIG_SYSTEM or /etc/regitconfig
+//	global    $REGIT_CONFIG_GLOBAL or ~/.regitconfig
+//	local     .git/config
+//	worktree  .git/config.worktree
+//
+// A file holds sections of "name = value" lines:
+//
+//	[user]
+//		name = Alice
+//	[remote "origin"]
+//		url = ../origin
+//
+// which define user.name and remote.origin.url. Section and key names are
+// case-insensitive, subsection names are not. A key may be given more than
+// once, and include.path pulls in another file at that point. Lines of the
+// form "section.key=value" outside any section, as written by older
+// versions, are still understood.
+
+var configScopes = []string{"system", "global", "local", "worktree"}
+
+const maxConfigIncludeDepth = 10
+
+// configEntry is one value of a key, with the file it came from.
+type configEntry struct {
+	Key   string
+	Value string
+	File  string
+}
+
+// ConfigOptions select the scope a config command works on, how values
+// are interpreted (Type is "", "bool", "int" or "path"), and whether their
+// origin is shown.
+type ConfigOptions struct {
+	Scope      string
+	Type       string
+	ShowOrigin bool
+}
+
+func configScopeFile(scope string) string {
+	switch scope {
+	case "system":
+		if path := os.Getenv("REGIT_CONFIG_SYSTEM"); path != "" {
+			return path
+		}
+		return "/etc/regitconfig"
+	case "global":
+		if path := os.Getenv("REGIT_CONFIG_GLOBAL"); path != "" {
+			return path
+		}
+		return expandHome("~/.regitconfig")
+	case "worktree":
+		return configFile + ".worktree"
+	}
+	return configFile
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// canonicalKey lowercases the section and key name of a dotted key, leaving
+// any subsection alone, and reports whether the key is well formed.
+func canonicalKey(key string) (string, bool) {
+	first, last := strings.Index(key, "."), strings.LastIndex(key, ".")
+	if first <= 0 || last == len(key)-1 {
+		return "", false
+	}
+	section, name := strings.ToLower(key[:first]), strings.ToLower(key[last+1:])
+	for _, r := range section {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return "", false
+		}
+	}
+	for i, r := range name {
+		if !(r >= 'a' && r <= 'z' || i > 0 && (r >= '0' && r <= '9' || r == '-')) {
+			return "", false
+		}
+	}
+	if first == last {
+		return section + "." + name, true
+	}
+	return section + key[first:last+1] + name, true
+}
+
+// parseSectionHeader turns "[section]" or `[section "sub"]` into the key
+// prefix "section" or "section.sub".
+func parseSectionHeader(line string) (string, bool) {
+	if !strings.HasPrefix(line, "[") {
+		return "", false
+	}
+	end := strings.LastIndex(line, "]")
+	if end < 0 {
+		return "", false
+	}
+	inner := strings.TrimSpace(line[1:end])
+	name, sub, hasSub := strings.Cut(inner, " ")
+	name = strings.ToLower(name)
+	if !hasSub {
+		// [section.sub] is an old spelling of [section "sub"].
+		if s, rest, ok := strings.Cut(name, "."); ok {
+			return s + "." + rest, true
+		}
+		return name, name != ""
+	}
+	sub = strings.TrimSpace(sub)
+	if len(sub) < 2 || sub[0] != '"' || sub[len(sub)-1] != '"' {
+		return "", false
+	}
+	sub = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(sub[1 : len(sub)-1])
+	return name + "." + sub, true
+}
+
+// parseConfigValue strips comments and quotes from the text after "=".
+func parseConfigValue(raw string) string {
+	var b strings.Builder
+	quoted, pendingSpace := false, ""
+	for i := 0; i < len(raw); i++ {
+		ch := raw[i]
+		switch {
+		case ch == '\\' && i+1 < len(raw):
+			i++
+			b.WriteString(pendingSpace)
+			pendingSpace = ""
+			switch raw[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(raw[i])
+			}
+		case ch == '"':
+			quoted = !quoted
+		case !quoted && (ch == '#' || ch == ';'):
+			return b.String()
+		case !quoted && (ch == ' ' || ch == '\t'):
+			if b.Len() > 0 {
+				pendingSpace += string(ch)
+			}
+		default:
+			b.WriteString(pendingSpace)
+			pendingSpace = ""
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+// formatConfigValue quotes and escapes value where the parser needs it.
+func formatConfigValue(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(value)
+	if value != strings.TrimSpace(value) || strings.ContainsAny(value, "#;") {
+		return `"` + escaped + `"`
+	}
+	return escaped
+}
+
+// parseConfigLine splits a line into its key (relative to the section it is
+// in) and value. A bare key means true.
+func parseConfigLine(line string) (name, value string, ok bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' || line[0] == ';' || line[0] == '[' {
+		return "", "", false
+	}
+	name, raw, hasValue := strings.Cut(line, "=")
+	name = strings.TrimSpace(name)
+	if !hasValue {
+		if i := strings.IndexAny(name, "#;"); i >= 0 {
+			name = strings.TrimSpace(name[:i])
+		}
+		return name, "true", name != ""
+	}
+	return name, parseConfigValue(strings.TrimSpace(raw)), name != ""
+}
+
+// readConfigFile appends the entries of path, and of any files it
+// includes, to entries.
+func readConfigFile(path string, depth int, entries []configEntry) []configEntry {
+	data, err := ioutil.ReadFile(path)
+	if err != nil || depth > maxConfigIncludeDepth {
+		return entries
+	}
+	section := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if prefix, ok := parseSectionHeader(trimmed); ok {
+			section = prefix
+			continue
+		}
+		name, value, ok := parseConfigLine(trimmed)
+		if !ok {
+			continue
+		}
+		full := name
+		if section != "" {
+			full = section + "." + name
+		}
+		key, valid := canonicalKey(full)
+		if !valid {
+			continue
+		}
+		entries = append(entries, configEntry{Key: key, Value: value, File: path})
+		if key == "include.path" {
+			include := expandHome(value)
+			if !filepath.IsAbs(include) {
+				include = filepath.Join(filepath.Dir(path), include)
+			}
+			entries = readConfigFile(include, depth+1, entries)
+		}
+	}
+	return entries
+}
+
+// readConfig returns every entry of the given scopes (all of them when
+// scope is ""), lowest precedence first.
+func readConfig(scope string) []configEntry {
+	var entries []configEntry
+	for _, s := range configScopes {
+		if scope == "" || scope == s {
+			entries = readConfigFile(configScopeFile(s), 0, entries)
+		}
+	}
+	return entries
+}
+
+func configValues(key, scope string) []configEntry {
+	key, ok := canonicalKey(key)
+	if !ok {
+		return nil
+	}
+	var values []configEntry
+	for _, e := range readConfig(scope) {
+		if e.Key == key {
+			values = append(values, e)
+		}
+	}
+	return values
+}
+
+// configValue returns the value of key, or "" if it is not set. When a key
+// is set more than once the last value wins.
+func configValue(key string) string {
+	values := configValues(key, "")
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1].Value
+}
+
+func parseConfigBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("bad boolean value %q", value)
+}
+
+// parseConfigInt accepts an integer with an optional k, m or g suffix.
+func parseConfigInt(value string) (int64, error) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	scale := int64(1)
+	if v != "" {
+		switch v[len(v)-1] {
+		case 'k':
+			scale = 1 << 10
+		case 'm':
+			scale = 1 << 20
+		case 'g':
+			scale = 1 << 30
+		}
+		if scale > 1 {
+			v = v[:len(v)-1]
+		}
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad numeric value %q", value)
+	}
+	return n * scale, nil
+}
+
+// configBool returns the boolean value of key, or def when it is unset or
+// not a boolean.
+func configBool(key string, def bool) bool {
+	values := configValues(key, "")
+	if len(values) == 0 {
+		return def
+	}
+	b, err := parseConfigBool(values[len(values)-1].Value)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// configInt returns the integer value of key, or def when it is unset or
+// not a number.
+func configInt(key string, def int64) int64 {
+	n, err := parseConfigInt(configValue(key))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// configPath returns the value of key with a leading ~ expanded.
+func configPath(key string) string {
+	return expandHome(configValue(key))
+}
+
+// typedConfigValue canonicalises value according to a --type option.
+func typedConfigValue(value, typ string) (string, error) {
+	switch typ {
+	case "bool":
+		b, err := parseConfigBool(value)
+		return strconv.FormatBool(b), err
+	case "int":
+		n, err := parseConfigInt(value)
+		return strconv.FormatInt(n, 10), err
+	case "path":
+		return expandHome(value), nil
+	case "":
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown type %q", typ)
+}
+
+func printConfigEntry(e configEntry, withKey bool, opts ConfigOptions) bool {
+	value, err := typedConfigValue(e.Value, opts.Type)
+	if err != nil {
+		fmt.Printf("%s: %v\n", e.Key, err)
+		return false
+	}
+	if opts.ShowOrigin {
+		fmt.Printf("file:%s\t", e.File)
+	}
+	if withKey {
+		fmt.Printf("%s=%s\n", e.Key, value)
+	} else {
+		fmt.Println(value)
+	}
+	return true
+}
+
+// ConfigGet prints the value of key that takes precedence, reporting
+// whether it was set.
+func ConfigGet(key string, opts ConfigOptions) bool {
+	values := configValues(key, opts.Scope)
+	if len(values) == 0 {
+		return false
+	}
+	return printConfigEntry(values[len(values)-1], false, opts)
+}
+
+// ConfigGetAll prints every value of a multi-valued key.
+func ConfigGetAll(key string, opts ConfigOptions) bool {
+	values := configValues(key, opts.Scope)
+	for _, e := range values {
+		if !printConfigEntry(e, false, opts) {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+func ConfigList(opts ConfigOptions) {
+	for _, e := range readConfig(opts.Scope) {
+		printConfigEntry(e, true, opts)
+	}
+}
+
+// ConfigSet sets key in the file of opts.Scope (local by default). With
+// add a further value is added instead of replacing the existing one.
+func ConfigSet(key, value string, add bool, opts ConfigOptions) bool {
+	if opts.Type != "" {
+		if _, err := typedConfigValue(value, opts.Type); err != nil {
+			fmt.Println(err)
+			return false
+		}
+	}
+	if err := editConfig(key, &value, add, false, opts.Scope); err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
+
+// ConfigUnset removes key from the file of opts.Scope; all must be set to
+// remove a key with several values.
+func ConfigUnset(key string, all bool, opts ConfigOptions) bool {
+	if err := editConfig(key, nil, false, all, opts.Scope); err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
+
+// editConfig rewrites one config file, setting key to *value (adding a
+// line when add is set) or removing it when value is nil.
+func editConfig(key string, value *string, add, all bool, scope string) error {
+	canonical, ok := canonicalKey(key)
+	if !ok {
+		return fmt.Errorf("invalid key: %s", key)
+	}
+	if scope == "" {
+		scope = "local"
+	}
+	path := configScopeFile(scope)
+	data, _ := ioutil.ReadFile(path)
+	text := strings.TrimSuffix(string(data), "\n")
+	var lines []string
+	if text != "" {
+		lines = strings.Split(text, "\n")
+	}
+
+	first, last := strings.Index(key, "."), strings.LastIndex(key, ".")
+	prefix, name := canonical[:last], key[last+1:]
+	var line string
+	if value != nil {
+		line = "\t" + name + " = " + formatConfigValue(*value)
+	}
+	section, sectionEnd := "", -1
+	var matches []int
+	legacy := map[int]bool{}
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if p, ok := parseSectionHeader(trimmed); ok {
+			section = p
+			if section == prefix {
+				sectionEnd = i
+			}
+			continue
+		}
+		n, _, ok := parseConfigLine(trimmed)
+		if !ok {
+			continue
+		}
+		full := n
+		if section != "" {
+			full = section + "." + n
+		}
+		if k, _ := canonicalKey(full); k == canonical {
+			matches = append(matches, i)
+			legacy[i] = section == ""
+		}
+		if section == prefix {
+			sectionEnd = i
+		}
+	}
+
+	switch {
+	case value == nil:
+		if len(matches) == 0 {
+			return fmt.Errorf("key not found: %s", key)
+		}
+		if len(matches) > 1 && !all {
+			return fmt.Errorf("%s has multiple values; use --unset-all", key)
+		}
+		for i := len(matches) - 1; i >= 0; i-- {
+			lines = append(lines[:matches[i]], lines[matches[i]+1:]...)
+		}
+	case len(matches) > 1 && !add:
+		return fmt.Errorf("%s has multiple values; use --add or --unset-all", key)
+	case len(matches) == 1 && !add:
+		if i := matches[0]; legacy[i] {
+			lines[i] = key + "=" + formatConfigValue(*value)
+		} else {
+			lines[i] = line
+		}
+	case sectionEnd >= 0:
+		lines = append(lines[:sectionEnd+1], append([]string{line}, lines[sectionEnd+1:]...)...)
+	default:
+		header := "[" + canonical[:first] + "]"
+		if first < last {
+			sub := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key[first+1 : last])
+			header = "[" + canonical[:first] + ` "` + sub + `"]`
+		}
+		lines = append(lines, header, line)
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		os.MkdirAll(dir, 0755)
+	}
+	out := strings.Join(lines, "\n")
+	if out != "" {
+		out += "\n"
+	}
+	return ioutil.WriteFile(path, []byte(out), 0644)
+}
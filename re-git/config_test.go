@@ -0,0 +1,363 @@
+package regit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCanonicalKey(t *testing.T) {
+	tests := []struct {
+		key, want string
+		wantOK    bool
+	}{
+		{"user.name", "user.name", true},
+		{"User.Name", "user.name", true},
+		{`Remote.Origin.URL`, "remote.Origin.url", true},
+		{"branch.feature/x.Merge", "branch.feature/x.merge", true},
+		{"a.b.c.d", "a.b.c.d", true},
+		{"core.key2", "core.key2", true},
+		{"my-section.my-key", "my-section.my-key", true},
+		{"user", "", false},
+		{".name", "", false},
+		{"user.", "", false},
+		{"user.2name", "", false},
+		{"us_er.name", "", false},
+		{"user.na_me", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := canonicalKey(tt.key)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("canonicalKey(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestParseSectionHeader(t *testing.T) {
+	tests := []struct {
+		line, want string
+		wantOK     bool
+	}{
+		{"[user]", "user", true},
+		{"[Core]", "core", true},
+		{`[remote "origin"]`, "remote.origin", true},
+		{`[Remote "Origin"]`, "remote.Origin", true},
+		{`[section "with \"quotes\" and \\"]`, `section.with "quotes" and \`, true},
+		{`[ remote   "a b" ]`, "remote.a b", true},
+		{"[branch.main]", "branch.main", true},
+		{"[user] # comment", "user", true},
+		{"[]", "", false},
+		{"[user", "", false},
+		{`[remote origin]`, "", false},
+		{"user.name = x", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseSectionHeader(tt.line)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseSectionHeader(%q) = %q, %v, want %q, %v", tt.line, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestParseConfigLine(t *testing.T) {
+	tests := []struct {
+		line, name, value string
+		ok                bool
+	}{
+		{"name = Alice", "name", "Alice", true},
+		{"  name=Alice  ", "name", "Alice", true},
+		{"name = Alice Smith", "name", "Alice Smith", true},
+		{"name = Alice   # who", "name", "Alice", true},
+		{"name = Alice ; who", "name", "Alice", true},
+		{`name = "  padded  "`, "name", "  padded  ", true},
+		{`name = "has # hash"`, "name", "has # hash", true},
+		{`name = a\tb\nc\"d\\`, "name", "a\tb\nc\"d\\", true},
+		{`name = half"quoted # part"`, "name", "halfquoted # part", true},
+		{"name =", "name", "", true},
+		{"bare", "bare", "true", true},
+		{"bare # comment", "bare", "true", true},
+		{"user.name=Legacy", "user.name", "Legacy", true},
+		{"", "", "", false},
+		{"# comment", "", "", false},
+		{"; comment", "", "", false},
+		{"[section]", "", "", false},
+		{"= value", "", "value", false},
+	}
+	for _, tt := range tests {
+		name, value, ok := parseConfigLine(tt.line)
+		if name != tt.name || value != tt.value || ok != tt.ok {
+			t.Errorf("parseConfigLine(%q) = %q, %q, %v, want %q, %q, %v", tt.line, name, value, ok, tt.name, tt.value, tt.ok)
+		}
+	}
+}
+
+func TestFormatConfigValue(t *testing.T) {
+	for _, value := range []string{"plain", "two words", " leading", "trailing ", "a#b", "a;b", `back\slash`, `"quoted"`, "tab\there", "new\nline", ""} {
+		formatted := formatConfigValue(value)
+		if got := parseConfigValue(formatted); got != value {
+			t.Errorf("%q formats as %q, which reads back as %q", value, formatted, got)
+		}
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main": `# top comment
+core.legacy = old style
+[user]
+	name = Alice   ; trailing comment
+	email = "alice@example.com"
+[Remote "Origin"]
+	URL = ../origin
+	fetch = one
+	fetch = two
+[branch.main]
+	remote = origin
+[core]
+	bare
+	bad_key = ignored
+	include
+[include]
+	path = extra
+[after]
+	key = last
+`,
+		"extra":    "[user]\n\tname = Included\n[include]\n\tpath = sub/more\n",
+		"sub/more": "[deep]\n\tkey = relative to extra\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	main, extra, more := filepath.Join(dir, "main"), filepath.Join(dir, "extra"), filepath.Join(dir, "sub/more")
+	want := []configEntry{
+		{"core.legacy", "old style", main},
+		{"user.name", "Alice", main},
+		{"user.email", "alice@example.com", main},
+		{"remote.Origin.url", "../origin", main},
+		{"remote.Origin.fetch", "one", main},
+		{"remote.Origin.fetch", "two", main},
+		{"branch.main.remote", "origin", main},
+		{"core.bare", "true", main},
+		{"core.include", "true", main},
+		{"include.path", "extra", main},
+		{"user.name", "Included", extra},
+		{"include.path", "sub/more", extra},
+		{"deep.key", "relative to extra", more},
+		{"after.key", "last", main},
+	}
+	if got := readConfigFile(main, 0, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("readConfigFile read\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestReadConfigFileIncludeLoop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loop")
+	ioutil.WriteFile(path, []byte("[include]\n\tpath = loop\n[a]\n\tb = c\n"), 0644)
+	entries := readConfigFile(path, 0, nil)
+	if n := len(entries); n != 2*(maxConfigIncludeDepth+1) {
+		t.Errorf("a self-include read %d entries, want the loop cut off after %d levels", n, maxConfigIncludeDepth)
+	}
+}
+
+func TestParseConfigBool(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    bool
+		wantErr bool
+	}{
+		{"true", true, false},
+		{"Yes", true, false},
+		{"on", true, false},
+		{"1", true, false},
+		{"false", false, false},
+		{"NO", false, false},
+		{"off", false, false},
+		{"0", false, false},
+		{"", false, false},
+		{"2", false, true},
+		{"maybe", false, true},
+	}
+	for _, tt := range tests {
+		got, err := parseConfigBool(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseConfigBool(%q) = %v, %v, want %v (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseConfigInt(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"42", 42, false},
+		{" -7 ", -7, false},
+		{"1k", 1024, false},
+		{"2M", 2 << 20, false},
+		{"3g", 3 << 30, false},
+		{"", 0, true},
+		{"k", 0, true},
+		{"1.5", 0, true},
+		{"10x", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseConfigInt(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseConfigInt(%q) = %d, %v, want %d (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestConfigScopes(t *testing.T) {
+	testRepo(t)
+	captureOutput(t, func() {
+		ConfigSet("core.editor", "system-editor", false, ConfigOptions{Scope: "system"})
+		ConfigSet("core.pager", "system-pager", false, ConfigOptions{Scope: "system"})
+		ConfigSet("core.editor", "global-editor", false, ConfigOptions{Scope: "global"})
+		ConfigSet("core.editor", "local-editor", false, ConfigOptions{})
+		ConfigSet("core.big", "2k", false, ConfigOptions{Scope: "worktree"})
+		ConfigSet("core.flag", "yes", false, ConfigOptions{Scope: "global"})
+	})
+
+	tests := []struct {
+		name string
+		run  func()
+		want string
+	}{
+		{"local wins", func() { ConfigGet("core.editor", ConfigOptions{}) }, "local-editor\n"},
+		{"scope", func() { ConfigGet("core.editor", ConfigOptions{Scope: "global"}) }, "global-editor\n"},
+		{"lower scope only", func() { ConfigGet("Core.Pager", ConfigOptions{}) }, "system-pager\n"},
+		{"get all", func() { ConfigGetAll("core.editor", ConfigOptions{}) }, "system-editor\nglobal-editor\nlocal-editor\n"},
+		{"int", func() { ConfigGet("core.big", ConfigOptions{Type: "int"}) }, "2048\n"},
+		{"bool", func() { ConfigGet("core.flag", ConfigOptions{Type: "bool"}) }, "true\n"},
+		{"bad bool", func() { ConfigGet("core.editor", ConfigOptions{Type: "bool"}) }, "core.editor: bad boolean value \"local-editor\"\n"},
+		{"origin", func() { ConfigGet("core.pager", ConfigOptions{ShowOrigin: true}) }, "file:" + os.Getenv("REGIT_CONFIG_SYSTEM") + "\tsystem-pager\n"},
+		{"list scope", func() { ConfigList(ConfigOptions{Scope: "global"}) }, "core.editor=global-editor\ncore.flag=yes\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := captureOutput(t, tt.run); got != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if !configBool("core.flag", false) || configInt("core.big", 0) != 2048 || configInt("core.editor", 5) != 5 {
+		t.Error("typed getters did not read the merged config")
+	}
+}
+
+func TestConfigSetAndUnset(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial string
+		run     func() bool
+		wantOK  bool
+		want    string
+	}{
+		{
+			name:   "new section",
+			run:    func() bool { return ConfigSet("user.name", "Alice", false, ConfigOptions{}) },
+			wantOK: true,
+			want:   "[user]\n\tname = Alice\n",
+		},
+		{
+			name:   "subsection",
+			run:    func() bool { return ConfigSet(`remote.My "Repo".URL`, "../x", false, ConfigOptions{}) },
+			wantOK: true,
+			want:   "[remote \"My \\\"Repo\\\"\"]\n\tURL = ../x\n",
+		},
+		{
+			name:    "into existing section",
+			initial: "[user]\n\tname = Alice\n[core]\n\tpager = less\n",
+			run:     func() bool { return ConfigSet("user.email", "a@example.com", false, ConfigOptions{}) },
+			wantOK:  true,
+			want:    "[user]\n\tname = Alice\n\temail = a@example.com\n[core]\n\tpager = less\n",
+		},
+		{
+			name:    "replace",
+			initial: "[user]\n\tname = Alice # me\n",
+			run:     func() bool { return ConfigSet("User.Name", "Bob Smith", false, ConfigOptions{}) },
+			wantOK:  true,
+			want:    "[user]\n\tName = Bob Smith\n",
+		},
+		{
+			name:    "replace legacy line",
+			initial: "user.name=Alice\n",
+			run:     func() bool { return ConfigSet("user.name", " padded", false, ConfigOptions{}) },
+			wantOK:  true,
+			want:    "user.name=\" padded\"\n",
+		},
+		{
+			name:    "add",
+			initial: "[remote \"o\"]\n\tfetch = one\n",
+			run:     func() bool { return ConfigSet("remote.o.fetch", "two", true, ConfigOptions{}) },
+			wantOK:  true,
+			want:    "[remote \"o\"]\n\tfetch = one\n\tfetch = two\n",
+		},
+		{
+			name:    "replace multi-valued",
+			initial: "[a]\n\tb = 1\n\tb = 2\n",
+			run:     func() bool { return ConfigSet("a.b", "3", false, ConfigOptions{}) },
+			want:    "[a]\n\tb = 1\n\tb = 2\n",
+		},
+		{
+			name:    "unset",
+			initial: "[a]\n\tb = 1\n\tc = 2\n",
+			run:     func() bool { return ConfigUnset("a.b", false, ConfigOptions{}) },
+			wantOK:  true,
+			want:    "[a]\n\tc = 2\n",
+		},
+		{
+			name:    "unset multi-valued",
+			initial: "[a]\n\tb = 1\n\tb = 2\n",
+			run:     func() bool { return ConfigUnset("a.b", false, ConfigOptions{}) },
+			want:    "[a]\n\tb = 1\n\tb = 2\n",
+		},
+		{
+			name:    "unset all",
+			initial: "[a]\n\tb = 1\nc = x\n\tb = 2\n",
+			run:     func() bool { return ConfigUnset("a.b", true, ConfigOptions{}) },
+			wantOK:  true,
+			want:    "[a]\nc = x\n",
+		},
+		{
+			name:    "unset missing",
+			initial: "[a]\n\tb = 1\n",
+			run:     func() bool { return ConfigUnset("a.c", false, ConfigOptions{}) },
+			want:    "[a]\n\tb = 1\n",
+		},
+		{
+			name: "invalid key",
+			run:  func() bool { return ConfigSet("nodot", "x", false, ConfigOptions{}) },
+		},
+		{
+			name: "bad typed value",
+			run:  func() bool { return ConfigSet("a.b", "x", false, ConfigOptions{Type: "int"}) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			if err := ioutil.WriteFile(configFile, []byte(tt.initial), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var ok bool
+			out := captureOutput(t, func() { ok = tt.run() })
+			if ok != tt.wantOK {
+				t.Errorf("ok = %v, want %v (output %q)", ok, tt.wantOK, out)
+			}
+			if got, _ := ioutil.ReadFile(configFile); string(got) != tt.want {
+				t.Errorf("config file is\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -19,10 +19,11 @@ const (
 	headFile   = ".git/HEAD"
 	refsDir    = ".git/refs"
 	headsDir   = ".git/refs/heads"
+	tagsDir    = ".git/refs/tags"
+	logsDir    = ".git/logs"
+	configFile = ".git/config"
 )
 
-var stashFile = ".git/stash"
-
 func Init() {
 	os.Mkdir(repoDir, 0755)
 	os.Mkdir(objectsDir, 0755)
@@ -60,19 +61,227 @@ func Add(file string) {
 	fmt.Println("Added", file)
 }
 
-func Commit(message string) {
-	index, err := ioutil.ReadFile(indexFile)
-	if err != nil || len(index) == 0 {
+// CommitOptions say where the message comes from and what kind of commit
+// to make, override the author and date recorded for it, ask for it to be
+// signed, and skip the pre-commit and commit-msg hooks.
+type CommitOptions struct {
+	MessageFile string // read the message from this file ("-" for stdin)
+	Edit        bool   // edit the given message in the editor
+	NoEdit      bool   // keep the amended commit's message as it is
+	All         bool   // stage changes to tracked files first
+	Amend       bool   // replace HEAD instead of adding a commit on top
+	Fixup       string // make a "fixup! <subject>" commit for this revision
+	Squash      string // make a "squash! <subject>" commit for this revision
+	Author      string // "Name <email>"
+	Date        string
+	Sign        bool
+	NoVerify    bool
+}
+
+// Commit records the staged changes, or only the working copies of paths
+// when any are given. Without a message (or MessageFile) the message is
+// written in the editor.
+func Commit(message string, paths []string, opts CommitOptions) {
+	head, hasHead := loadCommit(headCommitID())
+	if opts.Amend && !hasHead {
+		fmt.Println("No commit to amend")
+		return
+	}
+	if opts.All && len(paths) > 0 {
+		fmt.Println("Paths cannot be used with -a")
+		return
+	}
+	// Settle who is committing before running hooks or an editor.
+	var c commitEntry
+	if opts.Amend {
+		c.Author, c.Date = head.Author, head.Date
+	}
+	if opts.Author != "" {
+		author, err := parseIdentity(opts.Author)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		c.Author = author.String()
+	}
+	if opts.Date != "" {
+		date, err := parseDate(opts.Date)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		c.Date = date.Format(time.RFC3339)
+	}
+	if err := fillIdentity(&c); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if opts.All {
+		if err := stageTrackedChanges(); err != nil {
+			fmt.Println("Error staging changes:", err)
+			return
+		}
+	}
+	tree := stagedTree()
+	var committed []string
+	if len(paths) > 0 {
+		var err error
+		if tree, committed, err = treeWithPaths(head.Files, paths); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	parents := []string{}
+	if hasHead {
+		parents = []string{head.ID}
+	}
+	if opts.Amend {
+		parents = head.Parents
+	} else if len(changedFiles(head.Files, tree)) == 0 {
 		fmt.Println("Nothing to commit")
 		return
 	}
-	timestamp := time.Now().Format(time.RFC3339)
-	entry := fmt.Sprintf("commit %s\nDate: %s\n\n%s\n%s\n---\n", timestamp, timestamp, message, string(index))
-	f, _ := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
-	defer f.Close()
-	f.WriteString(entry)
-	ioutil.WriteFile(indexFile, []byte{}, 0644)
-	fmt.Println("Committed:", message)
+	if !opts.NoVerify {
+		if err := runHook("pre-commit", ""); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	var err error
+	if opts.MessageFile != "" {
+		var data []byte
+		if opts.MessageFile == "-" {
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(opts.MessageFile)
+		}
+		if err != nil {
+			fmt.Println("Error reading message file:", err)
+			return
+		}
+		message = string(data)
+	}
+	edit := opts.Edit
+	switch {
+	case opts.Fixup != "" || opts.Squash != "":
+		kind, rev := "fixup! ", opts.Fixup
+		if rev == "" {
+			kind, rev = "squash! ", opts.Squash
+			edit = edit || message == ""
+		}
+		target, err := resolveRevision(rev)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		// Fixing up a fixup still targets the original commit.
+		subj := commitSubject(target)
+		for strings.HasPrefix(subj, "fixup! ") || strings.HasPrefix(subj, "squash! ") {
+			_, subj, _ = strings.Cut(subj, " ")
+		}
+		prefix := kind + subj
+		if message != "" {
+			prefix += "\n\n" + message
+		}
+		message = prefix
+	case opts.Amend && message == "":
+		message, edit = head.Message, !opts.NoEdit
+	}
+	message, err = commitMessage(message, edit, opts.NoVerify)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	c.Parents, c.Message, c.Files = parents, message, tree
+	reason := "commit: "
+	switch {
+	case opts.Amend:
+		reason = "commit (amend): "
+	case !hasHead:
+		reason = "commit (initial): "
+	}
+	if opts.Sign {
+		if err := signCommit(&c); err != nil {
+			fmt.Println("Error signing commit:", err)
+			return
+		}
+	}
+	id, err := createCommit(c, reason+subject(message))
+	if err != nil {
+		fmt.Println("Error writing commit:", err)
+		return
+	}
+	index := map[string]string{}
+	if len(paths) > 0 {
+		// Changes staged for other paths stay staged.
+		index = readIndex()
+		for _, f := range committed {
+			delete(index, f)
+		}
+	}
+	writeIndex(index)
+	fmt.Printf("Committed %s: %s\n", shortID(id), subject(message))
+	runHook("post-commit", "")
+}
+
+// stageTrackedChanges stages the working copy of every tracked file that
+// has been modified or deleted.
+func stageTrackedChanges() error {
+	index := readIndex()
+	head := commitTree(headCommitID())
+	for f, oid := range stagedTree() {
+		data, err := ioutil.ReadFile(f)
+		switch {
+		case err != nil && head[f] != "":
+			index[f] = nullID
+		case err != nil:
+			delete(index, f)
+		case hashObject(data) != oid:
+			if index[f], err = writeObject(data); err != nil {
+				return err
+			}
+		}
+	}
+	return writeIndex(index)
+}
+
+// treeWithPaths returns base with the tracked files under paths replaced by
+// their working copies, and the files it took.
+func treeWithPaths(base map[string]string, paths []string) (map[string]string, []string, error) {
+	tree := map[string]string{}
+	for f, oid := range base {
+		tree[f] = oid
+	}
+	tracked := stagedTree()
+	for f, oid := range base {
+		tracked[f] = oid
+	}
+	var files []string
+	for _, p := range paths {
+		matched := false
+		for f := range tracked {
+			if pathMatcher([]string{p})(f) {
+				matched = true
+				files = append(files, f)
+			}
+		}
+		if !matched {
+			return nil, nil, fmt.Errorf("pathspec '%s' did not match any file known to re-git", p)
+		}
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			delete(tree, f)
+			continue
+		}
+		if tree[f], err = writeObject(data); err != nil {
+			return nil, nil, err
+		}
+	}
+	return tree, files, nil
 }
 
 func Status() {
@@ -131,8 +340,14 @@ func CreateBranch(name string) {
 		fmt.Println("Branch already exists:", name)
 		return
 	}
-	err := ioutil.WriteFile(branchPath, []byte{}, 0644)
-	if err != nil {
+	head := headCommitID()
+	if head == "" {
+		err := ioutil.WriteFile(branchPath, []byte{}, 0644)
+		if err != nil {
+			fmt.Println("Error creating branch:", name)
+			return
+		}
+	} else if err := updateRef("refs/heads/"+name, head, "branch: Created from HEAD"); err != nil {
 		fmt.Println("Error creating branch:", name)
 		return
 	}
@@ -151,42 +366,46 @@ func ListBranches() {
 	}
 }
 
-func StashSave() {
-	index, err := ioutil.ReadFile(indexFile)
-	if err != nil || len(index) == 0 {
-		fmt.Println("Nothing to stash")
+func CheckoutBranch(name string) {
+	branchPath := filepath.Join(headsDir, name)
+	if _, err := os.Stat(branchPath); os.IsNotExist(err) {
+		fmt.Println("Branch does not exist:", name)
 		return
 	}
-	err = ioutil.WriteFile(stashFile, index, 0644)
-	if err != nil {
-		fmt.Println("Error saving stash")
+	if files := localChanges(); len(files) > 0 {
+		fmt.Println("Cannot switch branches: you have local changes in")
+		for _, f := range files {
+			fmt.Println(" ", f)
+		}
 		return
 	}
-	ioutil.WriteFile(indexFile, []byte{}, 0644)
-	fmt.Println("Stashed current staged files")
-}
-
-func StashApply() {
-	stash, err := ioutil.ReadFile(stashFile)
-	if err != nil || len(stash) == 0 {
-		fmt.Println("No stash found")
-		return
+	oldHead := headCommitID()
+	if oldHead == "" {
+		oldHead = nullID
+	}
+	oldTree := commitTree(oldHead)
+	from := currentBranch()
+	if from == "" {
+		from = shortID(headCommitID())
 	}
-	err = ioutil.WriteFile(indexFile, stash, 0644)
+	err := setHead("refs/heads/"+name, "checkout: moving from "+from+" to "+name)
 	if err != nil {
-		fmt.Println("Error applying stash")
+		fmt.Println("Error updating HEAD")
 		return
 	}
-	fmt.Println("Applied stash to staging area")
+	if err := checkoutTree(oldTree, commitTree(headCommitID())); err != nil {
+		fmt.Println("Error updating working tree:", err)
+		return
+	}
+	fmt.Println("Switched to branch", name)
+	runHook("post-checkout", "", oldHead, readRef("HEAD"), "1")
 }
 
-func StashDrop() {
-	err := ioutil.WriteFile(stashFile, []byte{}, 0644)
+func UpdateHEAD(ref string) {
+	err := setHead(strings.TrimPrefix(ref, "ref: "), "update HEAD to "+ref)
 	if err != nil {
-		fmt.Println("Error dropping stash")
-		return
+		fmt.Println("Error updating HEAD")
 	}
-	fmt.Println("Dropped stash")
 }
 
 func Rename(oldName, newName string) {
@@ -0,0 +1,257 @@
+package regit
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// diffOp is one line of a line diff: ' ' for a line both sides share, '-'
+// for a line only in the old version and '+' for a line only in the new one.
+type diffOp struct {
+	Kind byte
+	Line string
+}
+
+// splitLines splits data into lines, each keeping its trailing newline.
+func splitLines(data []byte) []string {
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script from a to b with Myers'
+// algorithm. The trace keeps, for each d, only the diagonals -d..d that the
+// backtrack can read, so it grows with the edit distance rather than with
+// the length of the inputs.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	v := make([]int, 2*max+2)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		window := make([]int, 2*d+1)
+		copy(window, v[max-d:max+d+1])
+		trace = append(trace, window)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1]
+			} else {
+				x = v[max+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, d)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the trace from step d back to the start. trace[d][d+k]
+// holds the furthest x reached on diagonal k before step d.
+func backtrack(trace [][]int, a, b []string, d int) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{'+', b[y]})
+		} else {
+			x--
+			ops = append(ops, diffOp{'-', a[x]})
+		}
+	}
+	for x > 0 {
+		x--
+		ops = append(ops, diffOp{' ', a[x]})
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// matchLines maps each line of a to the line of b it is paired with in the
+// diff, or -1 if it was deleted.
+func matchLines(a, b []string) []int {
+	match := make([]int, len(a))
+	i, j := 0, 0
+	for _, op := range diffLines(a, b) {
+		switch op.Kind {
+		case ' ':
+			match[i] = j
+			i++
+			j++
+		case '-':
+			match[i] = -1
+			i++
+		case '+':
+			j++
+		}
+	}
+	return match
+}
+
+// unifiedDiff renders a diff as unified diff hunks with the given number of
+// context lines.
+func unifiedDiff(ops []diffOp, context int) string {
+	var b strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change and extend the hunk while changes are
+		// separated by at most 2*context unchanged lines.
+		first := start
+		for first < len(ops) && ops[first].Kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].Kind != ' ' {
+				last = i
+			} else if i-last > 2*context {
+				break
+			}
+		}
+		from := first - context
+		if from < start {
+			from = start
+		}
+		to := last + context + 1
+		if to > len(ops) {
+			to = len(ops)
+		}
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:from] {
+			if op.Kind != '+' {
+				oldLine++
+			}
+			if op.Kind != '-' {
+				newLine++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[from:to] {
+			if op.Kind != '+' {
+				oldCount++
+			}
+			if op.Kind != '-' {
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldLine--
+		}
+		if newCount == 0 {
+			newLine--
+		}
+		b.WriteString(hunkHeader(oldLine, oldCount, newLine, newCount))
+		for _, op := range ops[from:to] {
+			b.WriteByte(op.Kind)
+			b.WriteString(op.Line)
+			if !strings.HasSuffix(op.Line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+	return b.String()
+}
+
+func hunkHeader(oldLine, oldCount, newLine, newCount int) string {
+	return "@@ -" + hunkRange(oldLine, oldCount) + " +" + hunkRange(newLine, newCount) + " @@\n"
+}
+
+func hunkRange(line, count int) string {
+	if count == 1 {
+		return strconv.Itoa(line)
+	}
+	return strconv.Itoa(line) + "," + strconv.Itoa(count)
+}
+
+// fileDiff renders the change to path between two blob IDs ("" for a file
+// that does not exist on that side) in git's patch format.
+func fileDiff(path, oldOid, newOid string) string {
+	if oldOid == newOid {
+		return ""
+	}
+	oldData, _ := readObject(oldOid)
+	newData, _ := readObject(newOid)
+	from, to := "a/"+path, "b/"+path
+	header := "diff --git a/" + path + " b/" + path + "\n"
+	if oldOid == "" {
+		from = "/dev/null"
+		header += "new file\n"
+	} else if newOid == "" {
+		to = "/dev/null"
+		header += "deleted file\n"
+	}
+	return header + "--- " + from + "\n+++ " + to + "\n" + unifiedDiff(diffLines(splitLines(oldData), splitLines(newData)), 3)
+}
+
+// diffStat counts the lines added and removed between two blob IDs.
+func diffStat(oldOid, newOid string) (added, removed int) {
+	oldData, _ := readObject(oldOid)
+	newData, _ := readObject(newOid)
+	for _, op := range diffLines(splitLines(oldData), splitLines(newData)) {
+		switch op.Kind {
+		case '+':
+			added++
+		case '-':
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// treeDiff renders the changes between two trees, file by file in path
+// order.
+func treeDiff(from, to map[string]string) string {
+	var b strings.Builder
+	for _, f := range changedFiles(from, to) {
+		b.WriteString(fileDiff(f, from[f], to[f]))
+	}
+	return b.String()
+}
+
+// changedFiles lists the paths whose blob differs between two trees, sorted.
+func changedFiles(from, to map[string]string) []string {
+	var files []string
+	for f, oid := range to {
+		if from[f] != oid {
+			files = append(files, f)
+		}
+	}
+	for f := range from {
+		if _, ok := to[f]; !ok {
+			files = append(files, f)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
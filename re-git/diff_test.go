@@ -0,0 +1,90 @@
+package regit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		data string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a\n"}},
+		{"a\nb", []string{"a\n", "b"}},
+		{"a\n\nb\n", []string{"a\n", "\n", "b\n"}},
+	}
+	for _, tt := range tests {
+		if got := splitLines([]byte(tt.data)); len(got) != len(tt.want) || len(got) > 0 && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitLines(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string // one op per line, kind then text
+	}{
+		{"equal", "a\nb\n", "a\nb\n", " a| b"},
+		{"add to empty", "", "a\n", "+a"},
+		{"remove all", "a\nb\n", "", "-a|-b"},
+		{"change in middle", "a\nb\nc\n", "a\nx\nc\n", " a|-b|+x| c"},
+		{"insert", "a\nc\n", "a\nb\nc\n", " a|+b| c"},
+		{"missing final newline", "a\nb", "a\nb\n", " a|-b|+b"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, op := range diffLines(splitLines([]byte(tt.old)), splitLines([]byte(tt.new))) {
+			got = append(got, string(op.Kind)+strings.TrimSuffix(op.Line, "\n"))
+		}
+		if strings.Join(got, "|") != tt.want {
+			t.Errorf("%s: diffLines gave %q, want %q", tt.name, strings.Join(got, "|"), tt.want)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		context  int
+		want     string
+	}{
+		{
+			name: "single change",
+			old:  "a\nb\nc\n", new: "a\nB\nc\n", context: 3,
+			want: "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "new file",
+			old:  "", new: "a\nb\n", context: 3,
+			want: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "distant changes make two hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n", new: "one\n2\n3\n4\n5\n6\n7\neight\n", context: 1,
+			want: "@@ -1,2 +1,2 @@\n-1\n+one\n 2\n@@ -7,2 +7,2 @@\n 7\n-8\n+eight\n",
+		},
+		{
+			name: "no newline at end",
+			old:  "a\n", new: "a\nb", context: 3,
+			want: "@@ -1 +1,2 @@\n a\n+b\n\\ No newline at end of file\n",
+		},
+		{
+			name: "no changes",
+			old:  "a\n", new: "a\n", context: 3,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		got := unifiedDiff(diffLines(splitLines([]byte(tt.old)), splitLines([]byte(tt.new))), tt.context)
+		if got != tt.want {
+			t.Errorf("%s: unifiedDiff gave\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package regit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const commitEditMsgFile = ".git/COMMIT_EDITMSG"
+
+// editorCommand picks the editor from $REGIT_EDITOR, core.editor or
+// $EDITOR, in that order.
+func editorCommand() string {
+	if editor := os.Getenv("REGIT_EDITOR"); editor != "" {
+		return editor
+	}
+	if editor := configValue("core.editor"); editor != "" {
+		return editor
+	}
+	if editor := os.Getenv("EDITOR"); editor != "" {
+		return editor
+	}
+	return "vi"
+}
+
+// launchEditor opens path in the user's editor and waits for it to exit.
+func launchEditor(path string) error {
+	cmd := exec.Command("sh", "-c", editorCommand()+` "$@"`, editorCommand(), path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %v", editorCommand(), err)
+	}
+	return nil
+}
+
+// cleanupMessage strips trailing whitespace from every line, collapses runs
+// of blank lines and drops leading and trailing ones. With stripComments
+// lines starting with "#" are removed first.
+func cleanupMessage(text string, stripComments bool) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		if stripComments && strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// editMessage lets the user edit a commit message, dropping "#" comment
+// lines from the result.
+func editMessage(initial string) (string, error) {
+	if err := ioutil.WriteFile(commitEditMsgFile, []byte(initial+"\n"), 0644); err != nil {
+		return "", err
+	}
+	if err := launchEditor(commitEditMsgFile); err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(commitEditMsgFile)
+	if err != nil {
+		return "", err
+	}
+	message := cleanupMessage(string(data), true)
+	if message == "" {
+		return "", fmt.Errorf("empty commit message")
+	}
+	return message, nil
+}
+
+// commitStatusComment lists the staged changes as comment lines for the
+// commit message editor.
+func commitStatusComment() string {
+	var b strings.Builder
+	b.WriteString("# Please enter the commit message for your changes. Lines starting\n")
+	b.WriteString("# with '#' will be ignored, and an empty message aborts the commit.\n#\n")
+	if branch := currentBranch(); branch != "" {
+		fmt.Fprintf(&b, "# On branch %s\n", branch)
+	} else {
+		fmt.Fprintf(&b, "# HEAD detached at %s\n", shortID(headCommitID()))
+	}
+	head, staged := commitTree(headCommitID()), stagedTree()
+	changed := changedFiles(head, staged)
+	if len(changed) > 0 {
+		b.WriteString("# Changes to be committed:\n")
+	}
+	for _, f := range changed {
+		kind := "modified:"
+		if _, ok := head[f]; !ok {
+			kind = "new file:"
+		} else if _, ok := staged[f]; !ok {
+			kind = "deleted:"
+		}
+		fmt.Fprintf(&b, "#\t%-10s %s\n", kind, f)
+	}
+	b.WriteString("#\n")
+	return b.String()
+}
+
+// commitMessage produces the final message for a commit. message is what
+// -m or -F gave, if anything; without one (or with edit) the editor is
+// opened on it, or on commit.template, followed by the staged changes as
+// comments. The prepare-commit-msg hook sees the message before the editor
+// and commit-msg (unless noVerify is set) after it.
+func commitMessage(message string, edit, noVerify bool) (string, error) {
+	source, template := "message", ""
+	if message == "" {
+		edit = true
+		if path := configPath("commit.template"); path != "" {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("cannot read commit.template: %v", err)
+			}
+			message, template, source = string(data), cleanupMessage(string(data), true), "template"
+		}
+	}
+	text := strings.TrimRight(message, "\n") + "\n"
+	var editor func(string) error
+	if edit {
+		text += "\n" + commitStatusComment()
+		editor = launchEditor
+	}
+	text, err := commitMessageHooks(text, source, editor, noVerify)
+	if err != nil {
+		return "", err
+	}
+	message = cleanupMessage(text, edit)
+	if message == "" {
+		return "", fmt.Errorf("aborting commit due to empty commit message")
+	}
+	if template != "" && message == template {
+		return "", fmt.Errorf("aborting commit; you did not edit the message")
+	}
+	return message, nil
+}
@@ -0,0 +1,180 @@
+package regit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useEditor makes a shell script with body the editor; it gets the file to
+// edit as $1.
+func useEditor(t *testing.T, body string) {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "editor")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("REGIT_EDITOR", script)
+}
+
+func TestCleanupMessage(t *testing.T) {
+	tests := []struct {
+		name, in      string
+		stripComments bool
+		want          string
+	}{
+		{name: "plain", in: "subject", want: "subject"},
+		{name: "trailing whitespace", in: "subject  \t\nbody \r\n", want: "subject\nbody"},
+		{name: "blank runs", in: "\n\nsubject\n\n\n\nbody\n\n", want: "subject\n\nbody"},
+		{name: "comments kept", in: "subject\n# note", want: "subject\n# note"},
+		{name: "comments stripped", in: "subject\n# note\n\nbody\n#\n", stripComments: true, want: "subject\n\nbody"},
+		{name: "indented hash kept", in: "subject\n  # not a comment", stripComments: true, want: "subject\n  # not a comment"},
+		{name: "only comments", in: "# a\n#\n# b\n", stripComments: true, want: ""},
+		{name: "blank around comment", in: "a\n\n# x\n\nb", stripComments: true, want: "a\n\nb"},
+		{name: "empty", in: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := cleanupMessage(tt.in, tt.stripComments); got != tt.want {
+			t.Errorf("%s: cleanupMessage(%q, %v) = %q, want %q", tt.name, tt.in, tt.stripComments, got, tt.want)
+		}
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	testRepo(t)
+	t.Setenv("REGIT_EDITOR", "")
+	t.Setenv("EDITOR", "")
+	if got := editorCommand(); got != "vi" {
+		t.Errorf("default editor is %q, want vi", got)
+	}
+	t.Setenv("EDITOR", "nano")
+	if got := editorCommand(); got != "nano" {
+		t.Errorf("editor is %q, want $EDITOR", got)
+	}
+	captureOutput(t, func() { ConfigSet("core.editor", "emacs -nw", false, ConfigOptions{}) })
+	if got := editorCommand(); got != "emacs -nw" {
+		t.Errorf("editor is %q, want core.editor", got)
+	}
+	t.Setenv("REGIT_EDITOR", "ed")
+	if got := editorCommand(); got != "ed" {
+		t.Errorf("editor is %q, want $REGIT_EDITOR", got)
+	}
+}
+
+func TestCommitMessageEditor(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		edit     bool
+		template string
+		editor   string
+		want     string // "" when the commit should be aborted
+		wantOut  string
+	}{
+		{
+			name:    "message given",
+			message: "from -m",
+			editor:  "echo changed >\"$1\"",
+			want:    "from -m",
+		},
+		{
+			name:   "editor writes the message",
+			editor: "printf 'typed\\n\\nbody  \\n' >\"$1\"",
+			want:   "typed\n\nbody",
+		},
+		{
+			name:    "edit a given message",
+			message: "draft",
+			edit:    true,
+			editor:  "sed -i 's/draft/final/' \"$1\"",
+			want:    "final",
+		},
+		{
+			name:    "comments left in are dropped",
+			editor:  "sed -i '1s/^/subject/' \"$1\"",
+			want:    "subject",
+			wantOut: "",
+		},
+		{
+			name:    "empty message",
+			editor:  "true",
+			wantOut: "aborting commit due to empty commit message",
+		},
+		{
+			name:    "failing editor",
+			editor:  "exit 1",
+			wantOut: "failed",
+		},
+		{
+			name:     "template edited",
+			template: "Subject\n\n# Explain why\n",
+			editor:   "sed -i 's/^Subject$/Fix the thing/' \"$1\"",
+			want:     "Fix the thing",
+		},
+		{
+			name:     "template untouched",
+			template: "Subject\n\n# Explain why\n",
+			editor:   "true",
+			wantOut:  "aborting commit; you did not edit the message",
+		},
+		{
+			name:     "template ignored with a message",
+			message:  "given",
+			template: "Subject\n",
+			editor:   "true",
+			want:     "given",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			base := commitFiles(t, "base", map[string]string{"f": "a\n"})
+			useEditor(t, tt.editor)
+			if tt.template != "" {
+				path := filepath.Join(t.TempDir(), "template")
+				ioutil.WriteFile(path, []byte(tt.template), 0644)
+				captureOutput(t, func() { ConfigSet("commit.template", path, false, ConfigOptions{}) })
+			}
+			writeFiles(t, map[string]string{"f": "b\n"})
+			out := captureOutput(t, func() {
+				Add("f")
+				Commit(tt.message, nil, CommitOptions{Edit: tt.edit})
+			})
+			if !strings.Contains(out, tt.wantOut) {
+				t.Errorf("output does not mention %q:\n%s", tt.wantOut, out)
+			}
+			head := headCommitID()
+			if tt.want == "" {
+				if head != base {
+					t.Errorf("a commit was made:\n%s", out)
+				}
+				return
+			}
+			c, _ := loadCommit(head)
+			if head == base || c.Message != tt.want {
+				t.Errorf("HEAD has message %q, want %q:\n%s", c.Message, tt.want, out)
+			}
+		})
+	}
+}
+
+func TestCommitStatusComment(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "base", map[string]string{"kept": "1\n", "changed": "1\n", "gone": "1\n"})
+	writeFiles(t, map[string]string{"changed": "2\n", "added": "1\n"})
+	os.Remove("gone")
+	captureOutput(t, func() { Add("added") })
+	if err := stageTrackedChanges(); err != nil {
+		t.Fatal(err)
+	}
+	want := "# On branch master\n" +
+		"# Changes to be committed:\n" +
+		"#\tnew file:  added\n" +
+		"#\tmodified:  changed\n" +
+		"#\tdeleted:   gone\n#\n"
+	if got := commitStatusComment(); !strings.HasSuffix(got, want) || !strings.HasPrefix(got, "# Please enter the commit message") {
+		t.Errorf("status comment is\n%s\nwant it to end with\n%s", got, want)
+	}
+}
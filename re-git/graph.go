@@ -0,0 +1,114 @@
+package regit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// logGraph draws the lanes of log --graph. Each lane waits for the commit
+// it leads to; lane i is drawn in column 2*i. After a commit is drawn its
+// lane is handed to its parents (a merge opens a lane for each further
+// parent) and every lane slides to its place in the new layout, one column
+// step per row, drawn with '/' and '\'.
+type logGraph struct {
+	lanes []string
+}
+
+// graphEdge is a lane moving from lane At to lane To.
+type graphEdge struct {
+	At, To int
+}
+
+func laneIndex(lanes []string, id string) int {
+	for i, l := range lanes {
+		if l == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// lanesRow draws n lanes as "|", with a "*" in lane mark.
+func lanesRow(n, mark int) string {
+	row := []byte(strings.Repeat("| ", n))
+	if mark >= 0 {
+		row[2*mark] = '*'
+	}
+	return string(row)
+}
+
+// add draws commit c, whose parents are those shown in the graph, next to
+// the lines of text describing it.
+func (g *logGraph) add(c commitEntry, parents []string, text []string) []string {
+	col := laneIndex(g.lanes, c.ID)
+	if col < 0 {
+		g.lanes = append(g.lanes, c.ID)
+		col = len(g.lanes) - 1
+	}
+	var next []string
+	var edges []graphEdge
+	place := func(id string) int {
+		if i := laneIndex(next, id); i >= 0 {
+			return i
+		}
+		next = append(next, id)
+		return len(next) - 1
+	}
+	for i, id := range g.lanes {
+		if i != col {
+			edges = append(edges, graphEdge{i, place(id)})
+			continue
+		}
+		for _, p := range parents {
+			edges = append(edges, graphEdge{i, place(p)})
+		}
+	}
+
+	rows := []string{lanesRow(len(g.lanes), col)}
+	width := 2 * max(len(g.lanes), len(next))
+	for {
+		// A lane moving left waits while one moving right uses the same gap,
+		// so that lines never cross within a character.
+		rightGaps := map[int]bool{}
+		moving := false
+		for _, e := range edges {
+			if e.At < e.To {
+				rightGaps[e.At] = true
+			}
+			moving = moving || e.At != e.To
+		}
+		if !moving {
+			break
+		}
+		row := []byte(strings.Repeat(" ", width))
+		for i := range edges {
+			e := &edges[i]
+			switch {
+			case e.At < e.To:
+				row[2*e.At+1] = '\\'
+				e.At++
+			case e.At > e.To && !rightGaps[e.At-1]:
+				row[2*e.At-1] = '/'
+				e.At--
+			default:
+				row[2*e.At] = '|'
+			}
+		}
+		rows = append(rows, string(row))
+	}
+	g.lanes = next
+
+	var lines []string
+	for i := 0; i < len(rows) || i < len(text); i++ {
+		row := lanesRow(len(next), -1)
+		if i < len(rows) {
+			row = rows[i]
+		}
+		line := ""
+		if i < len(text) {
+			line = text[i]
+		}
+		lines = append(lines, strings.TrimRight(fmt.Sprintf("%-*s %s", width-1, strings.TrimRight(row, " "), line), " "))
+	}
+	return lines
+}
@@ -0,0 +1,139 @@
+package regit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogGraph(t *testing.T) {
+	// Each commit is its ID followed by its parents, newest first.
+	tests := []struct {
+		name    string
+		commits [][]string
+		want    string
+	}{
+		{
+			name:    "linear",
+			commits: [][]string{{"c", "b"}, {"b", "a"}, {"a"}},
+			want:    "* c\n* b\n* a",
+		},
+		{
+			name:    "merge",
+			commits: [][]string{{"m", "x", "y"}, {"x", "b"}, {"y", "b"}, {"b"}},
+			want: `*   m
+|\
+* | x
+| * y
+|/
+* b`,
+		},
+		{
+			name:    "octopus",
+			commits: [][]string{{"m", "p", "q", "r"}, {"p", "b"}, {"q", "b"}, {"r", "b"}, {"b"}},
+			want: `*     m
+|\
+| |\
+* | | p
+| * | q
+|/ /
+| * r
+|/
+* b`,
+		},
+		{
+			name:    "several tips",
+			commits: [][]string{{"t1", "b"}, {"t2", "b"}, {"t3", "b"}, {"b"}},
+			want: `* t1
+| * t2
+|/
+| * t3
+|/
+* b`,
+		},
+		{
+			name:    "nested merges",
+			commits: [][]string{{"m2", "m1", "z"}, {"z", "b"}, {"m1", "x", "y"}, {"y", "b"}, {"x", "b"}, {"b"}},
+			want: `*   m2
+|\
+| * z
+* |   m1
+|\ \
+| * | y
+| |/
+* | x
+|/
+* b`,
+		},
+		{
+			name:    "parent not shown",
+			commits: [][]string{{"c"}, {"b"}},
+			want:    "* c\n* b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g logGraph
+			var lines []string
+			for _, c := range tt.commits {
+				lines = append(lines, g.add(commitEntry{ID: c[0]}, c[1:], []string{c[0]})...)
+			}
+			if got := strings.Join(lines, "\n"); got != tt.want {
+				t.Errorf("graph is\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogGraphMultiLineText(t *testing.T) {
+	var g logGraph
+	lines := g.add(commitEntry{ID: "m"}, []string{"x", "y"}, []string{"commit m", "Author: A", "", "    merge"})
+	want := []string{"*   commit m", "|\\  Author: A", "| |", "| |     merge"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("graph is\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLogGraphCommand(t *testing.T) {
+	testRepo(t)
+	base := commitFiles(t, "base", map[string]string{"f": "1\n"})
+	captureOutput(t, func() { CreateBranch("side") })
+	main := commitFiles(t, "main", map[string]string{"g": "1\n"})
+	captureOutput(t, func() {
+		Tag("v1", "", TagOptions{})
+		CheckoutBranch("side")
+	})
+	side := commitFiles(t, "side", map[string]string{"h": "1\n"})
+	captureOutput(t, func() { CheckoutBranch("master") })
+
+	tests := []struct {
+		name string
+		opts LogOptions
+		want string
+	}{
+		{
+			name: "current branch",
+			opts: LogOptions{Graph: true, Oneline: true},
+			want: "* " + shortID(main) + " main\n* " + shortID(base) + " base\n",
+		},
+		{
+			name: "all refs decorated",
+			opts: LogOptions{Graph: true, Oneline: true, All: true, Decorate: true},
+			want: "* " + shortID(side) + " (side) side\n" +
+				"| * " + shortID(main) + " (HEAD -> master, tag: v1) main\n" +
+				"|/\n" +
+				"* " + shortID(base) + " base\n",
+		},
+		{
+			name: "reverse",
+			opts: LogOptions{Graph: true, Reverse: true},
+			want: "--graph cannot be used with --reverse or -L\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := captureOutput(t, func() { Log(tt.opts) }); got != tt.want {
+				t.Errorf("log printed\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package regit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// GrepOptions selects what Grep searches and how it reports matches.
+// Without Revs or Cached the working tree is searched.
+type GrepOptions struct {
+	Revs             []string
+	Cached           bool
+	Paths            []string
+	IgnoreCase       bool
+	WordRegexp       bool
+	LineNumber       bool
+	FilesWithMatches bool
+	Count            bool
+	Before, After    int
+}
+
+// grepTarget is a file to search: a blob, or the working file when Oid is
+// empty. Name is how matches in it are labelled.
+type grepTarget struct {
+	Name, File, Oid string
+}
+
+// grepTargets lists the files opts selects, in the order they are shown.
+func grepTargets(opts GrepOptions) ([]grepTarget, error) {
+	match := pathMatcher(opts.Paths)
+	var targets []grepTarget
+	add := func(prefix string, tree map[string]string) {
+		for _, f := range sortedKeys(tree) {
+			if match(f) {
+				targets = append(targets, grepTarget{prefix + f, f, tree[f]})
+			}
+		}
+	}
+	switch {
+	case len(opts.Revs) > 0:
+		for _, rev := range opts.Revs {
+			id, err := resolveRevision(rev)
+			if err != nil {
+				return nil, err
+			}
+			add(rev+":", commitTree(id))
+		}
+	case opts.Cached:
+		add("", stagedTree())
+	default:
+		// Tracked files are searched even if an ignore rule covers them.
+		files := map[string]string{}
+		for f := range stagedTree() {
+			if _, err := os.Stat(f); err == nil {
+				files[f] = ""
+			}
+		}
+		walkUnignored(func(f string) { files[f] = "" })
+		add("", files)
+	}
+	return targets, nil
+}
+
+// grepFile returns the report for one file and whether anything in it
+// matched.
+func grepFile(t grepTarget, re *regexp.Regexp, opts GrepOptions) (string, bool) {
+	var data []byte
+	if t.Oid != "" {
+		data, _ = readObject(t.Oid)
+	} else {
+		data, _ = ioutil.ReadFile(t.File)
+	}
+	lines := splitLines(data)
+	var hits []int
+	for n, line := range lines {
+		if re.MatchString(strings.TrimSuffix(line, "\n")) {
+			hits = append(hits, n)
+		}
+	}
+	switch {
+	case len(hits) == 0:
+		return "", false
+	case opts.FilesWithMatches:
+		return t.Name + "\n", true
+	case opts.Count:
+		return fmt.Sprintf("%s:%d\n", t.Name, len(hits)), true
+	case bytes.IndexByte(data, 0) >= 0:
+		return "Binary file " + t.Name + " matches\n", true
+	}
+	shown := map[int]byte{}
+	for _, n := range hits {
+		for c := max(n-opts.Before, 0); c <= min(n+opts.After, len(lines)-1); c++ {
+			if shown[c] == 0 {
+				shown[c] = '-'
+			}
+		}
+		shown[n] = ':'
+	}
+	var b strings.Builder
+	last := -1
+	for n, line := range lines {
+		sep := shown[n]
+		if sep == 0 {
+			continue
+		}
+		if last >= 0 && n > last+1 && (opts.Before > 0 || opts.After > 0) {
+			b.WriteString("--\n")
+		}
+		last = n
+		b.WriteString(t.Name + string(sep))
+		if opts.LineNumber {
+			fmt.Fprintf(&b, "%d%c", n+1, sep)
+		}
+		b.WriteString(strings.TrimSuffix(line, "\n") + "\n")
+	}
+	return b.String(), true
+}
+
+// Grep prints the lines matching the regular expression pattern in the
+// working tree, the index (Cached) or the given revisions. Files are
+// searched in parallel; output keeps path order. It reports whether
+// anything matched.
+func Grep(pattern string, opts GrepOptions) bool {
+	var revs []string
+	for _, rev := range opts.Revs {
+		if _, err := resolveRevision(rev); err != nil {
+			if _, statErr := os.Stat(rev); statErr == nil {
+				opts.Paths = append(opts.Paths, rev)
+				continue
+			}
+		}
+		revs = append(revs, rev)
+	}
+	opts.Revs = revs
+	if opts.Cached && len(revs) > 0 {
+		fmt.Println("--cached cannot be used with revisions")
+		return false
+	}
+	if opts.WordRegexp {
+		pattern = `\b(?:` + pattern + `)\b`
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		fmt.Println("Invalid pattern:", err)
+		return false
+	}
+	targets, err := grepTargets(opts)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	reports := make([]string, len(targets))
+	matched := make([]bool, len(targets))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				reports[i], matched[i] = grepFile(targets[i], re, opts)
+			}
+		}()
+	}
+	for i := range targets {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	context := opts.Before > 0 || opts.After > 0
+	found := false
+	for i, report := range reports {
+		if !matched[i] {
+			continue
+		}
+		if found && context && !opts.FilesWithMatches && !opts.Count {
+			fmt.Println("--")
+		}
+		found = true
+		fmt.Print(report)
+	}
+	return found
+}
@@ -0,0 +1,148 @@
+package regit
+
+import (
+	"testing"
+)
+
+func TestGrep(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "first", map[string]string{
+		"a.txt":     "alpha\nbeta\ngamma\n",
+		"src/b.txt": "Beta one\nbetamax\nother\n",
+	})
+	writeFiles(t, map[string]string{
+		"a.txt":      "alpha\nbeta\ngamma\ndelta\nbeta again\n",
+		"new.txt":    "beta new\n",
+		"skip.log":   "beta logged\n",
+		".gitignore": "*.log\n",
+		"bin.dat":    "beta\x00\n",
+	})
+	captureOutput(t, func() { Add("new.txt") })
+	tests := []struct {
+		name    string
+		pattern string
+		opts    GrepOptions
+		want    string
+		found   bool
+	}{
+		{
+			name:    "working tree",
+			pattern: "beta",
+			want:    "a.txt:beta\na.txt:beta again\nBinary file bin.dat matches\nnew.txt:beta new\nsrc/b.txt:betamax\n",
+			found:   true,
+		},
+		{
+			name:    "ignore case",
+			pattern: "beta",
+			opts:    GrepOptions{IgnoreCase: true, Paths: []string{"src"}},
+			want:    "src/b.txt:Beta one\nsrc/b.txt:betamax\n",
+			found:   true,
+		},
+		{
+			name:    "word",
+			pattern: "beta",
+			opts:    GrepOptions{WordRegexp: true, Paths: []string{"a.txt", "src"}},
+			want:    "a.txt:beta\na.txt:beta again\n",
+			found:   true,
+		},
+		{
+			name:    "line numbers",
+			pattern: "^beta$|delta",
+			opts:    GrepOptions{LineNumber: true, Paths: []string{"a.txt"}},
+			want:    "a.txt:2:beta\na.txt:4:delta\n",
+			found:   true,
+		},
+		{
+			name:    "files with matches",
+			pattern: "beta",
+			opts:    GrepOptions{FilesWithMatches: true},
+			want:    "a.txt\nbin.dat\nnew.txt\nsrc/b.txt\n",
+			found:   true,
+		},
+		{
+			name:    "count",
+			pattern: "beta",
+			opts:    GrepOptions{Count: true, Paths: []string{"a.txt", "src"}},
+			want:    "a.txt:2\nsrc/b.txt:1\n",
+			found:   true,
+		},
+		{
+			name:    "context",
+			pattern: "^alpha|again",
+			opts:    GrepOptions{LineNumber: true, After: 1, Paths: []string{"a.txt"}},
+			want:    "a.txt:1:alpha\na.txt-2-beta\n--\na.txt:5:beta again\n",
+			found:   true,
+		},
+		{
+			name:    "context before across files",
+			pattern: "gamma|other",
+			opts:    GrepOptions{Before: 1},
+			want:    "a.txt-beta\na.txt:gamma\n--\nsrc/b.txt-betamax\nsrc/b.txt:other\n",
+			found:   true,
+		},
+		{
+			name:    "cached",
+			pattern: "beta",
+			opts:    GrepOptions{Cached: true},
+			want:    "a.txt:beta\nnew.txt:beta new\nsrc/b.txt:betamax\n",
+			found:   true,
+		},
+		{
+			name:    "revision",
+			pattern: "a",
+			opts:    GrepOptions{Revs: []string{"HEAD"}, Count: true},
+			want:    "HEAD:a.txt:3\nHEAD:src/b.txt:2\n",
+			found:   true,
+		},
+		{
+			name:    "path given as revision",
+			pattern: "beta",
+			opts:    GrepOptions{Revs: []string{"new.txt"}},
+			want:    "new.txt:beta new\n",
+			found:   true,
+		},
+		{
+			name:    "no match",
+			pattern: "zeta",
+		},
+		{
+			name:    "cached with revision",
+			pattern: "beta",
+			opts:    GrepOptions{Cached: true, Revs: []string{"HEAD"}},
+			want:    "--cached cannot be used with revisions\n",
+		},
+		{
+			name:    "bad pattern",
+			pattern: "(",
+			want:    "Invalid pattern: error parsing regexp: missing closing ): `(`\n",
+		},
+		{
+			name:    "bad revision",
+			pattern: "beta",
+			opts:    GrepOptions{Revs: []string{"nope"}},
+			want:    "unknown revision: nope\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var found bool
+			got := captureOutput(t, func() { found = Grep(tt.pattern, tt.opts) })
+			if got != tt.want {
+				t.Errorf("Grep output:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if found != tt.found {
+				t.Errorf("Grep = %v, want %v", found, tt.found)
+			}
+		})
+	}
+}
+
+func TestGrepTrackedIgnoredFile(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "first", map[string]string{"kept.log": "match\n"})
+	writeFiles(t, map[string]string{".gitignore": "*.log\n", "new.log": "match\n"})
+	got := captureOutput(t, func() { Grep("match", GrepOptions{}) })
+	if got != "kept.log:match\n" {
+		t.Errorf("Grep printed %q, want only the tracked file", got)
+	}
+}
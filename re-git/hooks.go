@@ -0,0 +1,96 @@
+package regit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Hooks are executable files in .regit/hooks at the top of the working tree,
+// or the directory core.hooksPath names, run at fixed points:
+//
+//	pre-commit          before a commit is made; non-zero exit aborts it
+//	prepare-commit-msg  with the message file, its source and maybe a commit
+//	commit-msg          with the message file; non-zero exit aborts the commit
+//	post-commit         after a commit
+//	pre-push            with the remote and one "<local ref> <local id>
+//	                    <remote ref> <remote id>" line per ref on stdin;
+//	                    non-zero exit aborts the push
+//	post-checkout       with the old and new HEAD and 1 for a branch switch
+//	post-merge          after a merge or pull
+//
+// pre-commit, commit-msg and pre-push are skipped with --no-verify.
+
+func hooksDir() string {
+	if path := configPath("core.hooksPath"); path != "" {
+		return path
+	}
+	return filepath.Join(".regit", "hooks")
+}
+
+// runHook runs the named hook, if there is an executable one, with args
+// and stdin, returning an error when it exits non-zero.
+func runHook(name, stdin string, args ...string) error {
+	path := filepath.Join(hooksDir(), name)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+		return nil
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook failed: %v", name, err)
+	}
+	return nil
+}
+
+// prePushInput describes to the pre-push hook the branches a push to
+// remotePath would update.
+func prePushInput(remotePath string) string {
+	var b strings.Builder
+	for _, name := range branchNames() {
+		ref := "refs/heads/" + name
+		local := readRef(ref)
+		if !isObjectID(local) {
+			continue
+		}
+		remote := nullID
+		if data, err := ioutil.ReadFile(filepath.Join(remotePath, repoDir, ref)); err == nil && isObjectID(strings.TrimSpace(string(data))) {
+			remote = strings.TrimSpace(string(data))
+		}
+		if remote != local {
+			fmt.Fprintf(&b, "%s %s %s %s\n", ref, local, ref, remote)
+		}
+	}
+	return b.String()
+}
+
+// commitMessageHooks writes text to the message file and runs
+// prepare-commit-msg on it, then edit (when not nil) and, unless noVerify
+// is set, commit-msg, returning the text as they left it. source tells
+// prepare-commit-msg where the message came from.
+func commitMessageHooks(text, source string, edit func(file string) error, noVerify bool) (string, error) {
+	if err := ioutil.WriteFile(commitEditMsgFile, []byte(text), 0644); err != nil {
+		return "", err
+	}
+	if err := runHook("prepare-commit-msg", "", commitEditMsgFile, source); err != nil {
+		return "", err
+	}
+	if edit != nil {
+		if err := edit(commitEditMsgFile); err != nil {
+			return "", err
+		}
+	}
+	if !noVerify {
+		if err := runHook("commit-msg", "", commitEditMsgFile); err != nil {
+			return "", err
+		}
+	}
+	data, err := ioutil.ReadFile(commitEditMsgFile)
+	return string(data), err
+}
@@ -0,0 +1,148 @@
+package regit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommitHooks(t *testing.T) {
+	tests := []struct {
+		name        string
+		hooks       map[string]string
+		noVerify    bool
+		wantMessage string // "" when no commit should be made
+	}{
+		{
+			name:        "no hooks",
+			wantMessage: "change",
+		},
+		{
+			name:        "pre-commit passes",
+			hooks:       map[string]string{"pre-commit": "exit 0"},
+			wantMessage: "change",
+		},
+		{
+			name:  "pre-commit fails",
+			hooks: map[string]string{"pre-commit": "exit 1"},
+		},
+		{
+			name:        "no-verify skips pre-commit",
+			hooks:       map[string]string{"pre-commit": "exit 1"},
+			noVerify:    true,
+			wantMessage: "change",
+		},
+		{
+			name:  "commit-msg fails",
+			hooks: map[string]string{"commit-msg": "exit 1"},
+		},
+		{
+			name:        "commit-msg rewrites the message",
+			hooks:       map[string]string{"commit-msg": `echo "hooked" > "$1"`},
+			wantMessage: "hooked",
+		},
+		{
+			name:        "prepare-commit-msg sees the source",
+			hooks:       map[string]string{"prepare-commit-msg": `echo "from $2" > "$1"`},
+			wantMessage: "from message",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			base := commitFiles(t, "base", map[string]string{"f": "a\n"})
+			dir := filepath.Join(".regit", "hooks")
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for name, script := range tt.hooks {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			writeFiles(t, map[string]string{"f": "b\n"})
+			out := captureOutput(t, func() {
+				Add("f")
+				Commit("change", nil, CommitOptions{NoVerify: tt.noVerify})
+			})
+			head := headCommitID()
+			if tt.wantMessage == "" {
+				if head != base {
+					t.Errorf("a commit was made:\n%s", out)
+				}
+				return
+			}
+			c, _ := loadCommit(head)
+			if head == base || c.Message != tt.wantMessage {
+				t.Errorf("HEAD has message %q, want %q:\n%s", c.Message, tt.wantMessage, out)
+			}
+		})
+	}
+}
+
+func TestRunHook(t *testing.T) {
+	tests := []struct {
+		name      string
+		dir       string // where the hook lives; core.hooksPath is set unless it is .regit/hooks
+		mode      os.FileMode
+		script    string
+		wantErr   bool
+		wantInput string
+	}{
+		{name: "default dir", dir: filepath.Join(".regit", "hooks"), mode: 0755, script: "cat >input; echo \"$@\" >>input", wantInput: "in\na b\n"},
+		{name: "core.hooksPath", dir: "myhooks", mode: 0755, script: "cat >input; echo \"$@\" >>input", wantInput: "in\na b\n"},
+		{name: "not executable", dir: filepath.Join(".regit", "hooks"), mode: 0644, script: "touch input; exit 1"},
+		{name: "failing", dir: filepath.Join(".regit", "hooks"), mode: 0755, script: "touch input; exit 1", wantErr: true, wantInput: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			if tt.dir != filepath.Join(".regit", "hooks") {
+				captureOutput(t, func() { ConfigSet("core.hooksPath", tt.dir, false, ConfigOptions{}) })
+			}
+			os.MkdirAll(tt.dir, 0755)
+			if err := ioutil.WriteFile(filepath.Join(tt.dir, "test-hook"), []byte("#!/bin/sh\n"+tt.script+"\n"), tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			err := runHook("test-hook", "in\n", "a", "b")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("runHook error = %v, want error %v", err, tt.wantErr)
+			}
+			data, readErr := ioutil.ReadFile("input")
+			ran := readErr == nil
+			if ran != (tt.mode&0111 != 0) {
+				t.Errorf("hook ran = %v with mode %v", ran, tt.mode)
+			}
+			if ran && string(data) != tt.wantInput {
+				t.Errorf("hook got %q, want %q", data, tt.wantInput)
+			}
+		})
+	}
+	testRepo(t)
+	if err := runHook("missing", ""); err != nil {
+		t.Errorf("a missing hook gave %v", err)
+	}
+}
+
+func TestPrePushInput(t *testing.T) {
+	testRepo(t)
+	first := commitFiles(t, "first", map[string]string{"f": "1\n"})
+	captureOutput(t, func() {
+		CreateBranch("same")
+		CreateBranch("feature/x")
+	})
+	second := commitFiles(t, "second", map[string]string{"f": "2\n"})
+
+	remote := t.TempDir()
+	remoteHeads := filepath.Join(remote, repoDir, "refs", "heads")
+	os.MkdirAll(filepath.Join(remoteHeads, "feature"), 0755)
+	ioutil.WriteFile(filepath.Join(remoteHeads, "master"), []byte(first+"\n"), 0644)
+	ioutil.WriteFile(filepath.Join(remoteHeads, "same"), []byte(first+"\n"), 0644)
+
+	want := "refs/heads/feature/x " + first + " refs/heads/feature/x " + nullID + "\n" +
+		"refs/heads/master " + second + " refs/heads/master " + first + "\n"
+	if got := prePushInput(remote); got != want {
+		t.Errorf("prePushInput gave\n%s\nwant\n%s", got, want)
+	}
+}
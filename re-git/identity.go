@@ -0,0 +1,131 @@
+package regit
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commits record who wrote a change (the author) and who committed it,
+// each with a date. Both come from user.name and user.email unless
+// REGIT_AUTHOR_NAME, REGIT_AUTHOR_EMAIL and REGIT_AUTHOR_DATE (or their
+// REGIT_COMMITTER_ counterparts) override them.
+
+type identity struct {
+	Name, Email string
+}
+
+func (id identity) String() string {
+	return fmt.Sprintf("%s <%s>", id.Name, id.Email)
+}
+
+// parseIdentity parses "Name <email>".
+func parseIdentity(s string) (identity, error) {
+	open, end := strings.Index(s, "<"), strings.LastIndex(s, ">")
+	if open < 0 || end < open {
+		return identity{}, fmt.Errorf("identity %q is not in the form 'Name <email>'", s)
+	}
+	id := identity{Name: strings.TrimSpace(s[:open]), Email: strings.TrimSpace(s[open+1 : end])}
+	if id.Name == "" || id.Email == "" {
+		return identity{}, fmt.Errorf("identity %q is not in the form 'Name <email>'", s)
+	}
+	return id, nil
+}
+
+// roleIdentity returns the identity for role ("AUTHOR" or "COMMITTER").
+func roleIdentity(role string) (identity, error) {
+	id := identity{Name: os.Getenv("REGIT_" + role + "_NAME"), Email: os.Getenv("REGIT_" + role + "_EMAIL")}
+	if id.Name == "" {
+		id.Name = configValue("user.name")
+	}
+	if id.Email == "" {
+		id.Email = configValue("user.email")
+	}
+	if id.Name == "" || id.Email == "" {
+		return identity{}, fmt.Errorf(`%s identity unknown
+
+Tell re-git who you are by running
+
+  re-git config user.name "Your Name"
+  re-git config user.email you@example.com`, role[:1]+strings.ToLower(role[1:]))
+	}
+	return id, nil
+}
+
+// roleDate returns the date for role from REGIT_<role>_DATE, or now.
+func roleDate(role string) (string, error) {
+	if s := os.Getenv("REGIT_" + role + "_DATE"); s != "" {
+		t, err := parseDate(s)
+		if err != nil {
+			return "", err
+		}
+		return t.Format(time.RFC3339), nil
+	}
+	return time.Now().Format(time.RFC3339), nil
+}
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	tagDateFormat,
+	time.RFC1123Z,
+}
+
+// parseDate accepts RFC 3339 and the other common forms in dateLayouts,
+// as well as "<unix time> [<zone>]" with an optional leading "@".
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	fields := strings.Fields(strings.TrimPrefix(s, "@"))
+	if len(fields) == 1 || len(fields) == 2 {
+		if unix, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			t := time.Unix(unix, 0)
+			if len(fields) == 2 {
+				zone, err := time.Parse("-0700", fields[1])
+				if err != nil {
+					return time.Time{}, fmt.Errorf("invalid date: %s", s)
+				}
+				t = t.In(zone.Location())
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %s", s)
+}
+
+// fillIdentity completes the author, committer and dates of a commit that
+// is about to be written. Fields the caller already set, such as the
+// author of a picked commit, are kept.
+func fillIdentity(c *commitEntry) error {
+	if c.Author == "" {
+		author, err := roleIdentity("AUTHOR")
+		if err != nil {
+			return err
+		}
+		c.Author = author.String()
+	}
+	if c.Date == "" {
+		date, err := roleDate("AUTHOR")
+		if err != nil {
+			return err
+		}
+		c.Date = date
+	}
+	committer, err := roleIdentity("COMMITTER")
+	if err != nil {
+		return err
+	}
+	c.Committer = committer.String()
+	c.CommitDate, err = roleDate("COMMITTER")
+	return err
+}
@@ -0,0 +1,185 @@
+package regit
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseIdentity(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    identity
+		wantErr bool
+	}{
+		{in: "A U Thor <a@example.com>", want: identity{"A U Thor", "a@example.com"}},
+		{in: "  Padded   < pad@example.com >  ", want: identity{"Padded", "pad@example.com"}},
+		{in: "No Email", wantErr: true},
+		{in: "<a@example.com>", wantErr: true},
+		{in: "Name <>", wantErr: true},
+		{in: "Name >a@example.com<", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseIdentity(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseIdentity(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseIdentity(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	plus2 := time.FixedZone("", 2*3600)
+	minus5 := time.FixedZone("", -5*3600)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "2024-03-01T12:30:00+02:00", want: time.Date(2024, 3, 1, 12, 30, 0, 0, plus2)},
+		{in: "2024-03-01 12:30:00 -0500", want: time.Date(2024, 3, 1, 12, 30, 0, 0, minus5)},
+		{in: "2024-03-01T12:30:00", want: time.Date(2024, 3, 1, 12, 30, 0, 0, time.Local)},
+		{in: "2024-03-01 12:30:00", want: time.Date(2024, 3, 1, 12, 30, 0, 0, time.Local)},
+		{in: "2024-03-01 12:30", want: time.Date(2024, 3, 1, 12, 30, 0, 0, time.Local)},
+		{in: " 2024-03-01 ", want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)},
+		{in: "Fri Mar 1 12:30:00 2024 +0200", want: time.Date(2024, 3, 1, 12, 30, 0, 0, plus2)},
+		{in: "Fri, 01 Mar 2024 12:30:00 -0500", want: time.Date(2024, 3, 1, 12, 30, 0, 0, minus5)},
+		{in: "1709289000", want: time.Unix(1709289000, 0)},
+		{in: "@1709289000 +0200", want: time.Date(2024, 3, 1, 12, 30, 0, 0, plus2)},
+		{in: "1709289000 +02", wantErr: true},
+		{in: "yesterday", wantErr: true},
+		{in: "2024-13-01", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDate(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDate(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		_, gotOffset := got.Zone()
+		_, wantOffset := tt.want.Zone()
+		if gotOffset != wantOffset {
+			t.Errorf("parseDate(%q) has offset %d, want %d", tt.in, gotOffset, wantOffset)
+		}
+	}
+}
+
+func TestCommitIdentity(t *testing.T) {
+	tests := []struct {
+		name                 string
+		env                  map[string]string
+		opts                 CommitOptions
+		wantAuthor, wantDate string
+		wantCommitter        string
+		wantCommitDate       string
+		wantOut              string
+	}{
+		{
+			name:          "config",
+			wantAuthor:    "Test User <test@example.com>",
+			wantCommitter: "Test User <test@example.com>",
+		},
+		{
+			name: "environment",
+			env: map[string]string{
+				"REGIT_AUTHOR_NAME":     "Env Author",
+				"REGIT_AUTHOR_DATE":     "2020-01-02T03:04:05Z",
+				"REGIT_COMMITTER_EMAIL": "ci@example.com",
+				"REGIT_COMMITTER_DATE":  "@1600000000 +0000",
+			},
+			wantAuthor:     "Env Author <test@example.com>",
+			wantDate:       "2020-01-02T03:04:05Z",
+			wantCommitter:  "Test User <ci@example.com>",
+			wantCommitDate: "2020-09-13T12:26:40Z",
+		},
+		{
+			name:          "options",
+			env:           map[string]string{"REGIT_AUTHOR_NAME": "Env Author"},
+			opts:          CommitOptions{Author: "Opt Author <opt@example.com>", Date: "2021-05-06T07:08:09Z"},
+			wantAuthor:    "Opt Author <opt@example.com>",
+			wantDate:      "2021-05-06T07:08:09Z",
+			wantCommitter: "Test User <test@example.com>",
+		},
+		{
+			name:    "bad author",
+			opts:    CommitOptions{Author: "nobody"},
+			wantOut: `identity "nobody" is not in the form 'Name <email>'`,
+		},
+		{
+			name:    "bad date",
+			env:     map[string]string{"REGIT_COMMITTER_DATE": "soon"},
+			wantOut: "invalid date: soon",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			writeFiles(t, map[string]string{"f": "a\n"})
+			out := captureOutput(t, func() {
+				Add("f")
+				Commit("msg", nil, tt.opts)
+			})
+			c, ok := loadCommit(headCommitID())
+			if tt.wantOut != "" {
+				if !strings.Contains(out, tt.wantOut) || ok {
+					t.Errorf("commit printed %q and made a commit = %v, want %q", out, ok, tt.wantOut)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("no commit was made:\n%s", out)
+			}
+			if c.Author != tt.wantAuthor || c.Committer != tt.wantCommitter {
+				t.Errorf("author %q, committer %q, want %q and %q", c.Author, c.Committer, tt.wantAuthor, tt.wantCommitter)
+			}
+			if tt.wantDate != "" && !sameTime(c.Date, tt.wantDate) {
+				t.Errorf("author date %s, want %s", c.Date, tt.wantDate)
+			}
+			if tt.wantCommitDate != "" && !sameTime(c.CommitDate, tt.wantCommitDate) {
+				t.Errorf("commit date %s, want %s", c.CommitDate, tt.wantCommitDate)
+			}
+		})
+	}
+}
+
+func sameTime(a, b string) bool {
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	return errA == nil && errB == nil && ta.Equal(tb)
+}
+
+func TestCommitWithoutIdentityStopsBeforeEditing(t *testing.T) {
+	testRepo(t)
+	captureOutput(t, func() { ConfigUnset("user.email", false, ConfigOptions{}) })
+	t.Setenv("REGIT_EDITOR", "touch .git/edited; true")
+	writeFiles(t, map[string]string{"f": "a\n"})
+
+	out := captureOutput(t, func() {
+		Add("f")
+		Commit("", nil, CommitOptions{})
+	})
+	if !strings.Contains(out, "identity unknown") {
+		t.Errorf("commit did not report the missing identity:\n%s", out)
+	}
+	if _, err := os.Stat(".git/edited"); err == nil {
+		t.Error("the editor ran before the identity was checked")
+	}
+	if id := headCommitID(); id != "" {
+		t.Errorf("commit %s was made without an identity", id)
+	}
+}
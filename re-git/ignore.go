@@ -0,0 +1,167 @@
+package regit
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignorePattern is one line of a .gitignore file, or the pattern that
+// starts a line of .gitattributes. Patterns with a slash
+// other than a trailing one are matched against the path relative to the
+// directory of the file they come from; others against the last component
+// of the path, at any depth.
+type ignorePattern struct {
+	base     string
+	re       *regexp.Regexp
+	anchored bool
+	negate   bool
+	dirOnly  bool
+}
+
+// globRegexp translates a gitignore glob, where "*" and "?" do not match
+// "/" and "**" matches across directories.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[' && strings.Contains(glob[i+1:], "]"):
+			end := i + 1 + strings.Index(glob[i+1:], "]")
+			class := glob[i+1 : end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i = end
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// parsePattern compiles a glob found in a file in directory base.
+func parsePattern(glob, base string) (ignorePattern, bool) {
+	p := ignorePattern{base: base}
+	if strings.HasSuffix(glob, "/") {
+		p.dirOnly, glob = true, strings.TrimRight(glob, "/")
+	}
+	p.anchored = strings.Contains(glob, "/")
+	re, err := regexp.Compile("^" + globRegexp(strings.TrimPrefix(glob, "/")) + "$")
+	if err != nil {
+		return p, false
+	}
+	p.re = re
+	return p, true
+}
+
+// matches reports whether p applies to name.
+func (p ignorePattern) matches(name string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	rel := name
+	if p.base != "" {
+		if !strings.HasPrefix(name, p.base+"/") {
+			return false
+		}
+		rel = name[len(p.base)+1:]
+	}
+	if !p.anchored {
+		rel = path.Base(rel)
+	}
+	return p.re.MatchString(rel)
+}
+
+// readIgnoreFile parses the patterns of file, which apply below base.
+func readIgnoreFile(file, base string) []ignorePattern {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var patterns []ignorePattern
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(line, "!")
+		if negate {
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if p, ok := parsePattern(line, base); ok {
+			p.negate = negate
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// ignored reports whether the last pattern matching name decides that it
+// is ignored.
+func ignored(patterns []ignorePattern, name string, isDir bool) bool {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		if patterns[i].matches(name, isDir) {
+			return !patterns[i].negate
+		}
+	}
+	return false
+}
+
+// walkUnignored calls fn for every working file that the ignore rules do
+// not exclude: those of core.excludesFile, .git/info/exclude and each
+// directory's .gitignore. As in git, nothing inside an ignored directory is
+// visited.
+func walkUnignored(fn func(file string)) {
+	top := readIgnoreFile(configPath("core.excludesFile"), "")
+	top = append(top, readIgnoreFile(filepath.Join(repoDir, "info", "exclude"), "")...)
+	rules := map[string][]ignorePattern{}
+	filepath.Walk(".", func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		file = filepath.ToSlash(file)
+		parent := path.Dir(file)
+		if info.IsDir() {
+			if file == repoDir {
+				return filepath.SkipDir
+			}
+			inherited := top
+			if file != "." {
+				if ignored(rules[parent], file, true) {
+					return filepath.SkipDir
+				}
+				inherited = rules[parent]
+			}
+			base := file
+			if base == "." {
+				base = ""
+			}
+			patterns := append([]ignorePattern(nil), inherited...)
+			rules[file] = append(patterns, readIgnoreFile(filepath.Join(file, ".gitignore"), base)...)
+			return nil
+		}
+		if !ignored(rules[parent], file, false) {
+			fn(file)
+		}
+		return nil
+	})
+}
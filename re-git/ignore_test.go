@@ -0,0 +1,149 @@
+package regit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		glob string
+		want string
+	}{
+		{"*.o", `[^/]*\.o`},
+		{"a?c", `a[^/]c`},
+		{"**/build", `(?:.*/)?build`},
+		{"logs/**", `logs/.*`},
+		{"[abc].txt", `[abc]\.txt`},
+		{"[!abc]", `[^abc]`},
+		{"[unclosed", `\[unclosed`},
+		{`\*literal`, `\*literal`},
+		{`\#x`, `#x`},
+	}
+	for _, tt := range tests {
+		if got := globRegexp(tt.glob); got != tt.want {
+			t.Errorf("globRegexp(%q) = %q, want %q", tt.glob, got, tt.want)
+		}
+	}
+}
+
+func TestIgnorePatternMatches(t *testing.T) {
+	tests := []struct {
+		glob, base string
+		name       string
+		isDir      bool
+		want       bool
+	}{
+		{glob: "*.o", name: "main.o", want: true},
+		{glob: "*.o", name: "src/deep/main.o", want: true},
+		{glob: "*.o", name: "main.c"},
+		{glob: "/todo", name: "todo", want: true},
+		{glob: "/todo", name: "src/todo"},
+		{glob: "doc/*.txt", name: "doc/a.txt", want: true},
+		{glob: "doc/*.txt", name: "doc/sub/a.txt"},
+		{glob: "doc/*.txt", name: "x/doc/a.txt"},
+		{glob: "doc/**/*.txt", name: "doc/sub/a.txt", want: true},
+		{glob: "doc/**/*.txt", name: "doc/a.txt", want: true},
+		{glob: "**/tmp", name: "a/b/tmp", want: true},
+		{glob: "build/", name: "build", isDir: true, want: true},
+		{glob: "build/", name: "build"},
+		{glob: "build/", name: "src/build", isDir: true, want: true},
+		{glob: "*.log", base: "sub", name: "sub/x/a.log", want: true},
+		{glob: "*.log", base: "sub", name: "a.log"},
+		{glob: "/only", base: "sub", name: "sub/only", want: true},
+		{glob: "/only", base: "sub", name: "sub/x/only"},
+		{glob: "f[0-9]", name: "f7", want: true},
+		{glob: "f[!0-9]", name: "f7"},
+	}
+	for _, tt := range tests {
+		p, ok := parsePattern(tt.glob, tt.base)
+		if !ok {
+			t.Fatalf("parsePattern(%q) failed", tt.glob)
+		}
+		if got := p.matches(tt.name, tt.isDir); got != tt.want {
+			t.Errorf("%q in %q matches(%q, dir=%v) = %v, want %v", tt.glob, tt.base, tt.name, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestReadIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, ".gitignore")
+	data := "# comment\n\n*.log  \n!keep.log\r\n\\!bang\n\\#hash\nbuild/\n"
+	if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	patterns := readIgnoreFile(file, "")
+	if len(patterns) != 5 {
+		t.Fatalf("read %d patterns, want 5", len(patterns))
+	}
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{name: "a.log", want: true},
+		{name: "sub/a.log", want: true},
+		{name: "keep.log"},
+		{name: "!bang", want: true},
+		{name: "#hash", want: true},
+		{name: "comment"},
+		{name: "build", isDir: true, want: true},
+		{name: "build"},
+	}
+	for _, tt := range tests {
+		if got := ignored(patterns, tt.name, tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, dir=%v) = %v, want %v", tt.name, tt.isDir, got, tt.want)
+		}
+	}
+	if readIgnoreFile(filepath.Join(dir, "missing"), "") != nil {
+		t.Error("a missing file has patterns")
+	}
+}
+
+func TestWalkUnignored(t *testing.T) {
+	testRepo(t)
+	excludes := filepath.Join(os.Getenv("HOME"), "excludes")
+	writeFiles(t, map[string]string{
+		excludes:               "*.swp\n",
+		".git/info/exclude":    "secret\n",
+		".gitignore":           "*.log\nbuild/\n/top\nignoredir\n",
+		"a.txt":                "",
+		"a.log":                "",
+		"a.swp":                "",
+		"secret":               "",
+		"top":                  "",
+		"build/out":            "",
+		"src/.gitignore":       "!keep.log\n*.tmp\n",
+		"src/keep.log":         "",
+		"src/drop.log":         "",
+		"src/x.tmp":            "",
+		"src/top":              "",
+		"src/build/out":        "",
+		"other/x.tmp":          "",
+		"other/deep/main.go":   "",
+		"ignoredir/.gitignore": "",
+	})
+	captureOutput(t, func() {
+		ConfigSet("core.excludesFile", excludes, false, ConfigOptions{})
+	})
+	var got []string
+	walkUnignored(func(f string) { got = append(got, f) })
+	sort.Strings(got)
+	want := []string{
+		".gitignore",
+		"a.txt",
+		"other/deep/main.go",
+		"other/x.tmp",
+		"src/.gitignore",
+		"src/keep.log",
+		"src/top",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walkUnignored visited %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,283 @@
+package regit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// lineRange is a region of a file being traced by log -L: lines Start up
+// to End (0-based, End exclusive).
+type lineRange struct {
+	File       string
+	Start, End int
+}
+
+// funcStart matches a line that may begin a function definition: one
+// starting with a letter, "_" or "$", as git assumes by default.
+var funcStart = regexp.MustCompile(`^[[:alpha:]$_]`)
+
+// parseLineLogRange resolves a -L argument, "<start>,<end>:<file>" or
+// ":<funcname>:<file>", against the file as of commit c.
+func parseLineLogRange(spec string, c commitEntry) (lineRange, error) {
+	i := strings.LastIndex(spec, ":")
+	if i < 0 {
+		return lineRange{}, fmt.Errorf("-L argument not 'start,end:file' or ':funcname:file': %s", spec)
+	}
+	r := lineRange{File: spec[i+1:]}
+	lines := fileLines(c, r.File)
+	if lines == nil {
+		return lineRange{}, fmt.Errorf("there is no path %s in the commit", r.File)
+	}
+	what := spec[:i]
+	if strings.HasPrefix(what, ":") {
+		re, err := regexp.Compile(what[1:])
+		if err != nil {
+			return lineRange{}, fmt.Errorf("invalid function name pattern %q: %v", what[1:], err)
+		}
+		r.Start = -1
+		for n, line := range lines {
+			if funcStart.MatchString(line) && re.MatchString(line) {
+				r.Start = n
+				break
+			}
+		}
+		if r.Start < 0 {
+			return lineRange{}, fmt.Errorf("-L parameter '%s': no match", what[1:])
+		}
+		r.End = r.Start + 1
+		for r.End < len(lines) && !funcStart.MatchString(lines[r.End]) {
+			r.End++
+		}
+		return r, nil
+	}
+	start, end, err := ParseLineRange(what)
+	if err != nil {
+		return lineRange{}, err
+	}
+	if start == 0 {
+		start = 1
+	}
+	if end == 0 || end > len(lines) {
+		end = len(lines)
+	}
+	if start > len(lines) || start > end {
+		return lineRange{}, fmt.Errorf("file %s has only %d lines", r.File, len(lines))
+	}
+	r.Start, r.End = start-1, end
+	return r, nil
+}
+
+// traceRange follows r from a commit back to its parent version of the
+// file, given the diff from parent to commit. It returns the hunk showing
+// how the commit changed the region, if it did, and the region in the
+// parent; born is set when no line of the region existed before.
+func traceRange(r lineRange, ops []diffOp) (hunk string, parent lineRange, born bool) {
+	parent = lineRange{File: r.File, Start: -1}
+	var region []diffOp
+	oldStart, changed := 0, false
+	i, j := 0, 0
+	for n, op := range ops {
+		inside := j >= r.Start && j < r.End
+		if op.Kind == '-' {
+			// Deleted lines belong to the region when they were between two of
+			// its lines, or were replaced by its first ones.
+			inside = j > r.Start && j < r.End
+			if j == r.Start {
+				k := n
+				for k < len(ops) && ops[k].Kind == '-' {
+					k++
+				}
+				inside = k < len(ops) && ops[k].Kind == '+'
+			}
+		}
+		if inside {
+			if len(region) == 0 {
+				oldStart = i
+			}
+			region = append(region, op)
+			if op.Kind != ' ' {
+				changed = true
+			}
+			if op.Kind != '+' {
+				if parent.Start < 0 {
+					parent.Start = i
+				}
+				parent.End = i + 1
+			}
+		}
+		if op.Kind != '+' {
+			i++
+		}
+		if op.Kind != '-' {
+			j++
+		}
+	}
+	if !changed {
+		return "", parent, false
+	}
+	oldCount, newCount := 0, 0
+	for _, op := range region {
+		if op.Kind != '+' {
+			oldCount++
+		}
+		if op.Kind != '-' {
+			newCount++
+		}
+	}
+	if oldCount > 0 {
+		oldStart++
+	}
+	var b strings.Builder
+	b.WriteString(hunkHeader(oldStart, oldCount, r.Start+1, newCount))
+	for _, op := range region {
+		b.WriteByte(op.Kind)
+		b.WriteString(strings.TrimSuffix(op.Line, "\n") + "\n")
+	}
+	return b.String(), parent, parent.Start < 0
+}
+
+// addRange adds r to ranges unless it is there already, as happens when
+// history that forked and merged again leads back to the same commit.
+func addRange(ranges []lineRange, r lineRange) []lineRange {
+	for _, have := range ranges {
+		if have == r {
+			return ranges
+		}
+	}
+	return append(ranges, r)
+}
+
+// rangeDiff renders the change to a traced region in patch form.
+func rangeDiff(oldFile, newFile, hunk string) string {
+	from := "a/" + oldFile
+	if oldFile == "" {
+		from, oldFile = "/dev/null", newFile
+	}
+	return "diff --git a/" + oldFile + " b/" + newFile + "\n--- " + from + "\n+++ b/" + newFile + "\n" + hunk
+}
+
+// lineLog shows the commits that changed the regions given with -L, each
+// followed by how it changed them. A region is followed back through the
+// first parent, or through any parent a merge took it from unchanged.
+func lineLog(opts LogOptions, format string, refs map[string][]string) error {
+	rev := "HEAD"
+	if len(opts.Revs) > 0 {
+		rev = opts.Revs[0]
+	}
+	start, err := resolveRevision(rev)
+	if err != nil {
+		return err
+	}
+	commits, err := readCommits()
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]commitEntry, len(commits))
+	for _, c := range commits {
+		byID[c.ID] = c
+	}
+	var ranges []lineRange
+	for _, spec := range opts.LineRanges {
+		r, err := parseLineLogRange(spec, byID[start])
+		if err != nil {
+			return err
+		}
+		ranges = append(ranges, r)
+	}
+	filter, err := logFilter(opts)
+	if err != nil {
+		return err
+	}
+	tracing := map[string][]lineRange{start: ranges}
+	shown := 0
+	for _, id := range revList([]string{start}, nil) {
+		c := byID[id]
+		regions, ok := tracing[id]
+		if !ok {
+			continue
+		}
+		var diffs []string
+		for _, r := range regions {
+			current := fileLines(c, r.File)
+			via := ""
+			for _, p := range c.Parents {
+				if byID[p].Files[r.File] == c.Files[r.File] {
+					via = p
+					break
+				}
+			}
+			if via != "" {
+				tracing[via] = addRange(tracing[via], r)
+				continue
+			}
+			var old []string
+			if len(c.Parents) > 0 {
+				via = c.Parents[0]
+				old = fileLines(byID[via], r.File)
+			}
+			if old == nil {
+				hunk, _, _ := traceRange(r, diffLines(nil, current))
+				diffs = append(diffs, rangeDiff("", r.File, hunk))
+				continue
+			}
+			hunk, parent, born := traceRange(r, diffLines(old, current))
+			if hunk != "" {
+				diffs = append(diffs, rangeDiff(r.File, r.File, hunk))
+			}
+			if !born {
+				tracing[via] = addRange(tracing[via], parent)
+			}
+		}
+		if len(diffs) == 0 || !filter(c) {
+			continue
+		}
+		if opts.MaxCount > 0 && shown == opts.MaxCount {
+			break
+		}
+		if shown > 0 {
+			fmt.Println()
+		}
+		shown++
+		if format != "" {
+			fmt.Println(formatCommit(format, c, refs[c.ID]))
+		} else {
+			fmt.Println(strings.Join(commitText(c, opts.ShowSignature, refs[c.ID]), "\n"))
+		}
+		fmt.Println()
+		fmt.Print(strings.Join(diffs, "\n"))
+	}
+	return nil
+}
+
+// pickaxeMatch reports whether a non-merge commit changes the number of
+// occurrences of opts.Pickaxe in a file, or adds or removes a line matching
+// diffRegex, in the files under opts.Paths.
+func pickaxeMatch(c commitEntry, byID map[string]commitEntry, opts LogOptions, diffRegex *regexp.Regexp) bool {
+	if len(c.Parents) > 1 {
+		return false
+	}
+	var parent map[string]string
+	if len(c.Parents) == 1 {
+		parent = byID[c.Parents[0]].Files
+	}
+	match := pathMatcher(opts.Paths)
+	for _, f := range changedFiles(parent, c.Files) {
+		if !match(f) {
+			continue
+		}
+		oldData, _ := readObject(parent[f])
+		newData, _ := readObject(c.Files[f])
+		if opts.Pickaxe != "" && strings.Count(string(oldData), opts.Pickaxe) != strings.Count(string(newData), opts.Pickaxe) {
+			return true
+		}
+		if diffRegex != nil {
+			for _, op := range diffLines(splitLines(oldData), splitLines(newData)) {
+				if op.Kind != ' ' && diffRegex.MatchString(op.Line) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
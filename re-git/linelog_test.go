@@ -0,0 +1,268 @@
+package regit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const lineLogSource = `package p
+
+func alpha() {
+	return 1
+}
+
+func beta() {
+	x := 2
+	return x
+}
+`
+
+func TestParseLineLogRange(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "base", map[string]string{"f": lineLogSource})
+	c, _ := loadCommit(headCommitID())
+	tests := []struct {
+		spec    string
+		want    lineRange
+		wantErr string
+	}{
+		{spec: "2,3:f", want: lineRange{"f", 1, 3}},
+		{spec: ",2:f", want: lineRange{"f", 0, 2}},
+		{spec: "4,+2:f", want: lineRange{"f", 3, 5}},
+		{spec: "9,:f", want: lineRange{"f", 8, 10}},
+		{spec: "8,99:f", want: lineRange{"f", 7, 10}},
+		{spec: ":beta:f", want: lineRange{"f", 6, 10}},
+		{spec: ":^func a:f", want: lineRange{"f", 2, 6}},
+		{spec: ":package:f", want: lineRange{"f", 0, 2}},
+		{spec: "11,12:f", wantErr: "file f has only 10 lines"},
+		{spec: "3,2:f", wantErr: "file f has only 10 lines"},
+		{spec: ":gamma:f", wantErr: "-L parameter 'gamma': no match"},
+		{spec: ":return:f", wantErr: "-L parameter 'return': no match"},
+		{spec: ":(:f", wantErr: "invalid function name pattern"},
+		{spec: "1,2:missing", wantErr: "there is no path missing in the commit"},
+		{spec: "1,2", wantErr: "-L argument not 'start,end:file' or ':funcname:file'"},
+		{spec: "x,2:f", wantErr: "invalid line range"},
+	}
+	for _, tt := range tests {
+		got, err := parseLineLogRange(tt.spec, c)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseLineLogRange(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseLineLogRange(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestTraceRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		old, new   string
+		r          lineRange
+		wantHunk   string
+		wantParent lineRange
+		wantBorn   bool
+	}{
+		{
+			name: "unchanged region moved down",
+			old:  "a\nb\nc\n",
+			new:  "new\na\nb\nc\n",
+			r:    lineRange{"f", 2, 4},
+			// Only the lines the region covers count.
+			wantParent: lineRange{"f", 1, 3},
+		},
+		{
+			name:       "line changed",
+			old:        "a\nb\nc\n",
+			new:        "a\nB\nc\n",
+			r:          lineRange{"f", 0, 3},
+			wantHunk:   "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			wantParent: lineRange{"f", 0, 3},
+		},
+		{
+			name:       "change outside the region",
+			old:        "a\nb\nc\n",
+			new:        "a\nb\nC\n",
+			r:          lineRange{"f", 0, 2},
+			wantParent: lineRange{"f", 0, 2},
+		},
+		{
+			name:       "lines deleted inside",
+			old:        "a\nb\nc\nd\n",
+			new:        "a\nd\n",
+			r:          lineRange{"f", 0, 2},
+			wantHunk:   "@@ -1,4 +1,2 @@\n a\n-b\n-c\n d\n",
+			wantParent: lineRange{"f", 0, 4},
+		},
+		{
+			name:       "first line replaced",
+			old:        "a\nb\nc\n",
+			new:        "a\nB\nc\n",
+			r:          lineRange{"f", 1, 2},
+			wantHunk:   "@@ -2 +2 @@\n-b\n+B\n",
+			wantParent: lineRange{"f", 1, 2},
+		},
+		{
+			name:       "lines deleted just before",
+			old:        "a\nb\nc\n",
+			new:        "a\nc\n",
+			r:          lineRange{"f", 1, 2},
+			wantParent: lineRange{"f", 2, 3},
+		},
+		{
+			name:       "region added",
+			old:        "a\n",
+			new:        "a\nx\ny\n",
+			r:          lineRange{"f", 1, 3},
+			wantHunk:   "@@ -1,0 +2,2 @@\n+x\n+y\n",
+			wantParent: lineRange{"f", -1, 0},
+			wantBorn:   true,
+		},
+		{
+			name:       "new file",
+			new:        "x\ny\n",
+			r:          lineRange{"f", 0, 2},
+			wantHunk:   "@@ -0,0 +1,2 @@\n+x\n+y\n",
+			wantParent: lineRange{"f", -1, 0},
+			wantBorn:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunk, parent, born := traceRange(tt.r, diffLines(splitLines([]byte(tt.old)), splitLines([]byte(tt.new))))
+			if hunk != tt.wantHunk || parent != tt.wantParent || born != tt.wantBorn {
+				t.Errorf("traceRange = %q, %+v, %v, want %q, %+v, %v", hunk, parent, born, tt.wantHunk, tt.wantParent, tt.wantBorn)
+			}
+		})
+	}
+}
+
+func TestLineLog(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "add alpha", map[string]string{"f": "package p\n\nfunc alpha() {\n\treturn 1\n}\n"})
+	commitFiles(t, "add beta", map[string]string{"f": lineLogSource})
+	commitFiles(t, "change alpha", map[string]string{"f": strings.Replace(lineLogSource, "return 1", "return 10", 1)})
+	commitFiles(t, "other file", map[string]string{"g": "x\n"})
+	commitFiles(t, "change beta", map[string]string{"f": strings.Replace(strings.Replace(lineLogSource, "return 1", "return 10", 1), "x := 2", "x := 3", 1)})
+
+	tests := []struct {
+		name   string
+		ranges []string
+		max    int
+		want   string
+	}{
+		{
+			name:   "function",
+			ranges: []string{":beta:f"},
+			want: `change beta
+
+diff --git a/f b/f
+--- a/f
++++ b/f
+@@ -7,4 +7,4 @@
+ func beta() {
+-	x := 2
++	x := 3
+ 	return x
+ }
+
+add beta
+
+diff --git a/f b/f
+--- a/f
++++ b/f
+@@ -5,0 +7,4 @@
++func beta() {
++	x := 2
++	return x
++}
+`,
+		},
+		{
+			name:   "line range",
+			ranges: []string{"4,4:f"},
+			want: `change alpha
+
+diff --git a/f b/f
+--- a/f
++++ b/f
+@@ -4 +4 @@
+-	return 1
++	return 10
+
+add alpha
+
+diff --git a/f b/f
+--- /dev/null
++++ b/f
+@@ -0,0 +4 @@
++	return 1
+`,
+		},
+		{
+			name:   "max count",
+			ranges: []string{":beta:f"},
+			max:    1,
+			want:   "change beta\n\ndiff --git a/f b/f\n--- a/f\n+++ b/f\n@@ -7,4 +7,4 @@\n func beta() {\n-\tx := 2\n+\tx := 3\n \treturn x\n }\n",
+		},
+		{
+			name:   "bad range",
+			ranges: []string{"1,2:missing"},
+			want:   "there is no path missing in the commit\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureOutput(t, func() { Log(LogOptions{LineRanges: tt.ranges, MaxCount: tt.max, Format: "%s"}) })
+			if got != tt.want {
+				t.Errorf("log -L printed\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickaxe(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "add foo", map[string]string{"f": "foo()\n"})
+	commitFiles(t, "call foo again", map[string]string{"f": "foo()\nfoo()\n"})
+	commitFiles(t, "move foo", map[string]string{"f": "bar()\nfoo()\nfoo()\n"})
+	commitFiles(t, "foo in g", map[string]string{"g": "foo = 1\n"})
+	commitFiles(t, "drop foo", map[string]string{"f": "bar()\n"})
+
+	tests := []struct {
+		name    string
+		opts    LogOptions
+		want    []string
+		wantErr string
+	}{
+		{name: "-S", opts: LogOptions{Pickaxe: "foo()"}, want: []string{"drop foo", "call foo again", "add foo"}},
+		{name: "-S any file", opts: LogOptions{Pickaxe: "foo"}, want: []string{"drop foo", "foo in g", "call foo again", "add foo"}},
+		{name: "-S with path", opts: LogOptions{Pickaxe: "foo", Paths: []string{"g"}}, want: []string{"foo in g"}},
+		{name: "-G", opts: LogOptions{DiffRegex: `^bar\(`}, want: []string{"move foo"}},
+		{name: "-G any change", opts: LogOptions{DiffRegex: "foo"}, want: []string{"drop foo", "foo in g", "call foo again", "add foo"}},
+		{name: "-G with -n", opts: LogOptions{DiffRegex: "foo", MaxCount: 2}, want: []string{"drop foo", "foo in g"}},
+		{name: "bad -G", opts: LogOptions{DiffRegex: "("}, wantErr: "invalid regular expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := queryLog(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			var got []string
+			for _, c := range commits {
+				got = append(got, subject(c.Message))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pickaxe selected %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
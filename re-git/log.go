@@ -0,0 +1,469 @@
+package regit
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogOptions select the commits log shows and how it prints them.
+//
+// Revs are the starting points (HEAD if none); "A..B" and "^A" exclude
+// what A reaches. MaxCount caps the output, Since and Until bound the
+// commit date and accept relative dates such as "2 weeks ago". Authors and
+// Greps are regular expressions matched against the author and the
+// message; a commit matches if any of them does, or with AllMatch if every
+// grep does. Paths keep only commits that change something under them.
+// All adds every branch and tag to Revs. Graph draws the history's shape
+// beside the commits and Decorate names the refs pointing at each one.
+// Pickaxe keeps commits that change how often a string occurs and
+// DiffRegex those adding or removing a line that matches it. LineRanges
+// ("<start>,<end>:<file>" or ":<funcname>:<file>") show the history of
+// those regions instead, with the diffs that changed them.
+type LogOptions struct {
+	Revs          []string
+	All           bool
+	MaxCount      int
+	Since, Until  string
+	Authors       []string
+	Greps         []string
+	IgnoreCase    bool
+	AllMatch      bool
+	Paths         []string
+	Reverse       bool
+	Oneline       bool
+	Format        string
+	ShowSignature bool
+	Graph         bool
+	Decorate      bool
+	Pickaxe       string
+	DiffRegex     string
+	LineRanges    []string
+}
+
+// logDateFormat is how log shows dates, as git does by default.
+const logDateFormat = "Mon Jan 2 15:04:05 2006 -0700"
+
+var relativeDateUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+}
+
+// parseApproxDate accepts what parseDate does plus "now", "today",
+// "yesterday" and "<n> <unit>s ago" (also written "<n>.<unit>s.ago").
+func parseApproxDate(s string) (time.Time, error) {
+	now := time.Now()
+	words := strings.Fields(strings.ReplaceAll(strings.ToLower(s), ".", " "))
+	switch {
+	case len(words) == 1 && words[0] == "now":
+		return now, nil
+	case len(words) == 1 && words[0] == "today":
+		y, m, d := now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+	case len(words) == 1 && words[0] == "yesterday":
+		y, m, d := now.AddDate(0, 0, -1).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+	case len(words) >= 2 && len(words) <= 3:
+		n, err := strconv.Atoi(words[0])
+		if err != nil || (len(words) == 3 && words[2] != "ago") {
+			break
+		}
+		unit := strings.TrimSuffix(words[1], "s")
+		switch unit {
+		case "month":
+			return now.AddDate(0, -n, 0), nil
+		case "year":
+			return now.AddDate(-n, 0, 0), nil
+		}
+		if d, ok := relativeDateUnits[unit]; ok {
+			return now.Add(-time.Duration(n) * d), nil
+		}
+	}
+	return parseDate(s)
+}
+
+// commitTime is when a commit was made, falling back to the author date
+// for commits that predate committer information.
+func commitTime(c commitEntry) time.Time {
+	date := c.CommitDate
+	if date == "" {
+		date = c.Date
+	}
+	t, _ := time.Parse(time.RFC3339, date)
+	return t
+}
+
+// logFilter compiles the matching options into a predicate on commits.
+func logFilter(opts LogOptions) (func(commitEntry) bool, error) {
+	compile := func(patterns []string) ([]*regexp.Regexp, error) {
+		var res []*regexp.Regexp
+		for _, p := range patterns {
+			if opts.IgnoreCase {
+				p = "(?i)" + p
+			}
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %v", p, err)
+			}
+			res = append(res, re)
+		}
+		return res, nil
+	}
+	authors, err := compile(opts.Authors)
+	if err != nil {
+		return nil, err
+	}
+	greps, err := compile(opts.Greps)
+	if err != nil {
+		return nil, err
+	}
+	var since, until time.Time
+	if opts.Since != "" {
+		if since, err = parseApproxDate(opts.Since); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Until != "" {
+		if until, err = parseApproxDate(opts.Until); err != nil {
+			return nil, err
+		}
+	}
+	return func(c commitEntry) bool {
+		t := commitTime(c)
+		if (!since.IsZero() && t.Before(since)) || (!until.IsZero() && t.After(until)) {
+			return false
+		}
+		// Authors are found under the name the mailmap gives them too.
+		if len(authors) > 0 && !anyMatch(authors, c.Author, false) && !anyMatch(authors, commitAuthor(c).String(), false) {
+			return false
+		}
+		return len(greps) == 0 || anyMatch(greps, c.Message, opts.AllMatch)
+	}, nil
+}
+
+// anyMatch reports whether any of res matches s, or with all whether every
+// one does.
+func anyMatch(res []*regexp.Regexp, s string, all bool) bool {
+	for _, re := range res {
+		if re.MatchString(s) != all {
+			return !all
+		}
+	}
+	return all
+}
+
+// touchesPaths reports whether c changes a file under paths. A merge only
+// counts when it differs there from every parent, so that changes merged
+// in are shown once, on the branch that made them.
+func touchesPaths(c commitEntry, byID map[string]commitEntry, paths []string) bool {
+	match := pathMatcher(paths)
+	parents := c.Parents
+	if len(parents) == 0 {
+		parents = []string{""}
+	}
+	for _, p := range parents {
+		changed := false
+		for _, f := range changedFiles(byID[p].Files, c.Files) {
+			if match(f) {
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			return false
+		}
+	}
+	return true
+}
+
+// queryLog returns the commits opts select, newest first unless Reverse is
+// set.
+func queryLog(opts LogOptions) ([]commitEntry, error) {
+	var include, exclude []string
+	revs := opts.Revs
+	if opts.All {
+		revs = append(revs, "HEAD")
+		for _, name := range branchNames() {
+			revs = append(revs, "refs/heads/"+name)
+		}
+		for _, name := range tagNames() {
+			revs = append(revs, "refs/tags/"+name)
+		}
+	}
+	if len(revs) == 0 {
+		revs = []string{"HEAD"}
+	}
+	for _, rev := range revs {
+		target := &include
+		if strings.HasPrefix(rev, "^") {
+			target, rev = &exclude, rev[1:]
+		} else if from, to, ok := strings.Cut(rev, ".."); ok {
+			if from != "" {
+				id, err := resolveRevision(from)
+				if err != nil {
+					return nil, err
+				}
+				exclude = append(exclude, id)
+			}
+			if rev = to; rev == "" {
+				rev = "HEAD"
+			}
+		}
+		id, err := resolveRevision(rev)
+		if err != nil {
+			return nil, err
+		}
+		*target = append(*target, id)
+	}
+	filter, err := logFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+	var diffRegex *regexp.Regexp
+	if opts.DiffRegex != "" {
+		if diffRegex, err = regexp.Compile(opts.DiffRegex); err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %v", opts.DiffRegex, err)
+		}
+	}
+	commits, err := readCommits()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]commitEntry, len(commits))
+	for _, c := range commits {
+		byID[c.ID] = c
+	}
+	var selected []commitEntry
+	for _, id := range revList(include, exclude) {
+		if opts.MaxCount > 0 && len(selected) == opts.MaxCount {
+			break
+		}
+		c := byID[id]
+		if !filter(c) || (len(opts.Paths) > 0 && !touchesPaths(c, byID, opts.Paths)) {
+			continue
+		}
+		if (opts.Pickaxe != "" || diffRegex != nil) && !pickaxeMatch(c, byID, opts, diffRegex) {
+			continue
+		}
+		selected = append(selected, c)
+	}
+	if opts.Reverse {
+		for i, j := 0, len(selected)-1; i < j; i, j = i+1, j-1 {
+			selected[i], selected[j] = selected[j], selected[i]
+		}
+	}
+	return selected, nil
+}
+
+// messageBody is a commit message without its subject line.
+func messageBody(message string) string {
+	_, body, _ := strings.Cut(message, "\n")
+	return strings.TrimLeft(body, "\n")
+}
+
+// formatCommit expands the placeholders of a --format string: %H and %h
+// for the commit ID, %an, %ae and %ad for the author's name, email and
+// date, %cn, %ce and %cd for the committer's (%aN, %aE, %cN and %cE give
+// the names and emails the mailmap maps them to), %s and %b for the
+// subject and body, %d and %D for the refs pointing at the commit
+// (" (a, b)" and "a, b"), %n for a newline and %% for a percent sign.
+func formatCommit(format string, c commitEntry, refs []string) string {
+	author, _ := parseIdentity(c.Author)
+	committer, _ := parseIdentity(c.Committer)
+	authorDate, _ := time.Parse(time.RFC3339, c.Date)
+	mappedAuthor, mappedCommitter := canonicalIdentity(author), canonicalIdentity(committer)
+	values := map[string]string{
+		"H": c.ID, "h": shortID(c.ID),
+		"an": author.Name, "ae": author.Email, "ad": authorDate.Format(logDateFormat),
+		"aN": mappedAuthor.Name, "aE": mappedAuthor.Email,
+		"cn": committer.Name, "ce": committer.Email, "cd": commitTime(c).Format(logDateFormat),
+		"cN": mappedCommitter.Name, "cE": mappedCommitter.Email,
+		"s": subject(c.Message), "b": messageBody(c.Message),
+		"d": decoration(refs), "D": strings.Join(refs, ", "),
+		"n": "\n", "%": "%",
+	}
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] == '%' {
+			if v, ok := values[format[i+1:min(i+3, len(format))]]; ok {
+				b.WriteString(v)
+				i += 2
+				continue
+			}
+			if v, ok := values[format[i+1:min(i+2, len(format))]]; ok {
+				b.WriteString(v)
+				i++
+				continue
+			}
+		}
+		b.WriteByte(format[i])
+	}
+	return b.String()
+}
+
+// refDecorations maps commits to the names of the refs pointing at them:
+// "HEAD -> <branch>" (or "HEAD" when detached), other branches, then tags
+// as "tag: <name>".
+func refDecorations() map[string][]string {
+	refs := map[string][]string{}
+	head, branch := headCommitID(), currentBranch()
+	if head != "" {
+		if branch != "" && readRef("refs/heads/"+branch) == head {
+			refs[head] = append(refs[head], "HEAD -> "+branch)
+		} else {
+			refs[head] = append(refs[head], "HEAD")
+		}
+	}
+	for _, name := range branchNames() {
+		if id := readRef("refs/heads/" + name); isObjectID(id) && name != branch {
+			refs[id] = append(refs[id], name)
+		}
+	}
+	for _, name := range tagNames() {
+		if id := peelTag(readRef("refs/tags/" + name)); isObjectID(id) {
+			refs[id] = append(refs[id], "tag: "+name)
+		}
+	}
+	return refs
+}
+
+func decoration(refs []string) string {
+	if len(refs) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(refs, ", ") + ")"
+}
+
+// commitText describes a commit the way git log does by default.
+func commitText(c commitEntry, showSignature bool, refs []string) []string {
+	lines := []string{"commit " + c.ID + decoration(refs)}
+	if showSignature && c.Signature != "" {
+		lines = append(lines, commitSignature(c).String())
+	}
+	if len(c.Parents) > 1 {
+		var short []string
+		for _, p := range c.Parents {
+			short = append(short, shortID(p))
+		}
+		lines = append(lines, "Merge: "+strings.Join(short, " "))
+	}
+	if c.Author != "" {
+		lines = append(lines, "Author: "+c.Author)
+	}
+	if date, err := time.Parse(time.RFC3339, c.Date); err == nil {
+		lines = append(lines, "Date:   "+date.Format(logDateFormat))
+	}
+	lines = append(lines, "")
+	for _, line := range strings.Split(c.Message, "\n") {
+		lines = append(lines, "    "+line)
+	}
+	return lines
+}
+
+// Log shows the history selected by opts, newest first. Arguments in Revs
+// that are not revisions but name files are taken as paths.
+func Log(opts LogOptions) {
+	if opts.Graph && (opts.Reverse || len(opts.LineRanges) > 0) {
+		fmt.Println("--graph cannot be used with --reverse or -L")
+		return
+	}
+	var revs []string
+	for _, rev := range opts.Revs {
+		if _, err := resolveRevision(strings.TrimPrefix(rev, "^")); err != nil && !strings.Contains(rev, "..") {
+			if _, statErr := os.Stat(rev); statErr == nil {
+				opts.Paths = append(opts.Paths, rev)
+				continue
+			}
+			fmt.Printf("ambiguous argument '%s': unknown revision or path\n", rev)
+			return
+		}
+		revs = append(revs, rev)
+	}
+	opts.Revs = revs
+	if len(revs) == 0 && headCommitID() == "" {
+		fmt.Println("No commits yet")
+		return
+	}
+	commits, err := queryLog(opts)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	format := strings.TrimPrefix(strings.TrimPrefix(opts.Format, "tformat:"), "format:")
+	var refs map[string][]string
+	if opts.Decorate || strings.Contains(format, "%d") || strings.Contains(format, "%D") {
+		refs = refDecorations()
+	}
+	switch {
+	case opts.Oneline || format == "oneline":
+		format = "%h %s"
+		if opts.Decorate {
+			format = "%h%d %s"
+		}
+	case format == "medium":
+		format = ""
+	}
+	if len(opts.LineRanges) > 0 {
+		if err := lineLog(opts, format, refs); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+	shown := map[string]bool{}
+	for _, c := range commits {
+		shown[c.ID] = true
+	}
+	var graph logGraph
+	for i, c := range commits {
+		var text []string
+		if format != "" {
+			text = strings.Split(formatCommit(format, c, refs[c.ID]), "\n")
+		} else {
+			text = commitText(c, opts.ShowSignature, refs[c.ID])
+			if i < len(commits)-1 {
+				text = append(text, "")
+			}
+		}
+		if opts.Graph {
+			// Only the parents log shows get a lane.
+			var parents []string
+			for _, p := range c.Parents {
+				if shown[p] {
+					parents = append(parents, p)
+				}
+			}
+			text = graph.add(c, parents, text)
+		}
+		for _, line := range text {
+			fmt.Println(line)
+		}
+	}
+}
+
+// FindCommitByMessage returns the log indices of the commits whose message
+// contains substring.
+func FindCommitByMessage(substring string) []int {
+	match, err := logFilter(LogOptions{Greps: []string{regexp.QuoteMeta(substring)}})
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+	commits, err := readCommits()
+	if err != nil {
+		fmt.Println("Error reading log")
+		return nil
+	}
+	var indices []int
+	for i, c := range commits {
+		if match(c) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
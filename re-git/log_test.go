@@ -0,0 +1,242 @@
+package regit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseApproxDate(t *testing.T) {
+	now := time.Now()
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "now", want: now},
+		{in: "today", want: today},
+		{in: "Yesterday", want: today.AddDate(0, 0, -1)},
+		{in: "3 days ago", want: now.Add(-3 * 24 * time.Hour)},
+		{in: "3.days.ago", want: now.Add(-3 * 24 * time.Hour)},
+		{in: "1 day", want: now.Add(-24 * time.Hour)},
+		{in: "2 weeks ago", want: now.Add(-14 * 24 * time.Hour)},
+		{in: "90 minutes ago", want: now.Add(-90 * time.Minute)},
+		{in: "10 seconds ago", want: now.Add(-10 * time.Second)},
+		{in: "5 hours ago", want: now.Add(-5 * time.Hour)},
+		{in: "2 months ago", want: now.AddDate(0, -2, 0)},
+		{in: "1 year ago", want: now.AddDate(-1, 0, 0)},
+		{in: "2024-03-01", want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)},
+		{in: "2 fortnights ago", wantErr: true},
+		{in: "some days ago", wantErr: true},
+		{in: "3 days hence", wantErr: true},
+		{in: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseApproxDate(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseApproxDate(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.Sub(tt.want).Abs() > time.Minute {
+			t.Errorf("parseApproxDate(%q) = %v, want about %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// logHistory makes four commits with fixed authors and dates:
+//
+//	init            Alice  2024-01-01  a
+//	Fix bug         Bob    2024-02-01  b
+//	add feature     Alice  2024-03-01  a  (body "fixes #12")
+//	docs: readme    Carol  2024-04-01  docs/readme
+func logHistory(t *testing.T) {
+	t.Helper()
+	testRepo(t)
+	for _, c := range []struct {
+		message, author, date, file string
+	}{
+		{"init", "Alice <alice@example.com>", "2024-01-01T10:00:00Z", "a"},
+		{"Fix bug", "Bob <bob@example.com>", "2024-02-01T10:00:00Z", "b"},
+		{"add feature\n\nfixes #12", "Alice <alice@example.com>", "2024-03-01T10:00:00Z", "a"},
+		{"docs: readme", "Carol <carol@example.com>", "2024-04-01T10:00:00Z", "docs/readme"},
+	} {
+		t.Setenv("REGIT_COMMITTER_DATE", c.date)
+		writeFiles(t, map[string]string{c.file: c.message + "\n"})
+		out := captureOutput(t, func() {
+			Add(c.file)
+			Commit(c.message, nil, CommitOptions{Author: c.author, Date: c.date})
+		})
+		if commitSubject(headCommitID()) != subject(c.message) {
+			t.Fatalf("commit %q was not made:\n%s", c.message, out)
+		}
+	}
+}
+
+func TestQueryLog(t *testing.T) {
+	logHistory(t)
+	tests := []struct {
+		name    string
+		opts    LogOptions
+		want    []string
+		wantErr string
+	}{
+		{name: "all", want: []string{"docs: readme", "add feature", "Fix bug", "init"}},
+		{name: "max count", opts: LogOptions{MaxCount: 2}, want: []string{"docs: readme", "add feature"}},
+		{name: "since", opts: LogOptions{Since: "2024-02-15"}, want: []string{"docs: readme", "add feature"}},
+		{name: "until", opts: LogOptions{Until: "2024-02-01T10:00:00Z"}, want: []string{"Fix bug", "init"}},
+		{name: "since and until", opts: LogOptions{Since: "2024-01-15", Until: "2024-03-15"}, want: []string{"add feature", "Fix bug"}},
+		{name: "since relative", opts: LogOptions{Since: "1 day ago"}, want: nil},
+		{name: "author", opts: LogOptions{Authors: []string{"Alice"}}, want: []string{"add feature", "init"}},
+		{name: "author by email", opts: LogOptions{Authors: []string{"bob@"}}, want: []string{"Fix bug"}},
+		{name: "author is case sensitive", opts: LogOptions{Authors: []string{"alice "}}, want: nil},
+		{name: "author ignoring case", opts: LogOptions{Authors: []string{"^alice "}, IgnoreCase: true}, want: []string{"add feature", "init"}},
+		{name: "several authors", opts: LogOptions{Authors: []string{"Bob", "Carol"}}, want: []string{"docs: readme", "Fix bug"}},
+		{name: "grep", opts: LogOptions{Greps: []string{"fix"}}, want: []string{"add feature"}},
+		{name: "grep ignoring case", opts: LogOptions{Greps: []string{"fix"}, IgnoreCase: true}, want: []string{"add feature", "Fix bug"}},
+		{name: "grep any", opts: LogOptions{Greps: []string{"^init", "readme$"}}, want: []string{"docs: readme", "init"}},
+		{name: "grep all", opts: LogOptions{Greps: []string{"fix", "#1[0-9]"}, AllMatch: true}, want: []string{"add feature"}},
+		{name: "grep all failing", opts: LogOptions{Greps: []string{"fix", "readme"}, AllMatch: true}, want: nil},
+		{name: "author and grep", opts: LogOptions{Authors: []string{"Alice"}, Greps: []string{"init"}}, want: []string{"init"}},
+		{name: "path", opts: LogOptions{Paths: []string{"a"}}, want: []string{"add feature", "init"}},
+		{name: "directory", opts: LogOptions{Paths: []string{"docs"}}, want: []string{"docs: readme"}},
+		{name: "reverse", opts: LogOptions{Reverse: true, MaxCount: 3}, want: []string{"Fix bug", "add feature", "docs: readme"}},
+		{name: "revision", opts: LogOptions{Revs: []string{"HEAD~2"}}, want: []string{"Fix bug", "init"}},
+		{name: "range", opts: LogOptions{Revs: []string{"HEAD~2..HEAD"}}, want: []string{"docs: readme", "add feature"}},
+		{name: "open range", opts: LogOptions{Revs: []string{"HEAD~1.."}}, want: []string{"docs: readme"}},
+		{name: "exclusion", opts: LogOptions{Revs: []string{"HEAD", "^HEAD~3"}}, want: []string{"docs: readme", "add feature", "Fix bug"}},
+		{name: "bad regex", opts: LogOptions{Greps: []string{"("}}, wantErr: "invalid regular expression"},
+		{name: "bad date", opts: LogOptions{Since: "soon"}, wantErr: "invalid date: soon"},
+		{name: "bad revision", opts: LogOptions{Revs: []string{"nope"}}, wantErr: "nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := queryLog(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range commits {
+				got = append(got, subject(c.Message))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("log selected %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatCommit(t *testing.T) {
+	c := commitEntry{
+		ID:         strings.Repeat("ab", 20),
+		Author:     "Alice <alice@example.com>",
+		Date:       "2024-03-01T10:00:00+01:00",
+		Committer:  "Bob <bob@example.com>",
+		CommitDate: "2024-03-02T11:00:00Z",
+		Message:    "Subject line\n\nBody one.\nBody two.",
+	}
+	tests := []struct {
+		format string
+		refs   []string
+		want   string
+	}{
+		{"%H", nil, c.ID},
+		{"%h %s", nil, shortID(c.ID) + " Subject line"},
+		{"%an <%ae>", nil, "Alice <alice@example.com>"},
+		{"%cn <%ce>", nil, "Bob <bob@example.com>"},
+		{"%ad", nil, "Fri Mar 1 10:00:00 2024 +0100"},
+		{"%cd", nil, "Sat Mar 2 11:00:00 2024 +0000"},
+		{"%b", nil, "Body one.\nBody two."},
+		{"%s%n%n%b", nil, "Subject line\n\nBody one.\nBody two."},
+		{"%h%d", []string{"HEAD -> main", "tag: v1"}, shortID(c.ID) + " (HEAD -> main, tag: v1)"},
+		{"[%D]", []string{"main"}, "[main]"},
+		{"[%d]", nil, "[]"},
+		{"100%% %x %", nil, "100% %x %"},
+	}
+	for _, tt := range tests {
+		if got := formatCommit(tt.format, c, tt.refs); got != tt.want {
+			t.Errorf("formatCommit(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestLogOutput(t *testing.T) {
+	logHistory(t)
+	head := headCommitID()
+	tests := []struct {
+		name string
+		opts LogOptions
+		want string
+	}{
+		{
+			name: "oneline",
+			opts: LogOptions{Oneline: true, MaxCount: 2},
+			want: shortID(head) + " docs: readme\n" + shortID(commitParent(head)) + " add feature\n",
+		},
+		{
+			name: "format",
+			opts: LogOptions{Format: "format:%an: %s", Reverse: true},
+			want: "Alice: init\nBob: Fix bug\nAlice: add feature\nCarol: docs: readme\n",
+		},
+		{
+			name: "path argument",
+			opts: LogOptions{Revs: []string{"b"}, Format: "%s"},
+			want: "Fix bug\n",
+		},
+		{
+			name: "unknown argument",
+			opts: LogOptions{Revs: []string{"nope"}},
+			want: "ambiguous argument 'nope': unknown revision or path\n",
+		},
+		{
+			name: "medium",
+			opts: LogOptions{Revs: []string{"HEAD~1"}, MaxCount: 1},
+			want: "commit " + commitParent(head) + "\nAuthor: Alice <alice@example.com>\nDate:   Fri Mar 1 10:00:00 2024 +0000\n\n    add feature\n    \n    fixes #12\n",
+		},
+		{
+			name: "decorate",
+			opts: LogOptions{Oneline: true, Decorate: true, MaxCount: 1},
+			want: shortID(head) + " (HEAD -> master) docs: readme\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := captureOutput(t, func() { Log(tt.opts) }); got != tt.want {
+				t.Errorf("log printed\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func commitParent(id string) string {
+	c, _ := loadCommit(id)
+	return c.Parents[0]
+}
+
+func TestFindCommitByMessage(t *testing.T) {
+	logHistory(t)
+	tests := []struct {
+		substring string
+		want      []int
+	}{
+		{"feature", []int{2}},
+		{"i", []int{0, 1, 2}},
+		{"#12", []int{2}},
+		{"docs: ", []int{3}},
+		{"(", nil},
+		{"fix", []int{2}},
+	}
+	for _, tt := range tests {
+		if got := FindCommitByMessage(tt.substring); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindCommitByMessage(%q) = %v, want %v", tt.substring, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package regit
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// A mailmap maps the names and emails people committed under to the ones
+// they want to be known by. It is read from .mailmap at the top of the
+// working tree and then from the file mailmap.file names, each line being
+// one of
+//
+//	Proper Name <commit email>
+//	<proper email> <commit email>
+//	Proper Name <proper email> <commit email>
+//	Proper Name <proper email> Commit Name <commit email>
+//
+// Emails and names are matched ignoring case; "#" starts a comment.
+type mailmap map[mailmapKey]identity
+
+// mailmapKey is a commit email, lower-cased, with the commit name it
+// applies to or "" for any.
+type mailmapKey struct {
+	Email, Name string
+}
+
+// cutAddress splits "Name <email> rest" into its parts.
+func cutAddress(s string) (name, email, rest string, ok bool) {
+	open, end := strings.Index(s, "<"), strings.Index(s, ">")
+	if open < 0 || end < open {
+		return "", "", s, false
+	}
+	return strings.TrimSpace(s[:open]), strings.TrimSpace(s[open+1 : end]), s[end+1:], true
+}
+
+// readMailmapFile adds the entries of file to m. Later entries for the
+// same identity fill in or replace what earlier ones gave.
+func readMailmapFile(m mailmap, file string) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		name, email, rest, ok := cutAddress(line)
+		if !ok {
+			continue
+		}
+		proper := identity{Name: name}
+		key := mailmapKey{Email: strings.ToLower(email)}
+		if oldName, oldEmail, _, ok := cutAddress(rest); ok {
+			proper.Email = email
+			key = mailmapKey{strings.ToLower(oldEmail), strings.ToLower(oldName)}
+		} else if name == "" {
+			continue
+		}
+		entry := m[key]
+		if proper.Name != "" {
+			entry.Name = proper.Name
+		}
+		if proper.Email != "" {
+			entry.Email = proper.Email
+		}
+		m[key] = entry
+	}
+}
+
+// readMailmap reads .mailmap and then the file mailmap.file names.
+func readMailmap() mailmap {
+	m := mailmap{}
+	readMailmapFile(m, ".mailmap")
+	if file := configPath("mailmap.file"); file != "" {
+		readMailmapFile(m, file)
+	}
+	return m
+}
+
+// loadMailmap reads the mailmap the first time it is needed.
+var loadMailmap = sync.OnceValue(readMailmap)
+
+// canonicalIdentity is who id is according to the mailmap.
+func canonicalIdentity(id identity) identity {
+	m := loadMailmap()
+	email := strings.ToLower(id.Email)
+	entry, ok := m[mailmapKey{email, strings.ToLower(id.Name)}]
+	if !ok {
+		if entry, ok = m[mailmapKey{Email: email}]; !ok {
+			return id
+		}
+	}
+	if entry.Name != "" {
+		id.Name = entry.Name
+	}
+	if entry.Email != "" {
+		id.Email = entry.Email
+	}
+	return id
+}
@@ -0,0 +1,143 @@
+package regit
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useMailmap makes canonicalIdentity read the mailmap afresh on every call
+// for the rest of the test, rather than once per process.
+func useMailmap(t *testing.T) {
+	t.Helper()
+	saved := loadMailmap
+	loadMailmap = readMailmap
+	t.Cleanup(func() { loadMailmap = saved })
+}
+
+func TestCutAddress(t *testing.T) {
+	tests := []struct {
+		in                string
+		name, email, rest string
+		ok                bool
+	}{
+		{"Jane Doe <jane@example.com>", "Jane Doe", "jane@example.com", "", true},
+		{"  <a@x>  <b@x>", "", "a@x", "  <b@x>", true},
+		{"Jane <j@x> Old <o@x>", "Jane", "j@x", " Old <o@x>", true},
+		{"no address", "", "", "no address", false},
+		{"broken > <", "", "", "broken > <", false},
+	}
+	for _, tt := range tests {
+		name, email, rest, ok := cutAddress(tt.in)
+		if name != tt.name || email != tt.email || rest != tt.rest || ok != tt.ok {
+			t.Errorf("cutAddress(%q) = %q, %q, %q, %v, want %q, %q, %q, %v",
+				tt.in, name, email, rest, ok, tt.name, tt.email, tt.rest, tt.ok)
+		}
+	}
+}
+
+func TestReadMailmapFile(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want mailmap
+	}{
+		{
+			name: "proper name",
+			data: "Jane Doe <Jane@Example.com>\n",
+			want: mailmap{{Email: "jane@example.com"}: {Name: "Jane Doe"}},
+		},
+		{
+			name: "proper email",
+			data: "<jane@example.com> <jd@old.example>\n",
+			want: mailmap{{Email: "jd@old.example"}: {Email: "jane@example.com"}},
+		},
+		{
+			name: "proper name and email",
+			data: "Jane Doe <jane@example.com> <jd@old.example>\n",
+			want: mailmap{{Email: "jd@old.example"}: {Name: "Jane Doe", Email: "jane@example.com"}},
+		},
+		{
+			name: "commit name and email",
+			data: "Jane Doe <jane@example.com> JD <Shared@Example.com>\n",
+			want: mailmap{{Email: "shared@example.com", Name: "jd"}: {Name: "Jane Doe", Email: "jane@example.com"}},
+		},
+		{
+			name: "comments and junk",
+			data: "# Jane <j@x>\n\nno address here\n<only@email>\nJane Doe <jane@example.com> # <old@x>\n",
+			want: mailmap{{Email: "jane@example.com"}: {Name: "Jane Doe"}},
+		},
+		{
+			name: "later lines fill in",
+			data: "<jane@example.com> <jd@old.example>\nJane Doe <jd@old.example>\nJ. Doe <jd@old.example>\n",
+			want: mailmap{{Email: "jd@old.example"}: {Name: "J. Doe", Email: "jane@example.com"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "mailmap")
+			if err := ioutil.WriteFile(file, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got := mailmap{}
+			readMailmapFile(got, file)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readMailmapFile = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalIdentity(t *testing.T) {
+	testRepo(t)
+	useMailmap(t)
+	other := filepath.Join(t.TempDir(), "other")
+	writeFiles(t, map[string]string{
+		".mailmap": "Jane Doe <jane@example.com>\n" +
+			"Jane Doe <jane@example.com> <jd@old.example>\n" +
+			"Bot Owner <owner@example.com> Bot <shared@example.com>\n",
+		other: "<bob@example.com> <bob@old.example>\n",
+	})
+	captureOutput(t, func() { ConfigSet("mailmap.file", other, false, ConfigOptions{}) })
+	tests := []struct {
+		in, want identity
+	}{
+		{identity{"jane", "jane@example.com"}, identity{"Jane Doe", "jane@example.com"}},
+		{identity{"JD", "JD@old.example"}, identity{"Jane Doe", "jane@example.com"}},
+		{identity{"bot", "shared@example.com"}, identity{"Bot Owner", "owner@example.com"}},
+		{identity{"Someone", "shared@example.com"}, identity{"Someone", "shared@example.com"}},
+		{identity{"Bob", "bob@old.example"}, identity{"Bob", "bob@example.com"}},
+		{identity{"Stranger", "s@example.com"}, identity{"Stranger", "s@example.com"}},
+	}
+	for _, tt := range tests {
+		if got := canonicalIdentity(tt.in); got != tt.want {
+			t.Errorf("canonicalIdentity(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMailmapCommands(t *testing.T) {
+	logHistory(t)
+	useMailmap(t)
+	writeFiles(t, map[string]string{".mailmap": "Robert <robert@example.com> <bob@example.com>\nAlice Smith <alice@example.com>\n"})
+
+	got := captureOutput(t, func() { Shortlog(ShortlogOptions{Summary: true, Email: true}) })
+	want := "     2\tAlice Smith <alice@example.com>\n     1\tCarol <carol@example.com>\n     1\tRobert <robert@example.com>\n"
+	if got != want {
+		t.Errorf("Shortlog printed:\n%s\nwant:\n%s", got, want)
+	}
+
+	for _, author := range []string{"Robert", "robert@example", "Bob"} {
+		got = captureOutput(t, func() { Log(LogOptions{Authors: []string{author}, Format: "%s|%an|%aN <%aE>"}) })
+		if got != "Fix bug|Bob|Robert <robert@example.com>\n" {
+			t.Errorf("log --author=%s printed %q", author, got)
+		}
+	}
+
+	got = captureOutput(t, func() { Blame("", "b", BlameOptions{}) })
+	if !strings.Contains(got, "(Robert ") {
+		t.Errorf("blame does not use the mailmap:\n%s", got)
+	}
+}
@@ -0,0 +1,131 @@
+package regit
+
+import (
+	"sort"
+	"strings"
+)
+
+// merge3 merges the changes base->ours and base->theirs line by line. Where
+// both sides changed the same region differently the result contains
+// conflict markers and conflict is true.
+func merge3(base, ours, theirs []string, oursLabel, theirsLabel string) (result []string, conflict bool) {
+	mo := matchLines(base, ours)
+	mt := matchLines(base, theirs)
+	i, o, t := 0, 0, 0
+	for i < len(base) || o < len(ours) || t < len(theirs) {
+		// Copy lines all three versions agree on.
+		if i < len(base) && mo[i] == o && mt[i] == t {
+			result = append(result, base[i])
+			i, o, t = i+1, o+1, t+1
+			continue
+		}
+		// Find the next base line both sides kept; everything before it is
+		// one changed chunk.
+		end := i
+		for end < len(base) && (mo[end] < 0 || mt[end] < 0) {
+			end++
+		}
+		oEnd, tEnd := len(ours), len(theirs)
+		if end < len(base) {
+			oEnd, tEnd = mo[end], mt[end]
+		}
+		b, oc, tc := base[i:end], ours[o:oEnd], theirs[t:tEnd]
+		switch {
+		case equalLines(oc, tc), equalLines(tc, b):
+			result = append(result, oc...)
+		case equalLines(oc, b):
+			result = append(result, tc...)
+		default:
+			conflict = true
+			result = append(result, "<<<<<<< "+oursLabel+"\n")
+			result = append(result, terminated(oc)...)
+			result = append(result, "=======\n")
+			result = append(result, terminated(tc)...)
+			result = append(result, ">>>>>>> "+theirsLabel+"\n")
+		}
+		i, o, t = end, oEnd, tEnd
+	}
+	return result, conflict
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// terminated makes sure the last line ends in a newline so conflict markers
+// start on a line of their own.
+func terminated(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+	out := append([]string{}, lines...)
+	out[len(out)-1] += "\n"
+	return out
+}
+
+// mergeTrees merges the changes base->theirs into ours. Cleanly merged files
+// are returned in tree (content merges are written to the object store);
+// conflicted files keep ours' version in tree and their working-tree
+// contents, with conflict markers, are returned in conflicts.
+func mergeTrees(base, ours, theirs map[string]string, oursLabel, theirsLabel string) (tree map[string]string, conflicts map[string][]byte) {
+	tree = map[string]string{}
+	conflicts = map[string][]byte{}
+	paths := map[string]bool{}
+	for _, m := range []map[string]string{base, ours, theirs} {
+		for f := range m {
+			paths[f] = true
+		}
+	}
+	for f := range paths {
+		b, o, t := base[f], ours[f], theirs[f]
+		result := o
+		switch {
+		case o == t, t == b:
+		case o == b:
+			result = t
+		case o == "" || t == "":
+			// Modified on one side, deleted on the other: keep the
+			// modified version in the working tree.
+			kept := o
+			if kept == "" {
+				kept = t
+			}
+			data, _ := readObject(kept)
+			conflicts[f] = data
+		default:
+			bd, _ := readObject(b)
+			od, _ := readObject(o)
+			td, _ := readObject(t)
+			lines, conflict := merge3(splitLines(bd), splitLines(od), splitLines(td), oursLabel, theirsLabel)
+			data := []byte(strings.Join(lines, ""))
+			if conflict {
+				conflicts[f] = data
+			} else if oid, err := writeObject(data); err == nil {
+				result = oid
+			} else {
+				conflicts[f] = data
+			}
+		}
+		if result != "" {
+			tree[f] = result
+		}
+	}
+	return tree, conflicts
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package regit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		conflict           bool
+	}{
+		{"unchanged", "a\nb\n", "a\nb\n", "a\nb\n", "a\nb\n", false},
+		{"ours only", "a\nb\n", "a\nB\n", "a\nb\n", "a\nB\n", false},
+		{"theirs only", "a\nb\n", "a\nb\n", "A\nb\n", "A\nb\n", false},
+		{"both, apart", "a\nb\nc\n", "A\nb\nc\n", "a\nb\nC\n", "A\nb\nC\n", false},
+		{"both, the same", "a\nb\n", "a\nX\n", "a\nX\n", "a\nX\n", false},
+		{"theirs appends", "a\nb\n", "A\nb\n", "a\nb\nc\n", "A\nb\nc\n", false},
+		{
+			"adjacent changes", "a\n", "A\n", "a\nb\n",
+			"<<<<<<< HEAD\nA\n=======\na\nb\n>>>>>>> other\n", true,
+		},
+		{
+			"both, differently", "a\nb\nc\n", "a\nours\nc\n", "a\ntheirs\nc\n",
+			"a\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> other\nc\n", true,
+		},
+		{
+			"unterminated last lines", "a", "b", "c",
+			"<<<<<<< HEAD\nb\n=======\nc\n>>>>>>> other\n", true,
+		},
+	}
+	for _, tt := range tests {
+		lines, conflict := merge3(splitLines([]byte(tt.base)), splitLines([]byte(tt.ours)), splitLines([]byte(tt.theirs)), "HEAD", "other")
+		if got := strings.Join(lines, ""); got != tt.want || conflict != tt.conflict {
+			t.Errorf("%s: merge3 gave %q, %v; want %q, %v", tt.name, got, conflict, tt.want, tt.conflict)
+		}
+	}
+}
+
+func TestMergeTrees(t *testing.T) {
+	testRepo(t)
+	blob := func(data string) string {
+		oid, err := writeObject([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return oid
+	}
+	base := map[string]string{
+		"same": blob("s\n"), "ours": blob("o\n"), "theirs": blob("t\n"),
+		"merge": blob("1\n2\n3\n"), "clash": blob("c\n"),
+		"gone": blob("g\n"), "modified-deleted": blob("m\n"),
+	}
+	ours := map[string]string{
+		"same": base["same"], "ours": blob("O\n"), "theirs": base["theirs"],
+		"merge": blob("one\n2\n3\n"), "clash": blob("ours\n"),
+		"modified-deleted": blob("M\n"), "added": blob("new\n"),
+	}
+	theirs := map[string]string{
+		"same": base["same"], "ours": base["ours"], "theirs": blob("T\n"),
+		"merge": blob("1\n2\nthree\n"), "clash": blob("theirs\n"),
+		"gone": base["gone"],
+	}
+	tree, conflicts := mergeTrees(base, ours, theirs, "HEAD", "other")
+
+	want := map[string]string{
+		"same": base["same"], "ours": ours["ours"], "theirs": theirs["theirs"],
+		"merge": blob("one\n2\nthree\n"), "clash": ours["clash"],
+		"modified-deleted": ours["modified-deleted"], "added": ours["added"],
+	}
+	if !reflect.DeepEqual(tree, want) {
+		t.Errorf("merged tree is %v, want %v", tree, want)
+	}
+	if got := sortedKeys(conflicts); !reflect.DeepEqual(got, []string{"clash", "modified-deleted"}) {
+		t.Errorf("conflicts in %q, want clash and modified-deleted", got)
+	}
+	if got := string(conflicts["modified-deleted"]); got != "M\n" {
+		t.Errorf("modified/deleted conflict keeps %q, want the modified version", got)
+	}
+}
@@ -81,19 +81,42 @@ func IsTracked(file string) bool {
 }
 
 func PurgeUnreferencedObjects() {
-	log, err := ioutil.ReadFile(logFile)
+	commits, err := readCommits()
 	if err != nil {
 		fmt.Println("Error reading log")
 		return
 	}
-	entries := strings.Split(string(log), "---\n")
 	referenced := make(map[string]bool)
-	for _, entry := range entries[:len(entries)-1] {
-		lines := strings.Split(entry, "\n")
-		for _, line := range lines {
-			parts := strings.Split(line, " ")
-			if len(parts) == 2 {
-				referenced[parts[1]] = true
+	for _, c := range commits {
+		for _, oid := range c.Files {
+			referenced[oid] = true
+		}
+	}
+	for _, name := range tagNames() {
+		referenced[readRef("refs/tags/"+name)] = true
+	}
+	// Staged blobs are not in any commit yet.
+	for _, oid := range readIndex() {
+		if oid != nullID {
+			referenced[oid] = true
+		}
+	}
+	// Keep the trees of the commits the reflogs still name, and for
+	// stashes those of the index and untracked-files commits they carry.
+	byID := map[string]commitEntry{}
+	for _, c := range commits {
+		byID[c.ID] = c
+	}
+	for _, ref := range reflogRefs() {
+		for _, e := range readReflog(ref) {
+			ids := []string{e.Old, e.New}
+			if c, ok := byID[e.New]; ok && ref == stashRef && len(c.Parents) > 1 {
+				ids = append(ids, c.Parents[1:]...)
+			}
+			for _, id := range ids {
+				for _, oid := range byID[id].Files {
+					referenced[oid] = true
+				}
 			}
 		}
 	}
@@ -2,277 +2,154 @@ package regit
 
 import (
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
+	"sort"
 	"strings"
 )
 
-func Log() {
-	log, _ := ioutil.ReadFile(logFile)
-	entries := strings.Split(string(log), "---\n")
-	for _, entry := range entries {
-		if strings.TrimSpace(entry) != "" {
-			fmt.Println(entry)
-		}
+// commitAt returns the commitIdx'th commit of the log, oldest first.
+func commitAt(commitIdx int) (commitEntry, bool) {
+	commits, err := readCommits()
+	if err != nil || commitIdx < 0 || commitIdx >= len(commits) {
+		return commitEntry{}, false
 	}
+	return commits[commitIdx], true
 }
 
 func ListCommits() {
-	log, err := ioutil.ReadFile(logFile)
+	commits, err := readCommits()
 	if err != nil {
 		fmt.Println("Error reading log")
 		return
 	}
-	entries := strings.Split(string(log), "---\n")
-	for _, entry := range entries {
-		lines := strings.Split(entry, "\n")
-		for _, line := range lines {
-			if strings.HasPrefix(line, "commit ") || strings.HasPrefix(line, "Date:") {
-				fmt.Println(line)
-			}
-			if strings.HasPrefix(line, "commit ") {
-				fmt.Println("-----")
-			}
-		}
+	for _, c := range commits {
+		fmt.Println("commit " + c.ID)
+		fmt.Println("-----")
+		fmt.Println("Date: " + c.Date)
 	}
 }
 
+// FileHistory lists the commits that added, changed or deleted file,
+// giving the blob it has after each; a deletion shows the null ID.
 func FileHistory(file string) {
-	log, err := ioutil.ReadFile(logFile)
+	commits, err := readCommits()
 	if err != nil {
 		fmt.Println("Error reading log")
 		return
 	}
-	entries := strings.Split(string(log), "---\n")
-	for _, entry := range entries {
-		if strings.Contains(entry, file+" ") {
-			lines := strings.Split(entry, "\n")
-			for _, line := range lines {
-				if strings.HasPrefix(line, "commit ") || strings.HasPrefix(line, "Date:") || strings.HasPrefix(line, file+" ") {
-					fmt.Println(line)
-				}
+	byID := map[string]commitEntry{}
+	for _, c := range commits {
+		byID[c.ID] = c
+	}
+	for _, c := range commits {
+		oid, ok := c.Files[file]
+		var before string
+		if len(c.Parents) > 0 {
+			before = byID[c.Parents[0]].Files[file]
+		}
+		if !ok {
+			if before == "" {
+				continue
 			}
-			fmt.Println("-----")
+			oid = nullID
+		} else if oid == before {
+			continue
 		}
+		fmt.Println("commit " + c.ID)
+		fmt.Println("Date: " + c.Date)
+		fmt.Println(file + " " + oid)
+		fmt.Println("-----")
 	}
 }
 
 func CommitCount() int {
-	log, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		return 0
-	}
-	entries := strings.Split(string(log), "---\n")
-	return len(entries) - 1
-}
-
-func FindCommitByMessage(substring string) []int {
-	log, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		fmt.Println("Error reading log")
-		return nil
-	}
-	entries := strings.Split(string(log), "---\n")
-	var indices []int
-	for i, entry := range entries[:len(entries)-1] {
-		if strings.Contains(entry, substring) {
-			indices = append(indices, i)
-		}
-	}
-	return indices
+	commits, _ := readCommits()
+	return len(commits)
 }
 
 func FindFileOids(file string) []string {
-	log, err := ioutil.ReadFile(logFile)
+	commits, err := readCommits()
 	if err != nil {
 		fmt.Println("Error reading log")
 		return nil
 	}
-	entries := strings.Split(string(log), "---\n")
 	var oids []string
-	for _, entry := range entries[:len(entries)-1] {
-		lines := strings.Split(entry, "\n")
-		for _, line := range lines {
-			parts := strings.Split(line, " ")
-			if len(parts) == 2 && parts[0] == file {
-				oids = append(oids, parts[1])
-			}
+	for _, c := range commits {
+		if oid, ok := c.Files[file]; ok {
+			oids = append(oids, oid)
 		}
 	}
 	return oids
 }
 
 func ListAllTrackedFiles() []string {
-	log, err := ioutil.ReadFile(logFile)
+	commits, err := readCommits()
 	if err != nil {
 		return nil
 	}
-	entries := strings.Split(string(log), "---\n")
 	filesSet := make(map[string]struct{})
-	for _, entry := range entries[:len(entries)-1] {
-		lines := strings.Split(entry, "\n")
-		for _, line := range lines {
-			parts := strings.Split(line, " ")
-			if len(parts) == 2 {
-				filesSet[parts[0]] = struct{}{}
-			}
+	for _, c := range commits {
+		for f := range c.Files {
+			filesSet[f] = struct{}{}
 		}
 	}
 	files := make([]string, 0, len(filesSet))
 	for f := range filesSet {
 		files = append(files, f)
 	}
+	sort.Strings(files)
 	return files
 }
 
 func GetCommitMessage(commitIdx int) string {
-	log, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		return ""
-	}
-	entries := strings.Split(string(log), "---\n")
-	if commitIdx < 0 || commitIdx >= len(entries)-1 {
+	c, ok := commitAt(commitIdx)
+	if !ok {
 		return ""
 	}
-	entry := entries[commitIdx]
-	lines := strings.Split(entry, "\n")
-	for i, line := range lines {
-		if strings.HasPrefix(line, "commit ") {
-			for j := i + 1; j < len(lines); j++ {
-				if strings.HasPrefix(lines[j], "Date:") && j+1 < len(lines) {
-					return lines[j+1]
-				}
-			}
-		}
-	}
-	return ""
+	return subject(c.Message)
 }
 
 func GetCommitDate(commitIdx int) string {
-	log, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		return ""
-	}
-	entries := strings.Split(string(log), "---\n")
-	if commitIdx < 0 || commitIdx >= len(entries)-1 {
+	c, ok := commitAt(commitIdx)
+	if !ok {
 		return ""
 	}
-	entry := entries[commitIdx]
-	lines := strings.Split(entry, "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "Date:") {
-			return strings.TrimPrefix(line, "Date: ")
-		}
-	}
-	return ""
+	return c.Date
 }
 
 func GetCommitOidForFile(file string, commitIdx int) string {
-	log, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		return ""
-	}
-	entries := strings.Split(string(log), "---\n")
-	if commitIdx < 0 || commitIdx >= len(entries)-1 {
+	c, ok := commitAt(commitIdx)
+	if !ok {
 		return ""
 	}
-	entry := entries[commitIdx]
-	lines := strings.Split(entry, "\n")
-	for _, line := range lines {
-		parts := strings.Split(line, " ")
-		if len(parts) == 2 && parts[0] == file {
-			return parts[1]
-		}
-	}
-	return ""
-}
-
-func Blame(file string) {
-	log, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		fmt.Println("Error reading log")
-		return
-	}
-	entries := strings.Split(string(log), "---\n")
-	lineCommit := make(map[int]int)
-	var fileLines []string
-	for idx, entry := range entries[:len(entries)-1] {
-		lines := strings.Split(entry, "\n")
-		var oid string
-		for _, line := range lines {
-			parts := strings.Split(line, " ")
-			if len(parts) == 2 && parts[0] == file {
-				oid = parts[1]
-				break
-			}
-		}
-		if oid != "" {
-			objPath := filepath.Join(objectsDir, oid)
-			data, err := ioutil.ReadFile(objPath)
-			if err == nil {
-				fileLines = strings.Split(string(data), "\n")
-				for i := range fileLines {
-					lineCommit[i] = idx
-				}
-			}
-		}
-	}
-	for i, line := range fileLines {
-		fmt.Printf("%d %s | %s\n", lineCommit[i], GetCommitMessage(lineCommit[i]), line)
-	}
+	return c.Files[file]
 }
 
 func ShowCommitFiles(commitIdx int) {
-	log, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		fmt.Println("Error reading log")
-		return
-	}
-	entries := strings.Split(string(log), "---\n")
-	if commitIdx < 0 || commitIdx >= len(entries)-1 {
+	c, ok := commitAt(commitIdx)
+	if !ok {
 		fmt.Println("Invalid commit index")
 		return
 	}
-	entry := entries[commitIdx]
-	lines := strings.Split(entry, "\n")
 	fmt.Printf("Files in commit %d:\n", commitIdx)
-	for _, line := range lines {
-		parts := strings.Split(line, " ")
-		if len(parts) == 2 {
-			fmt.Println(parts[0])
-		}
+	for _, f := range sortedKeys(c.Files) {
+		fmt.Println(f)
 	}
 }
 
 func ShowCommitDiff(file string, idxA, idxB int) {
-	log, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		fmt.Println("Error reading log")
-		return
-	}
-	entries := strings.Split(string(log), "---\n")
-	if idxA < 0 || idxA >= len(entries)-1 || idxB < 0 || idxB >= len(entries)-1 {
+	a, okA := commitAt(idxA)
+	b, okB := commitAt(idxB)
+	if !okA || !okB {
 		fmt.Println("Invalid commit index")
 		return
 	}
-	getOid := func(entry string) string {
-		lines := strings.Split(entry, "\n")
-		for _, line := range lines {
-			parts := strings.Split(line, " ")
-			if len(parts) == 2 && parts[0] == file {
-				return parts[1]
-			}
-		}
-		return ""
-	}
-	oidA := getOid(entries[idxA])
-	oidB := getOid(entries[idxB])
+	oidA, oidB := a.Files[file], b.Files[file]
 	if oidA == "" || oidB == "" {
 		fmt.Println("File not found in one of the commits")
 		return
 	}
-	dataA, errA := ioutil.ReadFile(filepath.Join(objectsDir, oidA))
-	dataB, errB := ioutil.ReadFile(filepath.Join(objectsDir, oidB))
+	dataA, errA := readObject(oidA)
+	dataB, errB := readObject(oidB)
 	if errA != nil || errB != nil {
 		fmt.Println("Error reading file objects")
 		return
@@ -283,3 +160,23 @@ func ShowCommitDiff(file string, idxA, idxB int) {
 	fmt.Println("--- commit", idxB)
 	fmt.Println(string(dataB))
 }
+
+func HeadCommit() string {
+	c, ok := loadCommit(headCommitID())
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace("commit " + c.ID + "\n" + commitBody(&c))
+}
+
+func CommitFiles(commitIdx int) {
+	c, ok := commitAt(commitIdx)
+	if !ok {
+		fmt.Println("Invalid commit index")
+		return
+	}
+	fmt.Printf("Files in commit %d:\n", commitIdx)
+	for _, f := range sortedKeys(c.Files) {
+		fmt.Println(f)
+	}
+}
@@ -0,0 +1,88 @@
+package regit
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLegacyQueries(t *testing.T) {
+	testRepo(t)
+	t.Setenv("REGIT_AUTHOR_DATE", "2024-01-02T03:04:05Z")
+	first := commitFiles(t, "first", map[string]string{"f": "a\n"})
+	t.Setenv("REGIT_AUTHOR_DATE", "2024-02-03T04:05:06Z")
+	second := commitFiles(t, "second", map[string]string{"f": "b\n", "g": "c\n"})
+	c1, _ := loadCommit(first)
+	c2, _ := loadCommit(second)
+
+	tests := []struct {
+		idx           int
+		message, date string
+		oidF          string
+	}{
+		{0, "first", "2024-01-02T03:04:05Z", c1.Files["f"]},
+		{1, "second", "2024-02-03T04:05:06Z", c2.Files["f"]},
+		{2, "", "", ""},
+		{-1, "", "", ""},
+	}
+	for _, tt := range tests {
+		if got := GetCommitMessage(tt.idx); got != tt.message {
+			t.Errorf("GetCommitMessage(%d) = %q, want %q", tt.idx, got, tt.message)
+		}
+		if got := GetCommitDate(tt.idx); got != tt.date {
+			t.Errorf("GetCommitDate(%d) = %q, want %q", tt.idx, got, tt.date)
+		}
+		if got := GetCommitOidForFile("f", tt.idx); got != tt.oidF {
+			t.Errorf("GetCommitOidForFile(f, %d) = %q, want %q", tt.idx, got, tt.oidF)
+		}
+	}
+	if got, want := ListAllTrackedFiles(), []string{"f", "g"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListAllTrackedFiles() = %q, want %q", got, want)
+	}
+	if got, want := FindFileOids("f"), []string{c1.Files["f"], c2.Files["f"]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindFileOids(f) = %q, want %q", got, want)
+	}
+	if got := CommitCount(); got != 2 {
+		t.Errorf("CommitCount() = %d, want 2", got)
+	}
+	out := captureOutput(t, func() { ShowCommitFiles(1) })
+	if want := "Files in commit 1:\nf\ng\n"; out != want {
+		t.Errorf("ShowCommitFiles(1) printed %q, want %q", out, want)
+	}
+	out = captureOutput(t, func() { FileHistory("g") })
+	if !strings.Contains(out, "commit "+second) || strings.Contains(out, "commit "+first) {
+		t.Errorf("FileHistory(g) printed\n%s", out)
+	}
+}
+
+func TestFileHistory(t *testing.T) {
+	testRepo(t)
+	added := commitFiles(t, "add f", map[string]string{"f": "1\n"})
+	commitFiles(t, "add g", map[string]string{"g": "1\n"})
+	changed := commitFiles(t, "change f", map[string]string{"f": "2\n"})
+	commitFiles(t, "change g", map[string]string{"g": "2\n"})
+	os.Remove("f")
+	captureOutput(t, func() {
+		stageTrackedChanges()
+		Commit("delete f", nil, CommitOptions{})
+	})
+	deleted := headCommitID()
+	commitFiles(t, "change g again", map[string]string{"g": "3\n"})
+
+	out := captureOutput(t, func() { FileHistory("f") })
+	var got []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "commit ") || strings.HasPrefix(line, "f ") {
+			got = append(got, line)
+		}
+	}
+	want := []string{
+		"commit " + added, "f " + hashObject([]byte("1\n")),
+		"commit " + changed, "f " + hashObject([]byte("2\n")),
+		"commit " + deleted, "f " + nullID,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FileHistory(f) printed\n%s\nwant %q", out, want)
+	}
+}
@@ -0,0 +1,271 @@
+package regit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// RebaseOptions select an interactive rebase. Interactive lets the user edit
+// the todo list first; Autosquash moves "fixup! <subject>" and "squash!
+// <subject>" commits after the commit they refer to; Exec, if set, is run
+// after every commit.
+type RebaseOptions struct {
+	Interactive bool
+	Autosquash  bool
+	Exec        string
+}
+
+// Rebase replays the commits of the current branch that are not in upstream
+// on top of onto (upstream itself when onto is ""), then moves the branch to
+// the result. The replay runs on the sequencer, so conflicts can be resolved
+// and the rebase continued, skipped or aborted from a later invocation.
+func Rebase(upstream, onto string, opts RebaseOptions) {
+	if sequencerInProgress() {
+		fmt.Println("A cherry-pick, revert or rebase is already in progress; use --continue, --skip or --abort")
+		return
+	}
+	head := headCommitID()
+	if head == "" {
+		fmt.Println("No commits yet")
+		return
+	}
+	upstreamID, err := resolveRevision(upstream)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ontoID, ontoName := upstreamID, upstream
+	if onto != "" {
+		if ontoID, err = resolveRevision(onto); err != nil {
+			fmt.Println(err)
+			return
+		}
+		ontoName = onto
+	}
+	if files := localChanges(); len(files) > 0 {
+		fmt.Println("Cannot rebase: you have local changes in")
+		for _, f := range files {
+			fmt.Println(" ", f)
+		}
+		return
+	}
+
+	commits, _ := readCommits()
+	if onto == "" && !opts.Interactive && reachable(commits, []string{head})[upstreamID] {
+		fmt.Printf("Current branch %s is up to date.\n", currentBranch())
+		return
+	}
+	var todo []string
+	ids := revList([]string{head}, []string{upstreamID})
+	for i := len(ids) - 1; i >= 0; i-- {
+		if c, ok := loadCommit(ids[i]); ok && len(c.Parents) <= 1 {
+			todo = append(todo, "pick "+ids[i])
+		}
+	}
+	if opts.Autosquash {
+		todo = autosquash(todo)
+	}
+	if opts.Exec != "" {
+		var withExec []string
+		for _, step := range todo {
+			withExec = append(withExec, step, "exec "+opts.Exec)
+		}
+		todo = withExec
+	}
+
+	os.MkdirAll(sequencerDir, 0755)
+	if opts.Interactive {
+		edited, err := editTodo(todo, ontoID)
+		if err != nil {
+			fmt.Println(err)
+			os.RemoveAll(sequencerDir)
+			return
+		}
+		if len(edited) == 0 {
+			fmt.Println("Nothing to do")
+			os.RemoveAll(sequencerDir)
+			return
+		}
+		todo = edited
+	}
+	writeSeqLines("todo", todo)
+	writeSeqLines("opts", nil)
+	ioutil.WriteFile(seqPath("head"), []byte(head+"\n"), 0644)
+	ioutil.WriteFile(seqPath("onto"), []byte(ontoID+"\n"), 0644)
+	if ref := headRef(); ref != "" {
+		ioutil.WriteFile(seqPath("head-name"), []byte(ref+"\n"), 0644)
+	}
+
+	// Detach HEAD at the new base and replay from there.
+	if err := setHead(ontoID, "rebase (start): checkout "+ontoName); err != nil {
+		fmt.Println("Error updating HEAD")
+		return
+	}
+	if err := checkoutTree(commitTree(head), commitTree(ontoID)); err != nil {
+		fmt.Println("Error updating working tree:", err)
+		return
+	}
+	writeIndex(map[string]string{})
+	fmt.Printf("Rebasing onto %s\n", shortID(ontoID))
+	runSequencer()
+}
+
+func rebaseInProgress() bool {
+	_, err := os.Stat(seqPath("onto"))
+	return err == nil
+}
+
+// finishRebase moves the rebased branch to where HEAD ended up and attaches
+// HEAD to it again.
+func finishRebase() {
+	head := headCommitID()
+	onto := strings.Join(readSeqLines("onto"), "")
+	headName := readSeqLines("head-name")
+	if len(headName) == 0 {
+		fmt.Println("Successfully rebased detached HEAD")
+		return
+	}
+	updateRef(headName[0], head, "rebase (finish): "+headName[0]+" onto "+onto)
+	setHead(headName[0], "rebase (finish): returning to "+headName[0])
+	fmt.Println("Successfully rebased and updated", headName[0])
+}
+
+var todoActions = map[string]string{
+	"p": "pick", "pick": "pick",
+	"r": "reword", "reword": "reword",
+	"e": "edit", "edit": "edit",
+	"s": "squash", "squash": "squash",
+	"f": "fixup", "fixup": "fixup",
+	"d": "drop", "drop": "drop",
+	"x": "exec", "exec": "exec",
+}
+
+const todoHelp = `
+# Rebase onto %s
+#
+# Commands:
+# p, pick <commit>   = use commit
+# r, reword <commit> = use commit, but edit the commit message
+# e, edit <commit>   = use commit, but stop for amending
+# s, squash <commit> = use commit, but meld into previous commit
+# f, fixup <commit>  = like "squash", but discard this commit's message
+# d, drop <commit>   = remove commit
+# x, exec <command>  = run command (the rest of the line) using shell
+#
+# Lines can be reordered; they are executed from top to bottom.
+# Removing every line aborts the rebase.
+`
+
+// editTodo shows the todo list in the editor and parses the result back
+// into sequencer steps.
+func editTodo(todo []string, onto string) ([]string, error) {
+	var b strings.Builder
+	for _, step := range todo {
+		action, arg, _ := strings.Cut(step, " ")
+		if action == "exec" {
+			b.WriteString(step + "\n")
+		} else {
+			fmt.Fprintf(&b, "%s %s %s\n", action, shortID(arg), commitSubject(arg))
+		}
+	}
+	fmt.Fprintf(&b, todoHelp, shortID(onto))
+	path := seqPath("todo")
+	if err := ioutil.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return nil, err
+	}
+	if err := launchEditor(path); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var steps []string
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		word, rest, _ := strings.Cut(line, " ")
+		action, ok := todoActions[word]
+		if !ok {
+			return nil, fmt.Errorf("todo line %d: unknown command %q", n+1, word)
+		}
+		rest = strings.TrimSpace(rest)
+		if action == "exec" {
+			if rest == "" {
+				return nil, fmt.Errorf("todo line %d: exec needs a command", n+1)
+			}
+			steps = append(steps, "exec "+rest)
+			continue
+		}
+		rev, _, _ := strings.Cut(rest, " ")
+		id, err := resolveRevision(rev)
+		if err != nil {
+			return nil, fmt.Errorf("todo line %d: %v", n+1, err)
+		}
+		if (action == "squash" || action == "fixup") && len(steps) == 0 {
+			return nil, fmt.Errorf("todo line %d: cannot %s without a previous commit", n+1, action)
+		}
+		steps = append(steps, action+" "+id)
+	}
+	return steps, nil
+}
+
+// isIDPrefix reports whether s could abbreviate a commit ID: at least four
+// hex digits.
+func isIDPrefix(s string) bool {
+	if len(s) < 4 || len(s) > 40 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// autosquash moves each "fixup! <subject>" or "squash! <subject>" commit
+// right after the commit it names (by subject or ID prefix) and turns it
+// into a fixup or squash step.
+func autosquash(todo []string) []string {
+	type step struct {
+		line  string
+		fixes []string
+	}
+	var steps []*step
+	for _, line := range todo {
+		_, id, _ := strings.Cut(line, " ")
+		subj := commitSubject(id)
+		action, target := "", ""
+		if strings.HasPrefix(subj, "fixup! ") {
+			action, target = "fixup", strings.TrimPrefix(subj, "fixup! ")
+		} else if strings.HasPrefix(subj, "squash! ") {
+			action, target = "squash", strings.TrimPrefix(subj, "squash! ")
+		}
+		placed := false
+		if action != "" && target != "" {
+			for _, s := range steps {
+				_, sid, _ := strings.Cut(s.line, " ")
+				ssubj := commitSubject(sid)
+				if strings.HasPrefix(ssubj, target) || isIDPrefix(target) && strings.HasPrefix(sid, target) {
+					s.fixes = append(s.fixes, action+" "+id)
+					placed = true
+					break
+				}
+			}
+		}
+		if !placed {
+			steps = append(steps, &step{line: line})
+		}
+	}
+	var out []string
+	for _, s := range steps {
+		out = append(out, s.line)
+		out = append(out, s.fixes...)
+	}
+	return out
+}
@@ -0,0 +1,462 @@
+package regit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRebaseDropsPatchesAlreadyUpstream(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "base", map[string]string{"f": "a\n"})
+	captureOutput(t, func() { CreateBranch("side") })
+	commitFiles(t, "change f", map[string]string{"f": "b\n"})
+	commitFiles(t, "add g", map[string]string{"g": "x\n"})
+	captureOutput(t, func() { CheckoutBranch("side") })
+	commitFiles(t, "same change to f", map[string]string{"f": "b\n"})
+	commitFiles(t, "add h", map[string]string{"h": "y\n"})
+
+	out := captureOutput(t, func() { Rebase("master", "", RebaseOptions{}) })
+	if strings.Contains(out, "empty") {
+		t.Errorf("rebase stopped on an empty step:\n%s", out)
+	}
+	if sequencerInProgress() {
+		t.Fatalf("rebase did not finish:\n%s", out)
+	}
+	if ref := headRef(); ref != "refs/heads/side" {
+		t.Errorf("HEAD is %q, want refs/heads/side", ref)
+	}
+	want := []string{"add h", "add g", "change f", "base"}
+	if got := firstParentSubjects(headCommitID()); !reflect.DeepEqual(got, want) {
+		t.Errorf("history is %q, want %q", got, want)
+	}
+	for f, data := range map[string]string{"f": "b\n", "g": "x\n", "h": "y\n"} {
+		if got, _ := ioutil.ReadFile(f); string(got) != data {
+			t.Errorf("%s is %q, want %q", f, got, data)
+		}
+	}
+}
+
+func TestIsIDPrefix(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"", false},
+		{"abc", false},
+		{"abcd", true},
+		{"0123456789abcdef0123456789abcdef01234567", true},
+		{"0123456789abcdef0123456789abcdef012345678", false},
+		{"ABCD", false},
+		{"abcg", false},
+		{"add a", false},
+	}
+	for _, tt := range tests {
+		if got := isIDPrefix(tt.s); got != tt.want {
+			t.Errorf("isIDPrefix(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestAutosquash(t *testing.T) {
+	// In subjects and the wanted steps, {N} stands for the short ID of the
+	// Nth commit and {N:K} for its first K characters.
+	tests := []struct {
+		name     string
+		subjects []string
+		want     []string
+	}{
+		{
+			name:     "by subject",
+			subjects: []string{"Add a", "Add b", "fixup! Add a"},
+			want:     []string{"pick Add a", "fixup fixup! Add a", "pick Add b"},
+		},
+		{
+			name:     "by subject prefix",
+			subjects: []string{"Add a file", "Add b", "squash! Add a"},
+			want:     []string{"pick Add a file", "squash squash! Add a", "pick Add b"},
+		},
+		{
+			name:     "by ID",
+			subjects: []string{"Add a", "Add b", "fixup! {0}"},
+			want:     []string{"pick Add a", "fixup fixup! {0}", "pick Add b"},
+		},
+		{
+			name:     "several fixes keep their order",
+			subjects: []string{"Add a", "squash! Add a", "Add b", "fixup! Add a"},
+			want:     []string{"pick Add a", "squash squash! Add a", "fixup fixup! Add a", "pick Add b"},
+		},
+		{
+			name:     "too short to be an ID",
+			subjects: []string{"Add a", "fixup! {0:3}"},
+			want:     []string{"pick Add a", "pick fixup! {0:3}"},
+		},
+		{
+			name:     "empty target",
+			subjects: []string{"Add a", "fixup! "},
+			want:     []string{"pick Add a", "pick fixup! "},
+		},
+		{
+			name:     "unknown target",
+			subjects: []string{"Add a", "fixup! Add c"},
+			want:     []string{"pick Add a", "pick fixup! Add c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			var pairs []string
+			var todo []string
+			for i, subj := range tt.subjects {
+				c := commitEntry{Message: strings.NewReplacer(pairs...).Replace(subj), Files: map[string]string{}}
+				if err := writeCommit(&c); err != nil {
+					t.Fatal(err)
+				}
+				todo = append(todo, "pick "+c.ID)
+				pairs = append(pairs, fmt.Sprintf("{%d}", i), shortID(c.ID), fmt.Sprintf("{%d:3}", i), c.ID[:3])
+			}
+			var got []string
+			for _, line := range autosquash(todo) {
+				action, id, _ := strings.Cut(line, " ")
+				got = append(got, action+" "+commitSubject(id))
+			}
+			want := make([]string, len(tt.want))
+			for i, w := range tt.want {
+				want[i] = strings.NewReplacer(pairs...).Replace(w)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("autosquash gave %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRebase(t *testing.T) {
+	// History: base (f=1); master adds "main" (g=1); side adds "side one"
+	// (h=1) and "side two" (h=2); topic, off side one, adds "topic" (t=1).
+	tests := []struct {
+		name, branch   string
+		upstream, onto string
+		wantLog        []string
+		wantFiles      map[string]string
+		wantUpToDate   bool
+	}{
+		{
+			name:      "onto upstream",
+			branch:    "side",
+			upstream:  "master",
+			wantLog:   []string{"side two", "side one", "main", "base"},
+			wantFiles: map[string]string{"f": "1\n", "g": "1\n", "h": "2\n"},
+		},
+		{
+			name:      "onto another base",
+			branch:    "topic",
+			upstream:  "side~1",
+			onto:      "master",
+			wantLog:   []string{"topic", "main", "base"},
+			wantFiles: map[string]string{"g": "1\n", "t": "1\n", "h": ""},
+		},
+		{
+			name:         "already up to date",
+			branch:       "master",
+			upstream:     "master~1",
+			wantLog:      []string{"main", "base"},
+			wantUpToDate: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			commitFiles(t, "base", map[string]string{"f": "1\n"})
+			captureOutput(t, func() { CreateBranch("side") })
+			commitFiles(t, "main", map[string]string{"g": "1\n"})
+			captureOutput(t, func() { CheckoutBranch("side") })
+			commitFiles(t, "side one", map[string]string{"h": "1\n"})
+			captureOutput(t, func() { CreateBranch("topic") })
+			commitFiles(t, "side two", map[string]string{"h": "2\n"})
+			captureOutput(t, func() { CheckoutBranch("topic") })
+			commitFiles(t, "topic", map[string]string{"t": "1\n"})
+			captureOutput(t, func() { CheckoutBranch(tt.branch) })
+			before := headCommitID()
+
+			out := captureOutput(t, func() { Rebase(tt.upstream, tt.onto, RebaseOptions{}) })
+			if sequencerInProgress() {
+				t.Fatalf("rebase did not finish:\n%s", out)
+			}
+			if got := strings.Contains(out, "is up to date"); got != tt.wantUpToDate {
+				t.Errorf("up to date = %v, want %v:\n%s", got, tt.wantUpToDate, out)
+			}
+			if ref := headRef(); ref != "refs/heads/"+tt.branch {
+				t.Errorf("HEAD is %q, want it back on %s", ref, tt.branch)
+			}
+			if got := firstParentSubjects(headCommitID()); !reflect.DeepEqual(got, tt.wantLog) {
+				t.Errorf("history is %q, want %q:\n%s", got, tt.wantLog, out)
+			}
+			for f, want := range tt.wantFiles {
+				if got, _ := ioutil.ReadFile(f); string(got) != want {
+					t.Errorf("%s is %q, want %q", f, got, want)
+				}
+			}
+			if !tt.wantUpToDate {
+				if id, _ := resolveRevision(tt.branch + "@{1}"); id != before {
+					t.Errorf("%s@{1} is %s, want the branch before the rebase", tt.branch, commitSubject(id))
+				}
+			}
+		})
+	}
+}
+
+func TestRebaseConflict(t *testing.T) {
+	tests := []struct {
+		name    string
+		resolve func(t *testing.T)
+		wantLog []string
+		wantF   string
+	}{
+		{
+			name: "continue",
+			resolve: func(t *testing.T) {
+				writeFiles(t, map[string]string{"f": "both\n"})
+				captureOutput(t, SequencerContinue)
+			},
+			wantLog: []string{"side two", "side f", "main f", "base"},
+			wantF:   "both\n",
+		},
+		{
+			name:    "skip",
+			resolve: func(t *testing.T) { captureOutput(t, SequencerSkip) },
+			wantLog: []string{"side two", "main f", "base"},
+			wantF:   "main\n",
+		},
+		{
+			name:    "abort",
+			resolve: func(t *testing.T) { captureOutput(t, SequencerAbort) },
+			wantLog: []string{"side two", "side f", "base"},
+			wantF:   "side\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			commitFiles(t, "base", map[string]string{"f": "1\n"})
+			captureOutput(t, func() { CreateBranch("side") })
+			commitFiles(t, "main f", map[string]string{"f": "main\n"})
+			captureOutput(t, func() { CheckoutBranch("side") })
+			commitFiles(t, "side f", map[string]string{"f": "side\n"})
+			commitFiles(t, "side two", map[string]string{"g": "1\n"})
+
+			out := captureOutput(t, func() { Rebase("master", "", RebaseOptions{}) })
+			if !sequencerInProgress() || !strings.Contains(out, "CONFLICT: f") {
+				t.Fatalf("rebase did not stop on the conflict:\n%s", out)
+			}
+			tt.resolve(t)
+			if sequencerInProgress() {
+				t.Fatal("rebase is still in progress")
+			}
+			if ref := headRef(); ref != "refs/heads/side" {
+				t.Errorf("HEAD is %q, want it back on side", ref)
+			}
+			if got := firstParentSubjects(headCommitID()); !reflect.DeepEqual(got, tt.wantLog) {
+				t.Errorf("history is %q, want %q", got, tt.wantLog)
+			}
+			if got, _ := ioutil.ReadFile("f"); string(got) != tt.wantF {
+				t.Errorf("f is %q, want %q", got, tt.wantF)
+			}
+		})
+	}
+}
+
+// todoEditor makes the editor replace the rebase todo list with todo and
+// prefix the first line of any commit message with "edited: ".
+func todoEditor(t *testing.T, todo string) {
+	t.Helper()
+	dir := t.TempDir()
+	todoFile := filepath.Join(dir, "todo")
+	script := filepath.Join(dir, "editor")
+	if err := ioutil.WriteFile(todoFile, []byte(todo), 0644); err != nil {
+		t.Fatal(err)
+	}
+	body := "#!/bin/sh\ncase \"$1\" in\n*todo) cp " + todoFile + " \"$1\" ;;\n*) sed -i '1s/^/edited: /' \"$1\" ;;\nesac\n"
+	if err := ioutil.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("REGIT_EDITOR", script)
+}
+
+func TestInteractiveRebase(t *testing.T) {
+	// master: base, then a, b, c and d, each adding a file of that name.
+	tests := []struct {
+		name      string
+		todo      string
+		wantLog   []string
+		wantFiles []string
+		wantOut   string
+	}{
+		{
+			name:      "reorder and drop",
+			todo:      "pick master~2\npick master~3\ndrop master~1\npick master\n",
+			wantLog:   []string{"d", "a", "b", "base"},
+			wantFiles: []string{"a", "b", "d"},
+		},
+		{
+			name:      "removed lines are dropped",
+			todo:      "pick master~3\n# pick master~2\npick master\n",
+			wantLog:   []string{"d", "a", "base"},
+			wantFiles: []string{"a", "d"},
+		},
+		{
+			name:      "squash edits the combined message",
+			todo:      "pick master~3\nsquash master~2\npick master~1\npick master\n",
+			wantLog:   []string{"d", "c", "edited: a", "base"},
+			wantFiles: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:      "fixup keeps the first message",
+			todo:      "p master~3\nf master~2\nf master~1\np master\n",
+			wantLog:   []string{"d", "a", "base"},
+			wantFiles: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:      "reword",
+			todo:      "pick master~3\nr master~2\npick master~1\npick master\n",
+			wantLog:   []string{"d", "c", "edited: b", "a", "base"},
+			wantFiles: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:      "exec",
+			todo:      "pick master~3\nexec echo ran >ran\npick master~2\npick master~1\npick master\n",
+			wantLog:   []string{"d", "c", "b", "a", "base"},
+			wantFiles: []string{"a", "b", "c", "d", "ran"},
+			wantOut:   "Executing: echo ran >ran",
+		},
+		{
+			name:    "empty todo",
+			todo:    "# nothing\n",
+			wantLog: []string{"d", "c", "b", "a", "base"},
+			wantOut: "Nothing to do",
+		},
+		{
+			name:    "unknown command",
+			todo:    "pick master~3\npock master~2\n",
+			wantLog: []string{"d", "c", "b", "a", "base"},
+			wantOut: `todo line 2: unknown command "pock"`,
+		},
+		{
+			name:    "squash first",
+			todo:    "squash master~3\npick master~2\n",
+			wantLog: []string{"d", "c", "b", "a", "base"},
+			wantOut: "todo line 1: cannot squash without a previous commit",
+		},
+		{
+			name:    "exec without a command",
+			todo:    "pick master~3\nx\n",
+			wantLog: []string{"d", "c", "b", "a", "base"},
+			wantOut: "todo line 2: exec needs a command",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			commitFiles(t, "base", map[string]string{"base": "x\n"})
+			for _, name := range []string{"a", "b", "c", "d"} {
+				commitFiles(t, name, map[string]string{name: name + "\n"})
+			}
+			todoEditor(t, tt.todo)
+
+			out := captureOutput(t, func() { Rebase("master~4", "", RebaseOptions{Interactive: true}) })
+			if sequencerInProgress() {
+				t.Fatalf("rebase did not finish:\n%s", out)
+			}
+			if !strings.Contains(out, tt.wantOut) {
+				t.Errorf("output does not mention %q:\n%s", tt.wantOut, out)
+			}
+			if ref := headRef(); ref != "refs/heads/master" {
+				t.Errorf("HEAD is %q, want it back on master", ref)
+			}
+			if got := firstParentSubjects(headCommitID()); !reflect.DeepEqual(got, tt.wantLog) {
+				t.Errorf("history is %q, want %q:\n%s", got, tt.wantLog, out)
+			}
+			if tt.wantFiles == nil {
+				return
+			}
+			for _, name := range []string{"a", "b", "c", "d", "ran"} {
+				_, err := os.Stat(name)
+				want := false
+				for _, f := range tt.wantFiles {
+					want = want || f == name
+				}
+				if (err == nil) != want {
+					t.Errorf("%s exists = %v, want %v", name, err == nil, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInteractiveRebaseEdit(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "base", map[string]string{"f": "1\n"})
+	commitFiles(t, "a", map[string]string{"a": "a\n"})
+	commitFiles(t, "b", map[string]string{"b": "b\n"})
+	todoEditor(t, "edit master~1\npick master\n")
+
+	out := captureOutput(t, func() { Rebase("master~2", "", RebaseOptions{Interactive: true}) })
+	if !sequencerInProgress() {
+		t.Fatalf("rebase did not stop at the edit step:\n%s", out)
+	}
+	if got := commitSubject(headCommitID()); got != "a" {
+		t.Fatalf("stopped at %q, want a", got)
+	}
+	writeFiles(t, map[string]string{"a": "amended\n"})
+	captureOutput(t, func() {
+		Add("a")
+		Commit("a, amended", nil, CommitOptions{Amend: true})
+	})
+	captureOutput(t, SequencerContinue)
+	if sequencerInProgress() {
+		t.Fatal("rebase is still in progress")
+	}
+	want := []string{"b", "a, amended", "base"}
+	if got := firstParentSubjects(headCommitID()); !reflect.DeepEqual(got, want) {
+		t.Errorf("history is %q, want %q", got, want)
+	}
+	if got, _ := ioutil.ReadFile("a"); string(got) != "amended\n" {
+		t.Errorf("a is %q, want the amended content", got)
+	}
+}
+
+func TestInteractiveRebaseFailedExec(t *testing.T) {
+	tests := []struct {
+		name   string
+		resume func()
+	}{
+		{"skip", SequencerSkip},
+		{"continue", SequencerContinue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			commitFiles(t, "base", map[string]string{"base": "x\n"})
+			for _, name := range []string{"a", "b", "c"} {
+				commitFiles(t, name, map[string]string{name: name + "\n"})
+			}
+			todoEditor(t, "pick master~2\nexec false\npick master~1\npick master\n")
+
+			out := captureOutput(t, func() { Rebase("master~3", "", RebaseOptions{Interactive: true}) })
+			if !sequencerInProgress() || !strings.Contains(out, "Execution failed: false") {
+				t.Fatalf("rebase did not stop at the failed exec:\n%s", out)
+			}
+			out = captureOutput(t, tt.resume)
+			if sequencerInProgress() {
+				t.Fatalf("rebase did not finish:\n%s", out)
+			}
+			if got, want := firstParentSubjects(headCommitID()), []string{"c", "b", "a", "base"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("history is %q, want %q:\n%s", got, want, out)
+			}
+		})
+	}
+}
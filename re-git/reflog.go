@@ -0,0 +1,194 @@
+package regit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const nullID = "0000000000000000000000000000000000000000"
+
+// defaultReflogExpireDays is how long reflog entries survive gc unless
+// gc.reflogExpire (in days) says otherwise.
+const defaultReflogExpireDays = 90
+
+type reflogEntry struct {
+	Old, New string
+	Who      string
+	Time     time.Time
+	Reason   string
+}
+
+func reflogPath(ref string) string {
+	return filepath.Join(logsDir, ref)
+}
+
+// reflogIdentity names whoever is moving the ref. Unlike commits, ref moves
+// never fail for want of an identity, so it falls back to the login name.
+func reflogIdentity() string {
+	if id, err := roleIdentity("COMMITTER"); err == nil {
+		return id.String()
+	}
+	name := os.Getenv("USER")
+	if name == "" {
+		name = "unknown"
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s <%s@%s>", name, name, host)
+}
+
+func formatReflogEntry(e reflogEntry) string {
+	return fmt.Sprintf("%s %s %s %d %s\t%s\n", e.Old, e.New, e.Who, e.Time.Unix(), e.Time.Format("-0700"), e.Reason)
+}
+
+func appendReflog(ref, old, new, reason string) {
+	if old == "" {
+		old = nullID
+	}
+	if new == "" {
+		new = nullID
+	}
+	path := reflogPath(ref)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(formatReflogEntry(reflogEntry{Old: old, New: new, Who: reflogIdentity(), Time: time.Now(), Reason: reason}))
+}
+
+func writeReflog(ref string, entries []reflogEntry) error {
+	var b strings.Builder
+	for _, e := range entries {
+		b.WriteString(formatReflogEntry(e))
+	}
+	return ioutil.WriteFile(reflogPath(ref), []byte(b.String()), 0644)
+}
+
+// readReflog returns the entries of ref's reflog, oldest first.
+func readReflog(ref string) []reflogEntry {
+	data, err := ioutil.ReadFile(reflogPath(ref))
+	if err != nil {
+		return nil
+	}
+	var entries []reflogEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		head, reason, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(head)
+		if len(fields) < 4 {
+			continue
+		}
+		unix, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+		if err != nil {
+			continue
+		}
+		when := time.Unix(unix, 0)
+		if zone, err := time.Parse("-0700", fields[len(fields)-1]); err == nil {
+			when = when.In(zone.Location())
+		}
+		entries = append(entries, reflogEntry{
+			Old:    fields[0],
+			New:    fields[1],
+			Who:    strings.Join(fields[2:len(fields)-2], " "),
+			Time:   when,
+			Reason: reason,
+		})
+	}
+	return entries
+}
+
+// reflogRef maps a name used in revision syntax to the ref whose reflog
+// should be read.
+func reflogRef(name string) string {
+	switch {
+	case name == "" || name == "HEAD" || name == "@":
+		return "HEAD"
+	case name == "stash":
+		return stashRef
+	case strings.HasPrefix(name, "refs/"):
+		return name
+	}
+	if _, err := os.Stat(filepath.Join(headsDir, name)); err == nil {
+		return "refs/heads/" + name
+	}
+	return name
+}
+
+// reflogLookup returns the value ref had n moves ago; n == 0 is the current
+// value.
+func reflogLookup(ref string, n int) (string, error) {
+	entries := readReflog(ref)
+	if n < 0 || n >= len(entries) {
+		return "", fmt.Errorf("log for '%s' only has %d entries", ref, len(entries))
+	}
+	id := entries[len(entries)-1-n].New
+	if id == nullID {
+		return "", fmt.Errorf("%s@{%d} has no commit", ref, n)
+	}
+	return id, nil
+}
+
+func ReflogShow(name string) {
+	ref := reflogRef(name)
+	entries := readReflog(ref)
+	if len(entries) == 0 {
+		fmt.Println("No reflog for", ref)
+		return
+	}
+	display := strings.TrimPrefix(ref, "refs/heads/")
+	for n := len(entries) - 1; n >= 0; n-- {
+		e := entries[n]
+		fmt.Printf("%s %s@{%d}: %s\n", shortID(e.New), display, len(entries)-1-n, e.Reason)
+	}
+}
+
+// expireReflogs drops reflog entries older than the configured expiry.
+func expireReflogs() {
+	days := configInt("gc.reflogExpire", defaultReflogExpireDays)
+	cutoff := time.Now().AddDate(0, 0, -int(days))
+	for _, ref := range reflogRefs() {
+		if ref == stashRef {
+			// The stash stack lives in this reflog; never expire it.
+			continue
+		}
+		var kept []reflogEntry
+		entries := readReflog(ref)
+		for _, e := range entries {
+			if !e.Time.Before(cutoff) {
+				kept = append(kept, e)
+			}
+		}
+		if expired := len(entries) - len(kept); expired > 0 {
+			writeReflog(ref, kept)
+			fmt.Printf("Expired %d reflog entries for %s\n", expired, ref)
+		}
+	}
+}
+
+// reflogRefs lists the refs that have a reflog.
+func reflogRefs() []string {
+	var refs []string
+	filepath.Walk(logsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		ref, _ := filepath.Rel(logsDir, path)
+		refs = append(refs, filepath.ToSlash(ref))
+		return nil
+	})
+	return refs
+}
+
+// GC expires old reflog entries and then purges objects no commit uses.
+func GC() {
+	expireReflogs()
+	PurgeUnreferencedObjects()
+}
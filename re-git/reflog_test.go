@@ -0,0 +1,148 @@
+package regit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadReflog(t *testing.T) {
+	testRepo(t)
+	a, b := strings.Repeat("a", 40), strings.Repeat("b", 40)
+	lines := []string{
+		nullID + " " + a + " Test User <test@example.com> 1700000000 +0000\tcommit (initial): one",
+		a + " " + b + " Test User <test@example.com> 1700000100 +0200\tcheckout: moving from master to topic",
+		"not a reflog line",
+		a + " " + b + " 1700000200\tmissing identity and zone",
+	}
+	path := reflogPath("HEAD")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := readReflog("HEAD")
+	want := []reflogEntry{
+		{Old: nullID, New: a, Who: "Test User <test@example.com>", Time: time.Unix(1700000000, 0), Reason: "commit (initial): one"},
+		{Old: a, New: b, Who: "Test User <test@example.com>", Time: time.Unix(1700000100, 0), Reason: "checkout: moving from master to topic"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("readReflog gave %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Old != w.Old || g.New != w.New || g.Who != w.Who || !g.Time.Equal(w.Time) || g.Reason != w.Reason {
+			t.Errorf("entry %d = %+v, want %+v", i, g, w)
+		}
+	}
+	if _, offset := got[1].Time.Zone(); offset != 2*60*60 {
+		t.Errorf("entry 1 is in zone offset %d, want +0200", offset)
+	}
+}
+
+func TestReflogRecordsMoves(t *testing.T) {
+	testRepo(t)
+	c1 := commitFiles(t, "one", map[string]string{"f": "1\n"})
+	c2 := commitFiles(t, "two", map[string]string{"f": "2\n"})
+	captureOutput(t, func() { CreateBranch("topic") })
+	captureOutput(t, func() { CheckoutBranch("topic") })
+	captureOutput(t, func() { ResetTo("HEAD~1", "hard") })
+
+	tests := []struct {
+		ref     string
+		reasons []string
+		news    []string
+	}{
+		{"refs/heads/master", []string{"commit (initial): one", "commit: two"}, []string{c1, c2}},
+		{"refs/heads/topic", []string{"branch: Created from HEAD", "reset: moving to HEAD~1"}, []string{c2, c1}},
+		{"HEAD", []string{"commit (initial): one", "commit: two", "checkout: moving from master to topic", "reset: moving to HEAD~1"}, []string{c1, c2, c2, c1}},
+	}
+	for _, tt := range tests {
+		var reasons, news []string
+		for _, e := range readReflog(tt.ref) {
+			reasons = append(reasons, e.Reason)
+			news = append(news, e.New)
+		}
+		if !reflect.DeepEqual(reasons, tt.reasons) || !reflect.DeepEqual(news, tt.news) {
+			t.Errorf("%s reflog is %q -> %q, want %q -> %q", tt.ref, reasons, news, tt.reasons, tt.news)
+		}
+	}
+	if id, err := resolveRevision("topic@{1}"); err != nil || id != c2 {
+		t.Errorf("topic@{1} = %s, %v; want %s", id, err, c2)
+	}
+}
+
+func TestExpireReflogs(t *testing.T) {
+	testRepo(t)
+	a := strings.Repeat("a", 40)
+	old := reflogEntry{Old: nullID, New: a, Who: "T <t@example.com>", Time: time.Now().AddDate(0, 0, -100), Reason: "old"}
+	recent := reflogEntry{Old: a, New: a, Who: "T <t@example.com>", Time: time.Now().AddDate(0, 0, -10), Reason: "recent"}
+	for _, ref := range []string{"HEAD", "refs/heads/master", stashRef} {
+		os.MkdirAll(filepath.Dir(reflogPath(ref)), 0755)
+		if err := writeReflog(ref, []reflogEntry{old, recent}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		expire string // gc.reflogExpire, "" for the default
+		ref    string
+		want   []string
+	}{
+		{"", "HEAD", []string{"recent"}},
+		{"", "refs/heads/master", []string{"recent"}},
+		{"", stashRef, []string{"old", "recent"}},
+		{"5", "HEAD", nil},
+		{"5", stashRef, []string{"old", "recent"}},
+	}
+	for _, tt := range tests {
+		for _, ref := range []string{"HEAD", "refs/heads/master", stashRef} {
+			writeReflog(ref, []reflogEntry{old, recent})
+		}
+		captureOutput(t, func() {
+			ConfigUnset("gc.reflogExpire", false, ConfigOptions{})
+			if tt.expire != "" {
+				ConfigSet("gc.reflogExpire", tt.expire, false, ConfigOptions{})
+			}
+			expireReflogs()
+		})
+		var got []string
+		for _, e := range readReflog(tt.ref) {
+			got = append(got, e.Reason)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("gc.reflogExpire=%q: %s keeps %q, want %q", tt.expire, tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestGCKeepsReachableObjects(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "base", map[string]string{"f": "committed\n"})
+	writeFiles(t, map[string]string{"f": "stashed\n", "u": "untracked\n"})
+	captureOutput(t, func() { StashPush("", nil, true, false) })
+	writeFiles(t, map[string]string{"g": "staged\n"})
+	captureOutput(t, func() { Add("g") })
+	writeObject([]byte("garbage\n"))
+
+	out := captureOutput(t, GC)
+	if n := strings.Count(out, "Purged unreferenced object:"); n != 1 {
+		t.Errorf("gc purged %d objects, want only the garbage one:\n%s", n, out)
+	}
+	for _, data := range []string{"committed\n", "stashed\n", "untracked\n", "staged\n"} {
+		if _, err := readObject(hashObject([]byte(data))); err != nil {
+			t.Errorf("gc removed the blob of %q", data)
+		}
+	}
+
+	captureOutput(t, func() { Commit("add g", nil, CommitOptions{}) })
+	if out := captureOutput(t, func() { ResetTo("HEAD", "hard") }); strings.Contains(out, "Missing") || strings.Contains(out, "rror") {
+		t.Errorf("reset --hard after gc failed:\n%s", out)
+	}
+	if data, _ := ioutil.ReadFile("g"); string(data) != "staged\n" {
+		t.Errorf("g holds %q after reset --hard", data)
+	}
+}
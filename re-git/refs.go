@@ -0,0 +1,105 @@
+package regit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// headRef returns the ref HEAD points at ("refs/heads/master"), or "" when
+// HEAD is detached.
+func headRef() string {
+	data, err := ioutil.ReadFile(headFile)
+	if err != nil {
+		return "refs/heads/master"
+	}
+	head := strings.TrimSpace(string(data))
+	if strings.HasPrefix(head, "ref: ") {
+		return strings.TrimPrefix(head, "ref: ")
+	}
+	return ""
+}
+
+func currentBranch() string {
+	return strings.TrimPrefix(headRef(), "refs/heads/")
+}
+
+// readRef returns the commit ID stored in a ref such as "refs/heads/master"
+// or "HEAD", or "" if the ref does not exist or has no commits yet.
+func readRef(ref string) string {
+	if ref == "HEAD" {
+		if r := headRef(); r != "" {
+			return readRef(r)
+		}
+		data, _ := ioutil.ReadFile(headFile)
+		return strings.TrimSpace(string(data))
+	}
+	data, err := ioutil.ReadFile(filepath.Join(repoDir, ref))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// branchNames lists every branch, including ones nested in directories
+// such as feature/x.
+func branchNames() []string {
+	var names []string
+	filepath.Walk(headsDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			name, _ := filepath.Rel(headsDir, path)
+			names = append(names, filepath.ToSlash(name))
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names
+}
+
+func headCommitID() string {
+	return readRef("HEAD")
+}
+
+// updateRef points ref at newID and records the move in its reflog. When
+// the ref is the branch HEAD is on, HEAD's reflog gets the entry as well.
+func updateRef(ref, newID, reason string) error {
+	if ref == "HEAD" {
+		if r := headRef(); r != "" {
+			return updateRef(r, newID, reason)
+		}
+		old := readRef("HEAD")
+		if err := ioutil.WriteFile(headFile, []byte(newID+"\n"), 0644); err != nil {
+			return err
+		}
+		appendReflog("HEAD", old, newID, reason)
+		return nil
+	}
+	old := readRef(ref)
+	path := filepath.Join(repoDir, ref)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := ioutil.WriteFile(path, []byte(newID+"\n"), 0644); err != nil {
+		return err
+	}
+	appendReflog(ref, old, newID, reason)
+	if ref == headRef() {
+		appendReflog("HEAD", old, newID, reason)
+	}
+	return nil
+}
+
+// setHead attaches HEAD to ref, or detaches it at a commit when ref is a
+// commit ID, logging the checkout in HEAD's reflog.
+func setHead(ref, reason string) error {
+	old := readRef("HEAD")
+	content := "ref: " + ref + "\n"
+	if isObjectID(ref) {
+		content = ref + "\n"
+	}
+	if err := ioutil.WriteFile(headFile, []byte(content), 0644); err != nil {
+		return err
+	}
+	appendReflog("HEAD", old, readRef("HEAD"), reason)
+	return nil
+}
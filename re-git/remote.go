@@ -5,57 +5,118 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// Enhanced Pull: sync objects, log, refs, HEAD
 func Pull(remotePath string) {
 	remoteObjects := filepath.Join(remotePath, objectsDir)
 	remoteLog := filepath.Join(remotePath, logFile)
+	remoteHeads := filepath.Join(remotePath, headsDir)
+	remoteHEAD := filepath.Join(remotePath, headFile)
+
+	os.MkdirAll(objectsDir, 0755)
+	os.MkdirAll(refsDir, 0755)
+	os.MkdirAll(headsDir, 0755)
+
+	// Copy objects
 	files, err := ioutil.ReadDir(remoteObjects)
-	if err != nil {
-		fmt.Println("Error reading remote objects")
-		return
-	}
-	for _, f := range files {
-		src := filepath.Join(remoteObjects, f.Name())
-		dst := filepath.Join(objectsDir, f.Name())
-		data, err := ioutil.ReadFile(src)
-		if err == nil {
-			ioutil.WriteFile(dst, data, 0644)
+	if err == nil {
+		for _, f := range files {
+			src := filepath.Join(remoteObjects, f.Name())
+			dst := filepath.Join(objectsDir, f.Name())
+			data, err := ioutil.ReadFile(src)
+			if err == nil {
+				ioutil.WriteFile(dst, data, 0644)
+			}
 		}
 	}
-	remoteLogData, err := ioutil.ReadFile(remoteLog)
+
+	// Add remote commits to the log, keeping local ones reachable from the reflog
+	mergeCommitLog(logFile, remoteLog)
+
+	// Copy refs/heads
+	branches, err := ioutil.ReadDir(remoteHeads)
 	if err == nil {
-		localLogData, _ := ioutil.ReadFile(logFile)
-		merged := string(localLogData) + string(remoteLogData)
-		ioutil.WriteFile(logFile, []byte(merged), 0644)
+		for _, b := range branches {
+			data, err := ioutil.ReadFile(filepath.Join(remoteHeads, b.Name()))
+			if err != nil {
+				continue
+			}
+			ref := "refs/heads/" + b.Name()
+			id := strings.TrimSpace(string(data))
+			if id != readRef(ref) {
+				updateRef(ref, id, "pull: "+remotePath)
+			}
+		}
+	}
+
+	// Copy HEAD
+	headData, err := ioutil.ReadFile(remoteHEAD)
+	if err == nil && strings.TrimSpace(string(headData)) != "ref: "+headRef() {
+		setHead(strings.TrimPrefix(strings.TrimSpace(string(headData)), "ref: "), "pull: "+remotePath)
 	}
+
 	fmt.Println("Pulled from", remotePath)
+	runHook("post-merge", "", "0")
 }
 
-func Push(remotePath string) {
+// Enhanced Push: sync objects, log, refs, HEAD
+func Push(remotePath string, noVerify bool) {
+	if !noVerify {
+		if err := runHook("pre-push", prePushInput(remotePath), remotePath, remotePath); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
 	remoteObjects := filepath.Join(remotePath, objectsDir)
 	remoteLog := filepath.Join(remotePath, logFile)
+	remoteRefs := filepath.Join(remotePath, refsDir)
+	remoteHeads := filepath.Join(remotePath, headsDir)
+	remoteHEAD := filepath.Join(remotePath, headFile)
+
+	os.MkdirAll(remoteObjects, 0755)
+	os.MkdirAll(remoteRefs, 0755)
+	os.MkdirAll(remoteHeads, 0755)
+
+	// Copy objects
 	files, err := ioutil.ReadDir(objectsDir)
-	if err != nil {
-		fmt.Println("Error reading local objects")
-		return
-	}
-	for _, f := range files {
-		src := filepath.Join(objectsDir, f.Name())
-		dst := filepath.Join(remoteObjects, f.Name())
-		data, err := ioutil.ReadFile(src)
-		if err == nil {
-			ioutil.WriteFile(dst, data, 0644)
+	if err == nil {
+		for _, f := range files {
+			src := filepath.Join(objectsDir, f.Name())
+			dst := filepath.Join(remoteObjects, f.Name())
+			data, err := ioutil.ReadFile(src)
+			if err == nil {
+				ioutil.WriteFile(dst, data, 0644)
+			}
 		}
 	}
+
+	// Copy log
 	localLogData, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		fmt.Println("Error reading local log")
-		return
+	if err == nil {
+		ioutil.WriteFile(remoteLog, localLogData, 0644)
 	}
-	remoteLogData, _ := ioutil.ReadFile(remoteLog)
-	merged := string(remoteLogData) + string(localLogData)
-	ioutil.WriteFile(remoteLog, []byte(merged), 0644)
+
+	// Copy refs/heads
+	branches, err := ioutil.ReadDir(headsDir)
+	if err == nil {
+		for _, b := range branches {
+			src := filepath.Join(headsDir, b.Name())
+			dst := filepath.Join(remoteHeads, b.Name())
+			data, err := ioutil.ReadFile(src)
+			if err == nil {
+				ioutil.WriteFile(dst, data, 0644)
+			}
+		}
+	}
+
+	// Copy HEAD
+	headData, err := ioutil.ReadFile(headFile)
+	if err == nil {
+		ioutil.WriteFile(remoteHEAD, headData, 0644)
+	}
+
 	fmt.Println("Pushed to", remotePath)
 }
 
@@ -87,7 +148,6 @@ func Clone(remotePath, targetPath string) {
 
 func Fetch(remotePath string) {
 	remoteObjects := filepath.Join(remotePath, objectsDir)
-	remoteLog := filepath.Join(remotePath, logFile)
 	files, err := ioutil.ReadDir(remoteObjects)
 	if err != nil {
 		fmt.Println("Error reading remote objects")
@@ -108,15 +168,12 @@ func Fetch(remotePath string) {
 
 func Merge(remotePath string) {
 	remoteLog := filepath.Join(remotePath, logFile)
-	remoteLogData, err := ioutil.ReadFile(remoteLog)
-	if err != nil {
+	if err := mergeCommitLog(logFile, remoteLog); err != nil {
 		fmt.Println("Error reading remote log")
 		return
 	}
-	localLogData, _ := ioutil.ReadFile(logFile)
-	merged := string(localLogData) + string(remoteLogData)
-	ioutil.WriteFile(logFile, []byte(merged), 0644)
 	fmt.Println("Merged log from", remotePath)
+	runHook("post-merge", "", "0")
 }
 
 func MergeToRemote(remotePath string) {
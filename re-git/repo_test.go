@@ -0,0 +1,123 @@
+package regit
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testRepo makes an empty repository with an identity configured in a
+// temporary directory, and works in it for the rest of the test. The
+// system and global config files are kept out of the way.
+func testRepo(t *testing.T) {
+	t.Helper()
+	dir, home := t.TempDir(), t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("HOME", home)
+	t.Setenv("REGIT_CONFIG_SYSTEM", filepath.Join(home, "system"))
+	t.Setenv("REGIT_CONFIG_GLOBAL", filepath.Join(home, "global"))
+	t.Setenv("REGIT_EDITOR", "true")
+	for _, role := range []string{"AUTHOR", "COMMITTER"} {
+		for _, v := range []string{"NAME", "EMAIL", "DATE"} {
+			t.Setenv("REGIT_"+role+"_"+v, "")
+		}
+	}
+	captureOutput(t, func() {
+		Init()
+		ConfigSet("user.name", "Test User", false, ConfigOptions{})
+		ConfigSet("user.email", "test@example.com", false, ConfigOptions{})
+	})
+}
+
+// writeFiles writes each file with its contents, making directories as
+// needed.
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// commitFiles writes and stages files, commits them and returns the new
+// HEAD.
+func commitFiles(t *testing.T, message string, files map[string]string) string {
+	t.Helper()
+	writeFiles(t, files)
+	captureOutput(t, func() {
+		for name := range files {
+			Add(name)
+		}
+		Commit(message, nil, CommitOptions{})
+	})
+	id := headCommitID()
+	if c, ok := loadCommit(id); !ok || subject(c.Message) != message {
+		t.Fatalf("commit %q was not made", message)
+	}
+	return id
+}
+
+// captureOutput runs fn and returns what it printed to standard output.
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stdout, fn)
+}
+
+// captureStderr is captureOutput for standard error.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stderr, fn)
+}
+
+func captureFile(t *testing.T, file **os.File, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := *file
+	*file = w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	defer func() {
+		*file = saved
+	}()
+	fn()
+	w.Close()
+	*file = saved
+	return string(<-done)
+}
+
+// firstParentSubjects lists the subjects of id and its first-parent
+// ancestors, newest first.
+func firstParentSubjects(id string) []string {
+	var subjects []string
+	for id != "" {
+		c, ok := loadCommit(id)
+		if !ok {
+			break
+		}
+		subjects = append(subjects, subject(c.Message))
+		id = ""
+		if len(c.Parents) > 0 {
+			id = c.Parents[0]
+		}
+	}
+	return subjects
+}
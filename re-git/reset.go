@@ -0,0 +1,166 @@
+package regit
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ResetTo moves the current branch (or a detached HEAD) to rev. mode is one
+// of "soft" (keep index and working tree, staging the difference), "mixed"
+// (reset the index), "hard" (reset index and working tree) or "keep" (like
+// hard, but refuse to touch files with local changes).
+func ResetTo(rev, mode string) {
+	target, err := resolveRevision(rev)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	switch mode {
+	case "soft", "mixed", "hard":
+	case "keep":
+		if files := keepConflicts(commitTree(headCommitID()), stagedTree(), commitTree(target)); len(files) > 0 {
+			fmt.Println("Local changes would be overwritten by reset:")
+			for _, f := range files {
+				fmt.Println(" ", f)
+			}
+			fmt.Println("Aborting")
+			return
+		}
+	default:
+		fmt.Println("Unknown reset mode:", mode)
+		return
+	}
+
+	if err := resetTo(target, mode, "reset: moving to "+rev); err != nil {
+		fmt.Println("Error resetting:", err)
+		return
+	}
+	fmt.Printf("HEAD is now at %s %s\n", shortID(target), commitSubject(target))
+}
+
+func resetTo(target, mode, reason string) error {
+	head := commitTree(headCommitID())
+	staged := stagedTree()
+	tree := commitTree(target)
+	if err := updateRef("HEAD", target, reason); err != nil {
+		return err
+	}
+	index := map[string]string{}
+	if mode == "soft" {
+		index = indexFor(tree, staged)
+	}
+	if err := writeIndex(index); err != nil {
+		return err
+	}
+	if mode == "keep" {
+		// keepConflicts has made sure the files this touches are clean.
+		return applyTreeChange(head, tree)
+	}
+	if mode == "hard" {
+		from := staged
+		for f, oid := range head {
+			from[f] = oid
+		}
+		return checkoutTree(from, tree)
+	}
+	return nil
+}
+
+// keepConflicts lists files that reset --keep would have to overwrite even
+// though they have staged or unstaged changes.
+func keepConflicts(head, staged, tree map[string]string) []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, m := range []map[string]string{head, tree} {
+		for f := range m {
+			if seen[f] || head[f] == tree[f] {
+				continue
+			}
+			seen[f] = true
+			if staged[f] != head[f] || workingOid(f) != head[f] {
+				files = append(files, f)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// resetMerge moves HEAD to target as reset --merge does: the index is
+// cleared and only the working files that are staged, unmerged or differ
+// between the index and target are rewritten, so other local edits survive.
+// It refuses if one of those files also has unstaged changes, unless it is
+// one of the unmerged paths.
+func resetMerge(target string, unmerged []string, reason string) error {
+	staged := stagedTree()
+	tree := commitTree(target)
+	skip := map[string]bool{}
+	for _, f := range unmerged {
+		skip[f] = true
+	}
+	touched := map[string]string{}
+	for _, f := range changedFiles(staged, tree) {
+		touched[f] = staged[f]
+	}
+	for f := range readIndex() {
+		touched[f] = staged[f]
+	}
+	for _, f := range sortedKeys(touched) {
+		if !skip[f] && workingOid(f) != staged[f] {
+			return fmt.Errorf("your local changes to %s would be overwritten", f)
+		}
+	}
+	for f := range skip {
+		touched[f] = staged[f]
+	}
+	to := map[string]string{}
+	for f := range touched {
+		if oid, ok := tree[f]; ok {
+			to[f] = oid
+		}
+	}
+	if err := updateRef("HEAD", target, reason); err != nil {
+		return err
+	}
+	if err := writeIndex(map[string]string{}); err != nil {
+		return err
+	}
+	return checkoutTree(touched, to)
+}
+
+// ResetPaths sets the index entries for paths back to their version in rev,
+// leaving HEAD and the working tree alone. With rev "HEAD" this unstages
+// them.
+func ResetPaths(rev string, paths []string) {
+	target := ""
+	if rev != "HEAD" || headCommitID() != "" {
+		var err error
+		if target, err = resolveRevision(rev); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	head := commitTree(headCommitID())
+	tree := commitTree(target)
+	index := readIndex()
+	for _, p := range paths {
+		switch oid, ok := tree[p]; {
+		case ok && oid == head[p]:
+			delete(index, p)
+		case ok:
+			index[p] = oid
+		case head[p] != "":
+			index[p] = nullID
+		default:
+			delete(index, p)
+		}
+		if rev == "HEAD" {
+			fmt.Println("Unstaged", p)
+		} else {
+			fmt.Printf("Reset %s to %s\n", p, rev)
+		}
+	}
+	if err := writeIndex(index); err != nil {
+		fmt.Println("Error writing index")
+	}
+}
@@ -0,0 +1,101 @@
+package regit
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestResetTo(t *testing.T) {
+	tests := []struct {
+		mode       string
+		wantHead   int // index of the commit HEAD should be at
+		wantStaged int // index of the commit whose tree should be staged
+		wantWork   map[string]string
+	}{
+		{"soft", 0, 1, map[string]string{"f": "2\n", "g": "1\n", "h": "new\n"}},
+		{"mixed", 0, 0, map[string]string{"f": "2\n", "g": "1\n", "h": "new\n"}},
+		{"hard", 0, 0, map[string]string{"f": "1\n", "g": "1\n", "h": ""}},
+		{"keep", 0, 0, map[string]string{"f": "1\n", "g": "1\n", "h": ""}},
+		{"bogus", 1, 1, map[string]string{"f": "2\n", "g": "1\n", "h": "new\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			testRepo(t)
+			ids := []string{
+				commitFiles(t, "one", map[string]string{"f": "1\n", "g": "1\n"}),
+				commitFiles(t, "two", map[string]string{"f": "2\n", "h": "new\n"}),
+			}
+			captureOutput(t, func() { ResetTo("HEAD~1", tt.mode) })
+			if got := headCommitID(); got != ids[tt.wantHead] {
+				t.Errorf("HEAD is %s, want %s", commitSubject(got), commitSubject(ids[tt.wantHead]))
+			}
+			if got, want := stagedTree(), commitTree(ids[tt.wantStaged]); !reflect.DeepEqual(got, want) {
+				t.Errorf("staged tree is %v, want %v", got, want)
+			}
+			for f, want := range tt.wantWork {
+				if got, _ := ioutil.ReadFile(f); string(got) != want {
+					t.Errorf("%s is %q, want %q", f, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResetKeep(t *testing.T) {
+	tests := []struct {
+		name     string
+		local    map[string]string
+		wantMove bool
+	}{
+		{"local change to a file the reset leaves alone", map[string]string{"g": "mine\n"}, true},
+		{"local change to a file the reset changes", map[string]string{"f": "mine\n"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			c1 := commitFiles(t, "one", map[string]string{"f": "1\n", "g": "1\n"})
+			c2 := commitFiles(t, "two", map[string]string{"f": "2\n"})
+			writeFiles(t, tt.local)
+			captureOutput(t, func() { ResetTo("HEAD~1", "keep") })
+			want := c2
+			if tt.wantMove {
+				want = c1
+			}
+			if got := headCommitID(); got != want {
+				t.Errorf("HEAD is %s, want %s", commitSubject(got), commitSubject(want))
+			}
+			for f, data := range tt.local {
+				if got, _ := ioutil.ReadFile(f); string(got) != data {
+					t.Errorf("local change to %s was lost: %q", f, got)
+				}
+			}
+		})
+	}
+}
+
+func TestResetPaths(t *testing.T) {
+	testRepo(t)
+	c1 := commitFiles(t, "one", map[string]string{"f": "1\n", "g": "1\n"})
+	commitFiles(t, "two", map[string]string{"f": "2\n", "h": "new\n"})
+	writeFiles(t, map[string]string{"g": "staged\n"})
+	captureOutput(t, func() { Add("g") })
+
+	tests := []struct {
+		rev, path string
+		want      string // the staged oid; "" when not in the staged tree
+	}{
+		{"HEAD", "g", commitTree(c1)["g"]},
+		{"HEAD~1", "f", commitTree(c1)["f"]},
+		{"HEAD~1", "h", ""},
+	}
+	for _, tt := range tests {
+		captureOutput(t, func() { ResetPaths(tt.rev, []string{tt.path}) })
+		if got := stagedTree()[tt.path]; got != tt.want {
+			t.Errorf("after reset %s -- %s the index has %q, want %q", tt.rev, tt.path, got, tt.want)
+		}
+	}
+	if got, _ := ioutil.ReadFile("g"); string(got) != "staged\n" {
+		t.Errorf("reset of paths changed the working tree: g is %q", got)
+	}
+}
@@ -0,0 +1,86 @@
+package regit
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestRevert(t *testing.T) {
+	// History: one (f=1, g=1), two (f=2), three (g=2).
+	tests := []struct {
+		name        string
+		revs        []string
+		noCommit    bool
+		wantLog     []string
+		wantFiles   map[string]string
+		wantStopped bool
+	}{
+		{
+			name:      "one commit",
+			revs:      []string{"HEAD~1"},
+			wantLog:   []string{`Revert "two"`, "three", "two", "one"},
+			wantFiles: map[string]string{"f": "1\n", "g": "2\n"},
+		},
+		{
+			name:      "a range, newest first",
+			revs:      []string{"HEAD~2..HEAD"},
+			wantLog:   []string{`Revert "two"`, `Revert "three"`, "three", "two", "one"},
+			wantFiles: map[string]string{"f": "1\n", "g": "1\n"},
+		},
+		{
+			name:      "no commit",
+			revs:      []string{"HEAD~1"},
+			noCommit:  true,
+			wantLog:   []string{"three", "two", "one"},
+			wantFiles: map[string]string{"f": "1\n", "g": "2\n"},
+		},
+		{
+			name:        "conflict",
+			revs:        []string{"HEAD~1", "HEAD~2"},
+			wantLog:     []string{`Revert "two"`, "three", "two", "one"},
+			wantFiles:   map[string]string{"g": "2\n"},
+			wantStopped: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			commitFiles(t, "one", map[string]string{"f": "1\n", "g": "1\n"})
+			commitFiles(t, "two", map[string]string{"f": "2\n"})
+			commitFiles(t, "three", map[string]string{"g": "2\n"})
+
+			out := captureOutput(t, func() { Revert(tt.revs, tt.noCommit) })
+			if got := firstParentSubjects(headCommitID()); !reflect.DeepEqual(got, tt.wantLog) {
+				t.Errorf("history is %q, want %q:\n%s", got, tt.wantLog, out)
+			}
+			for f, want := range tt.wantFiles {
+				if got, _ := ioutil.ReadFile(f); string(got) != want {
+					t.Errorf("%s is %q, want %q", f, got, want)
+				}
+			}
+			if sequencerInProgress() != tt.wantStopped {
+				t.Errorf("sequencer in progress = %v, want %v:\n%s", sequencerInProgress(), tt.wantStopped, out)
+			}
+		})
+	}
+}
+
+func TestRevertAbort(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "one", map[string]string{"f": "1\n"})
+	commitFiles(t, "two", map[string]string{"f": "2\n"})
+	three := commitFiles(t, "three", map[string]string{"f": "3\n"})
+
+	out := captureOutput(t, func() { Revert([]string{"HEAD~1"}, false) })
+	if !sequencerInProgress() {
+		t.Fatalf("revert did not stop on the conflict:\n%s", out)
+	}
+	captureOutput(t, SequencerAbort)
+	if sequencerInProgress() || headCommitID() != three {
+		t.Errorf("abort left HEAD at %s", commitSubject(headCommitID()))
+	}
+	if got, _ := ioutil.ReadFile("f"); string(got) != "3\n" {
+		t.Errorf("abort left f as %q", got)
+	}
+}
@@ -0,0 +1,171 @@
+package regit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// resolveRevision turns a revision into a commit ID. Supported forms are a
+// full or abbreviated commit ID, HEAD (or @), branch and tag names, full
+// ref paths, <ref>@{n} reflog entries, and any of those followed by ~n, ^
+// or ^n.
+func resolveRevision(spec string) (string, error) {
+	base, suffix := spec, ""
+	if i := strings.IndexAny(spec, "~^"); i >= 0 {
+		base, suffix = spec[:i], spec[i:]
+	}
+	id, err := resolveBase(base)
+	if err != nil {
+		return "", err
+	}
+	for suffix != "" {
+		op := suffix[0]
+		suffix = suffix[1:]
+		n, digits := 1, 0
+		for digits < len(suffix) && suffix[digits] >= '0' && suffix[digits] <= '9' {
+			digits++
+		}
+		if digits > 0 {
+			n, _ = strconv.Atoi(suffix[:digits])
+			suffix = suffix[digits:]
+		}
+		if op == '~' {
+			for ; n > 0; n-- {
+				if id, err = nthParent(id, 1); err != nil {
+					return "", err
+				}
+			}
+		} else if n > 0 {
+			if id, err = nthParent(id, n); err != nil {
+				return "", err
+			}
+		}
+	}
+	return id, nil
+}
+
+func resolveBase(base string) (string, error) {
+	if i := strings.Index(base, "@{"); i >= 0 && strings.HasSuffix(base, "}") {
+		n, err := strconv.Atoi(base[i+2 : len(base)-1])
+		if err != nil {
+			return "", fmt.Errorf("bad reflog selector: %s", base)
+		}
+		return reflogLookup(reflogRef(base[:i]), n)
+	}
+	if base == "HEAD" || base == "@" {
+		if id := headCommitID(); id != "" {
+			return id, nil
+		}
+		return "", fmt.Errorf("HEAD does not point to a commit yet")
+	}
+	for _, ref := range []string{base, "refs/" + base, "refs/heads/" + base, "refs/tags/" + base} {
+		if strings.HasPrefix(ref, "refs/") {
+			if id := readRef(ref); isObjectID(id) {
+				return peelTag(id), nil
+			}
+		}
+	}
+	if len(base) >= 4 {
+		commits, _ := readCommits()
+		match := ""
+		for _, c := range commits {
+			if strings.HasPrefix(c.ID, base) && c.ID != match {
+				if match != "" {
+					return "", fmt.Errorf("ambiguous revision: %s", base)
+				}
+				match = c.ID
+			}
+		}
+		if match != "" {
+			return match, nil
+		}
+	}
+	return "", fmt.Errorf("unknown revision: %s", base)
+}
+
+func nthParent(id string, n int) (string, error) {
+	c, ok := loadCommit(id)
+	if !ok {
+		return "", fmt.Errorf("commit %s not found", shortID(id))
+	}
+	if n > len(c.Parents) {
+		return "", fmt.Errorf("commit %s has no parent %d", shortID(id), n)
+	}
+	return c.Parents[n-1], nil
+}
+
+func RevParse(spec string) {
+	id, err := resolveRevision(spec)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(id)
+}
+
+// resolveCommits expands a revision or an "A..B" range (commits reachable
+// from B but not from A) into commit IDs, newest first.
+func resolveCommits(spec string) ([]string, error) {
+	from, to, isRange := strings.Cut(spec, "..")
+	if !isRange {
+		id, err := resolveRevision(spec)
+		if err != nil {
+			return nil, err
+		}
+		return []string{id}, nil
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+	toID, err := resolveRevision(to)
+	if err != nil {
+		return nil, err
+	}
+	var exclude []string
+	if from != "" {
+		fromID, err := resolveRevision(from)
+		if err != nil {
+			return nil, err
+		}
+		exclude = append(exclude, fromID)
+	}
+	return revList([]string{toID}, exclude), nil
+}
+
+// revList returns the commits reachable from include but not from exclude,
+// newest first. The log only ever appends, so a commit always comes after
+// its parents and log order is a topological order.
+func revList(include, exclude []string) []string {
+	commits, _ := readCommits()
+	excluded := reachable(commits, exclude)
+	wanted := reachable(commits, include)
+	var ids []string
+	for i := len(commits) - 1; i >= 0; i-- {
+		id := commits[i].ID
+		if wanted[id] && !excluded[id] {
+			ids = append(ids, id)
+			delete(wanted, id)
+		}
+	}
+	return ids
+}
+
+func reachable(commits []commitEntry, starts []string) map[string]bool {
+	parents := make(map[string][]string, len(commits))
+	for _, c := range commits {
+		parents[c.ID] = c.Parents
+	}
+	seen := map[string]bool{}
+	stack := append([]string{}, starts...)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		stack = append(stack, parents[id]...)
+	}
+	return seen
+}
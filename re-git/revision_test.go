@@ -0,0 +1,92 @@
+package regit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveRevision(t *testing.T) {
+	testRepo(t)
+	c1 := commitFiles(t, "one", map[string]string{"f": "1\n"})
+	c2 := commitFiles(t, "two", map[string]string{"f": "2\n"})
+	captureOutput(t, func() { CreateBranch("topic") })
+	c3 := commitFiles(t, "three", map[string]string{"f": "3\n"})
+	merge := commitEntry{Parents: []string{c3, c1}, Date: "2024-01-01T00:00:00Z", Message: "merge", Files: commitTree(c3)}
+	if err := writeCommit(&merge); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateRef("refs/heads/merged", merge.ID, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateRef("refs/tags/v1", c1, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		spec string
+		want string // "" for an error
+	}{
+		{"HEAD", c3},
+		{"@", c3},
+		{"master", c3},
+		{"refs/heads/master", c3},
+		{"heads/master", c3},
+		{"topic", c2},
+		{"v1", c1},
+		{c2, c2},
+		{c2[:7], c2},
+		{c2[:4], c2},
+		{"HEAD~1", c2},
+		{"HEAD^", c2},
+		{"HEAD^^", c1},
+		{"HEAD~2", c1},
+		{"HEAD~0", c3},
+		{"topic~1", c1},
+		{"merged^1", c3},
+		{"merged^2", c1},
+		{"merged^2~0", c1},
+		{"master@{0}", c3},
+		{"master@{1}", c2},
+		{"HEAD~3", ""},
+		{"merged^3", ""},
+		{"master@{9}", ""},
+		{"nosuch", ""},
+		{c2[:3], ""},
+	}
+	for _, tt := range tests {
+		got, err := resolveRevision(tt.spec)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("resolveRevision(%q) = %s, want an error", tt.spec, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolveRevision(%q) = %s, %v; want %s", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestResolveCommits(t *testing.T) {
+	testRepo(t)
+	c1 := commitFiles(t, "one", map[string]string{"f": "1\n"})
+	c2 := commitFiles(t, "two", map[string]string{"f": "2\n"})
+	c3 := commitFiles(t, "three", map[string]string{"f": "3\n"})
+
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"HEAD~1", []string{c2}},
+		{"HEAD~2..HEAD", []string{c3, c2}},
+		{c1 + "..", []string{c3, c2}},
+		{"..HEAD~1", []string{c2, c1}},
+		{"HEAD..HEAD~1", nil},
+	}
+	for _, tt := range tests {
+		got, err := resolveCommits(tt.spec)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resolveCommits(%q) = %q, %v; want %q", tt.spec, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package regit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// The sequencer replays a list of commits (cherry-picking or reverting them)
+// one at a time, stopping on conflicts. It also drives rebase. Its state
+// lives in .git/sequencer so a later invocation can continue or abort:
+//
+//	todo       remaining steps, one "<action> <commit>" per line
+//	head       the commit HEAD was at before the sequence started
+//	opts       one option per line
+//	current    the step that stopped with conflicts or came out empty
+//	conflicts  the paths left with conflicts by that step
+//	head-name  for a rebase, the branch to move once all steps are done
+//	onto       for a rebase, the commit the steps are replayed onto
+//	amend      set while stopped at an "edit" step
+//
+// Besides pick and revert, an interactive rebase uses reword, edit, squash,
+// fixup, drop and exec steps; for exec the rest of the line is a command.
+//
+// The message for the stopped step is kept in .git/MERGE_MSG.
+const (
+	sequencerDir = ".git/sequencer"
+	mergeMsgFile = ".git/MERGE_MSG"
+)
+
+type sequencerOptions struct {
+	NoCommit     bool
+	RecordOrigin bool
+}
+
+func seqPath(name string) string {
+	return filepath.Join(sequencerDir, name)
+}
+
+func readSeqLines(name string) []string {
+	data, err := ioutil.ReadFile(seqPath(name))
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func writeSeqLines(name string, lines []string) error {
+	data := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		data += "\n"
+	}
+	return ioutil.WriteFile(seqPath(name), []byte(data), 0644)
+}
+
+func readSeqOptions() sequencerOptions {
+	var opts sequencerOptions
+	for _, line := range readSeqLines("opts") {
+		switch line {
+		case "no-commit":
+			opts.NoCommit = true
+		case "record-origin":
+			opts.RecordOrigin = true
+		}
+	}
+	return opts
+}
+
+func sequencerInProgress() bool {
+	_, err := os.Stat(sequencerDir)
+	return err == nil
+}
+
+// startSequencer records the steps and runs them.
+func startSequencer(action string, ids []string, opts sequencerOptions) {
+	if sequencerInProgress() {
+		fmt.Println("A cherry-pick, revert or rebase is already in progress; use --continue, --skip or --abort")
+		return
+	}
+	if headCommitID() == "" {
+		fmt.Println("No commits yet")
+		return
+	}
+	if !opts.NoCommit && len(readIndex()) > 0 {
+		fmt.Println("You have staged changes; commit or reset them first")
+		return
+	}
+	os.MkdirAll(sequencerDir, 0755)
+	var todo, optLines []string
+	for _, id := range ids {
+		todo = append(todo, action+" "+id)
+	}
+	if opts.NoCommit {
+		optLines = append(optLines, "no-commit")
+	}
+	if opts.RecordOrigin {
+		optLines = append(optLines, "record-origin")
+	}
+	writeSeqLines("todo", todo)
+	writeSeqLines("opts", optLines)
+	ioutil.WriteFile(seqPath("head"), []byte(headCommitID()+"\n"), 0644)
+	runSequencer()
+}
+
+// runSequencer works through the todo list until it is empty or a step
+// stops.
+func runSequencer() {
+	opts := readSeqOptions()
+	for {
+		todo := readSeqLines("todo")
+		if len(todo) == 0 {
+			finishSequencer()
+			return
+		}
+		action, id, _ := strings.Cut(todo[0], " ")
+		switch action {
+		case "drop":
+			writeSeqLines("todo", todo[1:])
+			continue
+		case "exec":
+			writeSeqLines("todo", todo[1:])
+			fmt.Println("Executing:", id)
+			if err := runExec(id); err != nil {
+				// Recorded so --continue and --skip know nothing was applied.
+				writeSeqLines("current", []string{todo[0]})
+				fmt.Printf("Execution failed: %s (%v)\n", id, err)
+				fmt.Println("Fix the problem and run --continue, or --abort to give up")
+				return
+			}
+			continue
+		}
+		conflicts, empty, err := applyStep(action, id, opts)
+		if err != nil {
+			fmt.Println("Error:", err)
+			fmt.Println("Fix the problem and run --continue, --skip to leave this commit out, or --abort to give up")
+			return
+		}
+		writeSeqLines("todo", todo[1:])
+		if empty && rebaseInProgress() {
+			// The upstream already has this change.
+			os.Remove(mergeMsgFile)
+			continue
+		}
+		if empty {
+			writeSeqLines("current", []string{todo[0]})
+			what := "cherry-pick"
+			if action == "revert" {
+				what = "revert"
+			}
+			fmt.Printf("The previous %s is now empty\n", what)
+			fmt.Println("Run --continue to commit it anyway, or --skip to leave it out")
+			return
+		}
+		if len(conflicts) > 0 {
+			writeSeqLines("current", []string{todo[0]})
+			writeSeqLines("conflicts", conflicts)
+			fmt.Printf("Could not %s %s %s\n", action, shortID(id), commitSubject(id))
+			for _, f := range conflicts {
+				fmt.Println("CONFLICT:", f)
+			}
+			fmt.Println("Resolve the conflicts, then run --continue (or --skip, or --abort)")
+			return
+		}
+		if action == "edit" && !opts.NoCommit {
+			stopForEdit()
+			return
+		}
+	}
+}
+
+func runExec(command string) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// stopForEdit pauses the sequence after an "edit" step so the commit can be
+// amended; changes staged before --continue are folded into it.
+func stopForEdit() {
+	head := headCommitID()
+	ioutil.WriteFile(seqPath("amend"), []byte(head+"\n"), 0644)
+	fmt.Printf("Stopped at %s %s\n", shortID(head), commitSubject(head))
+	fmt.Println("Amend the commit by staging changes, then run --continue")
+}
+
+// finishSequencer runs once the todo list is empty.
+func finishSequencer() {
+	if rebaseInProgress() {
+		finishRebase()
+	}
+	os.RemoveAll(sequencerDir)
+}
+
+// applyStep performs one step on top of the index and working tree as a
+// three-way merge: a revert applies commit->parent, every other step applies
+// parent->commit like a pick. It returns the paths left with conflicts; if there are
+// none the step has been committed (unless opts.NoCommit is set). A step
+// that would leave HEAD's tree unchanged is not committed but reported as
+// empty.
+func applyStep(action, id string, opts sequencerOptions) ([]string, bool, error) {
+	c, ok := loadCommit(id)
+	if !ok {
+		return nil, false, fmt.Errorf("commit %s not found", shortID(id))
+	}
+	if len(c.Parents) > 1 {
+		return nil, false, fmt.Errorf("commit %s is a merge", shortID(id))
+	}
+	parent := ""
+	if len(c.Parents) == 1 {
+		parent = c.Parents[0]
+	}
+	var base, theirs map[string]string
+	var message, theirsLabel string
+	switch action {
+	case "pick", "reword", "edit", "squash", "fixup":
+		base, theirs = commitTree(parent), c.Files
+		theirsLabel = shortID(id) + " (" + subject(c.Message) + ")"
+		message = c.Message
+		if opts.RecordOrigin {
+			message += "\n\n(cherry picked from commit " + id + ")"
+		}
+		prev, _ := loadCommit(headCommitID())
+		if action == "squash" {
+			message = prev.Message + "\n\n" + c.Message
+		} else if action == "fixup" {
+			message = prev.Message
+		}
+	case "revert":
+		base, theirs = c.Files, commitTree(parent)
+		message = fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s.", subject(c.Message), id)
+		theirsLabel = "parent of " + shortID(id)
+	default:
+		return nil, false, fmt.Errorf("unknown action %q", action)
+	}
+
+	head := headCommitID()
+	headTree := commitTree(head)
+	ours := stagedTree()
+	if !opts.NoCommit && len(readIndex()) > 0 {
+		return nil, false, fmt.Errorf("you have staged changes; commit or reset them first")
+	}
+	merged, conflicts := mergeTrees(base, ours, theirs, "HEAD", theirsLabel)
+	for _, f := range changedPaths(ours, merged, conflicts) {
+		if workingOid(f) != ours[f] {
+			return nil, false, fmt.Errorf("your local changes to %s would be overwritten", f)
+		}
+	}
+	if err := applyTreeChange(ours, merged); err != nil {
+		return nil, false, err
+	}
+	for f, data := range conflicts {
+		ioutil.WriteFile(f, data, 0644)
+	}
+	if len(conflicts) > 0 || opts.NoCommit {
+		ioutil.WriteFile(mergeMsgFile, []byte(message+"\n"), 0644)
+		return sortedKeys(conflicts), false, writeIndex(indexFor(headTree, merged))
+	}
+	// Squash and fixup fold into the commit before, so they may add nothing.
+	if action != "squash" && action != "fixup" && len(changedFiles(headTree, merged)) == 0 {
+		ioutil.WriteFile(mergeMsgFile, []byte(message+"\n"), 0644)
+		return nil, true, nil
+	}
+	newID, err := commitStep(action, c, merged, message)
+	if err != nil {
+		return nil, false, err
+	}
+	fmt.Printf("[%s] %s\n", shortID(newID), commitSubject(newID))
+	return nil, false, writeIndex(map[string]string{})
+}
+
+// commitStep commits the result of a step. A picked commit keeps its
+// original author and date; squash and fixup fold the result into the
+// commit before instead of adding a new one.
+func commitStep(action string, orig commitEntry, tree map[string]string, message string) (string, error) {
+	head, _ := loadCommit(headCommitID())
+	c := commitEntry{Parents: []string{head.ID}, Message: message, Files: tree}
+	var reason string
+	switch action {
+	case "revert":
+		reason = "revert: "
+	case "squash", "fixup":
+		c.Parents, c.Author, c.Date = head.Parents, head.Author, head.Date
+		reason = "rebase (" + action + "): "
+	default:
+		c.Author, c.Date = orig.Author, orig.Date
+		reason = "cherry-pick: "
+		if rebaseInProgress() {
+			reason = "rebase (" + action + "): "
+		}
+	}
+	if action == "reword" || action == "squash" {
+		edited, err := editMessage(message)
+		if err != nil {
+			return "", err
+		}
+		c.Message = edited
+	}
+	return createCommit(c, reason+subject(c.Message))
+}
+
+func changedPaths(from, to map[string]string, conflicts map[string][]byte) []string {
+	var paths []string
+	for f, oid := range to {
+		if from[f] != oid || conflicts[f] != nil {
+			paths = append(paths, f)
+		}
+	}
+	for f := range from {
+		if _, ok := to[f]; !ok {
+			paths = append(paths, f)
+		}
+	}
+	return paths
+}
+
+// SequencerContinue stages the resolved files of a stopped step, commits it
+// and carries on with the rest of the todo list.
+func SequencerContinue() {
+	if !sequencerInProgress() {
+		fmt.Println("No cherry-pick, revert or rebase in progress")
+		return
+	}
+	opts := readSeqOptions()
+	if amend := readSeqLines("amend"); len(amend) > 0 {
+		if len(readIndex()) > 0 {
+			c, _ := loadCommit(headCommitID())
+			if _, err := amendHead(stagedTree(), c.Message, "rebase (amend): "+subject(c.Message)); err != nil {
+				fmt.Println("Error amending commit:", err)
+				return
+			}
+			writeIndex(map[string]string{})
+		}
+		os.Remove(seqPath("amend"))
+	}
+	current := readSeqLines("current")
+	if len(current) > 0 && strings.HasPrefix(current[0], "exec ") {
+		// A failed exec left nothing to commit.
+		clearStoppedStep()
+		current = nil
+	}
+	if len(current) > 0 {
+		index := readIndex()
+		headTree := commitTree(headCommitID())
+		for _, f := range readSeqLines("conflicts") {
+			data, err := ioutil.ReadFile(f)
+			switch {
+			case err != nil && headTree[f] != "":
+				index[f] = nullID
+			case err != nil:
+				delete(index, f)
+			case strings.Contains(string(data), "<<<<<<< "):
+				fmt.Println("Conflicts remain in", f)
+				return
+			default:
+				oid, err := writeObject(data)
+				if err != nil {
+					fmt.Println("Error staging", f)
+					return
+				}
+				index[f] = oid
+			}
+		}
+		if err := writeIndex(index); err != nil {
+			fmt.Println("Error writing index")
+			return
+		}
+		if !opts.NoCommit {
+			data, _ := ioutil.ReadFile(mergeMsgFile)
+			message := strings.TrimSpace(string(data))
+			action, origID, _ := strings.Cut(current[0], " ")
+			orig, _ := loadCommit(origID)
+			id, err := commitStep(action, orig, stagedTree(), message)
+			if err != nil {
+				fmt.Println("Error writing commit:", err)
+				return
+			}
+			writeIndex(map[string]string{})
+			fmt.Printf("[%s] %s\n", shortID(id), commitSubject(id))
+		}
+		clearStoppedStep()
+		if action, _, _ := strings.Cut(current[0], " "); action == "edit" && !opts.NoCommit {
+			stopForEdit()
+			return
+		}
+	}
+	runSequencer()
+}
+
+func clearStoppedStep() {
+	os.Remove(seqPath("current"))
+	os.Remove(seqPath("conflicts"))
+	os.Remove(mergeMsgFile)
+}
+
+// SequencerSkip throws away the changes of the stopped step, leaving other
+// local edits alone, and carries on with the next one.
+func SequencerSkip() {
+	if !sequencerInProgress() {
+		fmt.Println("No cherry-pick, revert or rebase in progress")
+		return
+	}
+	current := readSeqLines("current")
+	if len(current) > 0 && strings.HasPrefix(current[0], "exec ") {
+		// The command is already off the todo list; there is nothing to undo.
+		fmt.Println("Skipped", current[0])
+		clearStoppedStep()
+		runSequencer()
+		return
+	}
+	if len(current) > 0 {
+		if err := resetMerge(headCommitID(), readSeqLines("conflicts"), "sequencer: skip"); err != nil {
+			fmt.Println("Error resetting:", err)
+			return
+		}
+	} else if todo := readSeqLines("todo"); len(todo) > 0 {
+		// The step failed before changing anything; just drop it.
+		current = todo[:1]
+		writeSeqLines("todo", todo[1:])
+	}
+	if len(current) > 0 {
+		_, id, _ := strings.Cut(current[0], " ")
+		fmt.Printf("Skipped %s %s\n", shortID(id), commitSubject(id))
+	}
+	clearStoppedStep()
+	runSequencer()
+}
+
+// SequencerAbort puts HEAD and the index back to where they were before the
+// sequence started, and the working files the sequence changed with them.
+func SequencerAbort() {
+	if !sequencerInProgress() {
+		fmt.Println("No cherry-pick, revert or rebase in progress")
+		return
+	}
+	head := readSeqLines("head")
+	if len(head) == 0 {
+		fmt.Println("Sequencer state is damaged; removing it")
+		os.RemoveAll(sequencerDir)
+		return
+	}
+	if err := resetMerge(head[0], readSeqLines("conflicts"), "sequencer: abort"); err != nil {
+		fmt.Println("Error resetting:", err)
+		return
+	}
+	if headName := readSeqLines("head-name"); len(headName) > 0 {
+		setHead(headName[0], "rebase (abort): returning to "+headName[0])
+	}
+	os.RemoveAll(sequencerDir)
+	os.Remove(mergeMsgFile)
+	fmt.Printf("HEAD is now at %s %s\n", shortID(head[0]), commitSubject(head[0]))
+}
@@ -0,0 +1,70 @@
+package regit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ShortlogOptions selects the commits Shortlog summarises, as for log, and
+// how: Summary prints only the counts, Numbered sorts authors by them
+// rather than by name and Email adds each author's email.
+type ShortlogOptions struct {
+	Revs         []string
+	All          bool
+	Since, Until string
+	Paths        []string
+	Summary      bool
+	Numbered     bool
+	Email        bool
+}
+
+// commitAuthor is who wrote c, as the mailmap knows them. Commits whose
+// author is not in the form "Name <email>" are credited to the whole
+// string.
+func commitAuthor(c commitEntry) identity {
+	author, err := parseIdentity(c.Author)
+	if err != nil {
+		return identity{Name: c.Author}
+	}
+	return canonicalIdentity(author)
+}
+
+// Shortlog prints the subjects of the selected commits grouped by author,
+// oldest first within each group.
+func Shortlog(opts ShortlogOptions) {
+	commits, err := queryLog(LogOptions{
+		Revs: opts.Revs, All: opts.All, Since: opts.Since, Until: opts.Until,
+		Paths: opts.Paths, Reverse: true,
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	subjects := map[string][]string{}
+	for _, c := range commits {
+		author := commitAuthor(c)
+		name := author.Name
+		if opts.Email {
+			name = author.String()
+		}
+		subjects[name] = append(subjects[name], subject(c.Message))
+	}
+	names := sortedKeys(subjects)
+	if opts.Numbered {
+		sort.SliceStable(names, func(i, j int) bool {
+			return len(subjects[names[i]]) > len(subjects[names[j]])
+		})
+	}
+	for _, name := range names {
+		if opts.Summary {
+			fmt.Printf("%6d\t%s\n", len(subjects[name]), name)
+			continue
+		}
+		fmt.Printf("%s (%d):\n", name, len(subjects[name]))
+		for _, s := range subjects[name] {
+			fmt.Println("      " + strings.TrimSpace(s))
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,74 @@
+package regit
+
+import (
+	"testing"
+)
+
+func TestShortlog(t *testing.T) {
+	logHistory(t)
+	tests := []struct {
+		name string
+		opts ShortlogOptions
+		want string
+	}{
+		{
+			name: "grouped by author",
+			want: "Alice (2):\n      init\n      add feature\n\n" +
+				"Bob (1):\n      Fix bug\n\n" +
+				"Carol (1):\n      docs: readme\n\n",
+		},
+		{
+			name: "summary",
+			opts: ShortlogOptions{Summary: true},
+			want: "     2\tAlice\n     1\tBob\n     1\tCarol\n",
+		},
+		{
+			name: "numbered keeps ties in name order",
+			opts: ShortlogOptions{Summary: true, Numbered: true, Revs: []string{"HEAD~1"}},
+			want: "     2\tAlice\n     1\tBob\n",
+		},
+		{
+			name: "email",
+			opts: ShortlogOptions{Summary: true, Email: true, Paths: []string{"b", "docs"}},
+			want: "     1\tBob <bob@example.com>\n     1\tCarol <carol@example.com>\n",
+		},
+		{
+			name: "since",
+			opts: ShortlogOptions{Since: "2024-02-15"},
+			want: "Alice (1):\n      add feature\n\nCarol (1):\n      docs: readme\n\n",
+		},
+		{
+			name: "nothing selected",
+			opts: ShortlogOptions{Paths: []string{"missing"}},
+		},
+		{
+			name: "bad revision",
+			opts: ShortlogOptions{Revs: []string{"nope"}},
+			want: "unknown revision: nope\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureOutput(t, func() { Shortlog(tt.opts) })
+			if got != tt.want {
+				t.Errorf("Shortlog printed:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitAuthor(t *testing.T) {
+	testRepo(t)
+	tests := []struct {
+		author string
+		want   identity
+	}{
+		{"Alice <alice@example.com>", identity{Name: "Alice", Email: "alice@example.com"}},
+		{"just a name", identity{Name: "just a name"}},
+	}
+	for _, tt := range tests {
+		if got := commitAuthor(commitEntry{Author: tt.author}); got != tt.want {
+			t.Errorf("commitAuthor(%q) = %+v, want %+v", tt.author, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package regit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Commits and tags are signed with an ed25519 key whose seed is kept,
+// base64 encoded, in the file named by user.signingKey. The signature is
+// stored in the object as "ed25519 <public key> <signature>" and covers the
+// object's text without it. A signature only counts as good when its key
+// is listed in the trusted keys file (signing.trustedKeys, by default
+// .git/trusted_keys), one "<public key> Name <email>" per line.
+const defaultTrustedKeysFile = ".git/trusted_keys"
+
+const signatureScheme = "ed25519"
+
+func loadSigningKey() (ed25519.PrivateKey, error) {
+	path := configPath("user.signingKey")
+	if path == "" {
+		return nil, fmt.Errorf("no signing key configured (set user.signingKey)")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read signing key: %v", err)
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%s is not an ed25519 signing key", path)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// signPayload signs payload with the configured key and returns the value
+// to store in the object's signature header.
+func signPayload(payload string) (string, error) {
+	key, err := loadSigningKey()
+	if err != nil {
+		return "", err
+	}
+	pub := key.Public().(ed25519.PublicKey)
+	sig := ed25519.Sign(key, []byte(payload))
+	return fmt.Sprintf("%s %s %s", signatureScheme, base64.StdEncoding.EncodeToString(pub), base64.StdEncoding.EncodeToString(sig)), nil
+}
+
+func keyFingerprint(pub string) string {
+	sum := sha256.Sum256([]byte(pub))
+	return hex.EncodeToString(sum[:8])
+}
+
+func trustedKeys() map[string]string {
+	path := configPath("signing.trustedKeys")
+	if path == "" {
+		path = defaultTrustedKeysFile
+	}
+	keys := map[string]string{}
+	data, _ := ioutil.ReadFile(path)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, identity, _ := strings.Cut(line, " ")
+		keys[key] = strings.TrimSpace(identity)
+	}
+	return keys
+}
+
+// signatureStatus is the outcome of checking an object's signature.
+type signatureStatus struct {
+	Signed   bool
+	Valid    bool
+	Key      string
+	Identity string // set when the key is trusted
+}
+
+func (s signatureStatus) Good() bool {
+	return s.Valid && s.Identity != ""
+}
+
+func (s signatureStatus) String() string {
+	switch {
+	case !s.Signed:
+		return "No signature"
+	case !s.Valid:
+		return "BAD signature with key " + keyFingerprint(s.Key)
+	case s.Identity == "":
+		return "Good signature with untrusted key " + keyFingerprint(s.Key)
+	}
+	return fmt.Sprintf("Good signature from %s (key %s)", s.Identity, keyFingerprint(s.Key))
+}
+
+func checkSignature(signature, payload string) signatureStatus {
+	if signature == "" {
+		return signatureStatus{}
+	}
+	status := signatureStatus{Signed: true}
+	fields := strings.Fields(signature)
+	if len(fields) != 3 || fields[0] != signatureScheme {
+		return status
+	}
+	status.Key = fields[1]
+	pub, err1 := base64.StdEncoding.DecodeString(fields[1])
+	sig, err2 := base64.StdEncoding.DecodeString(fields[2])
+	if err1 != nil || err2 != nil || len(pub) != ed25519.PublicKeySize {
+		return status
+	}
+	status.Valid = ed25519.Verify(ed25519.PublicKey(pub), []byte(payload), sig)
+	if status.Valid {
+		status.Identity = trustedKeys()[status.Key]
+	}
+	return status
+}
+
+// signCommit fills in c.Signature over the rest of the commit.
+func signCommit(c *commitEntry) error {
+	c.Signature = ""
+	sig, err := signPayload(commitBody(c))
+	if err != nil {
+		return err
+	}
+	c.Signature = sig
+	return nil
+}
+
+func commitSignature(c commitEntry) signatureStatus {
+	signature := c.Signature
+	c.Signature = ""
+	return checkSignature(signature, commitBody(&c))
+}
+
+func tagSignature(t tagObject) signatureStatus {
+	signature := t.Signature
+	t.Signature = ""
+	return checkSignature(signature, formatTag(t))
+}
+
+// VerifyCommit checks the signature of the commit rev names and reports
+// whether it is good and from a trusted key.
+func VerifyCommit(rev string) bool {
+	id, err := resolveRevision(rev)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	c, _ := loadCommit(id)
+	status := commitSignature(c)
+	fmt.Println(status)
+	return status.Good()
+}
+
+// VerifyTag checks the signature of an annotated tag.
+func VerifyTag(name string) bool {
+	t, ok := readTag(readRef("refs/tags/" + name))
+	if !ok {
+		fmt.Println(name, "is not an annotated tag")
+		return false
+	}
+	status := tagSignature(t)
+	fmt.Println(status)
+	return status.Good()
+}
+
+// GenerateSigningKey writes a new ed25519 key to path and its public half
+// to path.pub.
+func GenerateSigningKey(path string) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		fmt.Println("Error generating key:", err)
+		return
+	}
+	path = expandHome(path)
+	if err := ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key.Seed())+"\n"), 0600); err != nil {
+		fmt.Println("Error writing key:", err)
+		return
+	}
+	encoded := base64.StdEncoding.EncodeToString(pub)
+	if err := ioutil.WriteFile(path+".pub", []byte(encoded+"\n"), 0644); err != nil {
+		fmt.Println("Error writing key:", err)
+		return
+	}
+	fmt.Println("Wrote signing key to", path)
+	fmt.Println("Public key:", encoded)
+}
@@ -0,0 +1,191 @@
+package regit
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testSigningKey writes a fixed key (seed of all seed bytes) to a temp file,
+// points user.signingKey at it and returns its encoded public half.
+func testSigningKey(t *testing.T, seed byte) string {
+	t.Helper()
+	seedBytes := make([]byte, ed25519.SeedSize)
+	for i := range seedBytes {
+		seedBytes[i] = seed
+	}
+	path := filepath.Join(t.TempDir(), "key")
+	if err := ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(seedBytes)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	captureOutput(t, func() { ConfigSet("user.signingKey", path, false, ConfigOptions{}) })
+	pub := ed25519.NewKeyFromSeed(seedBytes).Public().(ed25519.PublicKey)
+	return base64.StdEncoding.EncodeToString(pub)
+}
+
+func trustKey(t *testing.T, pub, identity string) {
+	t.Helper()
+	data := "# trusted keys\n\n" + pub + " " + identity + "\n"
+	if err := ioutil.WriteFile(defaultTrustedKeysFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckSignature(t *testing.T) {
+	testRepo(t)
+	pub := testSigningKey(t, 1)
+	trustKey(t, pub, "Test User <test@example.com>")
+	sig, err := signPayload("payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(sig)
+	other := testSigningKey(t, 2)
+	untrusted, err := signPayload("payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		payload   string
+		want      string
+		wantGood  bool
+	}{
+		{name: "unsigned", payload: "payload", want: "No signature"},
+		{
+			name:      "trusted",
+			signature: sig,
+			payload:   "payload",
+			want:      "Good signature from Test User <test@example.com> (key " + keyFingerprint(pub) + ")",
+			wantGood:  true,
+		},
+		{
+			name:      "untrusted",
+			signature: untrusted,
+			payload:   "payload",
+			want:      "Good signature with untrusted key " + keyFingerprint(other),
+		},
+		{name: "changed payload", signature: sig, payload: "payload!", want: "BAD signature with key " + keyFingerprint(pub)},
+		{name: "signature from another key", signature: "ed25519 " + pub + " " + strings.Fields(untrusted)[2], payload: "payload", want: "BAD signature with key " + keyFingerprint(pub)},
+		{name: "unknown scheme", signature: "rsa " + fields[1] + " " + fields[2], payload: "payload", want: "BAD signature with key " + keyFingerprint("")},
+		{name: "bad base64", signature: "ed25519 " + fields[1] + " !!!", payload: "payload", want: "BAD signature with key " + keyFingerprint(pub)},
+		{name: "short key", signature: "ed25519 AAAA " + fields[2], payload: "payload", want: "BAD signature with key " + keyFingerprint("AAAA")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := checkSignature(tt.signature, tt.payload)
+			if got := status.String(); got != tt.want {
+				t.Errorf("status is %q, want %q", got, tt.want)
+			}
+			if status.Good() != tt.wantGood {
+				t.Errorf("Good() = %v, want %v", status.Good(), tt.wantGood)
+			}
+		})
+	}
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	testRepo(t)
+	if _, err := signPayload("x"); err == nil || !strings.Contains(err.Error(), "no signing key configured") {
+		t.Errorf("signing without a key gave %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "bad")
+	ioutil.WriteFile(path, []byte("bm90IGEga2V5\n"), 0600)
+	captureOutput(t, func() { ConfigSet("user.signingKey", path, false, ConfigOptions{}) })
+	if _, err := signPayload("x"); err == nil || !strings.Contains(err.Error(), "is not an ed25519 signing key") {
+		t.Errorf("signing with a bad key gave %v", err)
+	}
+}
+
+func TestSignedCommit(t *testing.T) {
+	testRepo(t)
+	pub := testSigningKey(t, 1)
+	writeFiles(t, map[string]string{"f": "1\n"})
+	captureOutput(t, func() {
+		Add("f")
+		Commit("signed", nil, CommitOptions{Sign: true})
+	})
+	c, ok := loadCommit(headCommitID())
+	if !ok || !strings.HasPrefix(c.Signature, "ed25519 "+pub+" ") {
+		t.Fatalf("commit signature is %q", c.Signature)
+	}
+
+	if VerifyCommit("HEAD") {
+		t.Error("a signature from an untrusted key verified")
+	}
+	trustKey(t, pub, "Test User <test@example.com>")
+	var good bool
+	out := captureOutput(t, func() { good = VerifyCommit("HEAD") })
+	if !good || !strings.HasPrefix(out, "Good signature from Test User") {
+		t.Errorf("verify-commit printed %q, good = %v", out, good)
+	}
+	if out := captureOutput(t, func() { Log(LogOptions{ShowSignature: true}) }); !strings.Contains(out, "commit "+c.ID+"\nGood signature from Test User") {
+		t.Errorf("log --show-signature printed\n%s", out)
+	}
+
+	// The signature no longer matches once the commit is altered.
+	c.Message = "forged"
+	if err := writeCommit(&c); err != nil {
+		t.Fatal(err)
+	}
+	if out := captureOutput(t, func() { good = VerifyCommit(c.ID) }); good || !strings.HasPrefix(out, "BAD signature") {
+		t.Errorf("verify-commit on an altered commit printed %q", out)
+	}
+
+	commitFiles(t, "unsigned", map[string]string{"f": "2\n"})
+	if out := captureOutput(t, func() { good = VerifyCommit("HEAD") }); good || out != "No signature\n" {
+		t.Errorf("verify-commit on an unsigned commit printed %q", out)
+	}
+}
+
+func TestSignedTag(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "base", map[string]string{"f": "1\n"})
+	pub := testSigningKey(t, 3)
+	trustKey(t, pub, "Releaser <rel@example.com>")
+	captureOutput(t, func() {
+		Tag("v1", "", TagOptions{Message: "Release 1", Sign: true})
+		Tag("v2", "", TagOptions{Message: "Release 2"})
+		Tag("light", "", TagOptions{})
+	})
+
+	tests := []struct {
+		tag      string
+		want     string
+		wantGood bool
+	}{
+		{"v1", "Good signature from Releaser <rel@example.com> (key " + keyFingerprint(pub) + ")\n", true},
+		{"v2", "No signature\n", false},
+		{"light", "light is not an annotated tag\n", false},
+	}
+	for _, tt := range tests {
+		var good bool
+		out := captureOutput(t, func() { good = VerifyTag(tt.tag) })
+		if out != tt.want || good != tt.wantGood {
+			t.Errorf("verify-tag %s printed %q (good %v), want %q (good %v)", tt.tag, out, good, tt.want, tt.wantGood)
+		}
+	}
+}
+
+func TestGenerateSigningKey(t *testing.T) {
+	testRepo(t)
+	path := filepath.Join(t.TempDir(), "key")
+	captureOutput(t, func() { GenerateSigningKey(path) })
+	captureOutput(t, func() { ConfigSet("user.signingKey", path, false, ConfigOptions{}) })
+	pub, err := ioutil.ReadFile(path + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := signPayload("payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := checkSignature(sig, "payload"); !status.Valid || status.Key != strings.TrimSpace(string(pub)) {
+		t.Errorf("signature with the generated key is %v", status)
+	}
+}
@@ -0,0 +1,383 @@
+package regit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A stash entry is a commit recording the working tree, whose first parent
+// is the commit HEAD was at and whose second parent records the index. When
+// untracked files are stashed too, a third parent holds them. The entries
+// form a stack kept in the reflog of refs/stash, so stash@{0} is the newest.
+const stashRef = "refs/stash"
+
+// stashIndex parses "stash@{n}" (or a bare n) into n; "" is the newest entry.
+func stashIndex(rev string) (int, error) {
+	if rev == "" {
+		return 0, nil
+	}
+	s := strings.TrimSuffix(strings.TrimPrefix(rev, "stash@{"), "}")
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s is not a stash reference", rev)
+	}
+	return n, nil
+}
+
+// loadStash returns the stash commit for rev, checking it looks like one.
+func loadStash(rev string) (string, commitEntry, error) {
+	n, err := stashIndex(rev)
+	if err != nil {
+		return "", commitEntry{}, err
+	}
+	name := fmt.Sprintf("stash@{%d}", n)
+	id, err := reflogLookup(stashRef, n)
+	if err != nil {
+		return name, commitEntry{}, fmt.Errorf("%s does not exist", name)
+	}
+	c, ok := loadCommit(id)
+	if !ok || len(c.Parents) < 2 {
+		return name, commitEntry{}, fmt.Errorf("%s is not a stash commit", name)
+	}
+	return name, c, nil
+}
+
+// pathMatcher reports whether a file falls under one of paths; no paths
+// matches everything.
+func pathMatcher(paths []string) func(string) bool {
+	return func(f string) bool {
+		if len(paths) == 0 {
+			return true
+		}
+		for _, p := range paths {
+			p = filepath.ToSlash(filepath.Clean(p))
+			if p == "." || f == p || strings.HasPrefix(f, p+"/") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// untrackedFiles lists working files that are neither in HEAD nor staged,
+// leaving out those the ignore rules exclude unless withIgnored is set.
+func untrackedFiles(tracked map[string]string, withIgnored bool) []string {
+	var files []string
+	add := func(file string) {
+		if _, ok := tracked[file]; !ok {
+			files = append(files, file)
+		}
+	}
+	if !withIgnored {
+		walkUnignored(add)
+		return files
+	}
+	filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path == repoDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		add(filepath.ToSlash(path))
+		return nil
+	})
+	return files
+}
+
+func StashPush(message string, paths []string, includeUntracked, includeIgnored bool) {
+	head := headCommitID()
+	if head == "" {
+		fmt.Println("You do not have the initial commit yet")
+		return
+	}
+	selected := pathMatcher(paths)
+	headTree := commitTree(head)
+	staged := stagedTree()
+
+	indexTree := commitTree(head)
+	for _, f := range changedFiles(headTree, staged) {
+		if !selected(f) {
+			continue
+		}
+		if oid, ok := staged[f]; ok {
+			indexTree[f] = oid
+		} else {
+			delete(indexTree, f)
+		}
+	}
+	workTree := map[string]string{}
+	for f, oid := range indexTree {
+		workTree[f] = oid
+	}
+	tracked := map[string]string{}
+	for _, tree := range []map[string]string{headTree, staged} {
+		for f, oid := range tree {
+			tracked[f] = oid
+		}
+	}
+	for f := range tracked {
+		if !selected(f) {
+			continue
+		}
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			delete(workTree, f)
+			continue
+		}
+		oid, err := writeObject(data)
+		if err != nil {
+			fmt.Println("Error saving", f+":", err)
+			return
+		}
+		workTree[f] = oid
+	}
+	untracked := map[string]string{}
+	if includeUntracked {
+		for _, f := range untrackedFiles(tracked, includeIgnored) {
+			if !selected(f) {
+				continue
+			}
+			data, err := ioutil.ReadFile(f)
+			if err != nil {
+				continue
+			}
+			oid, err := writeObject(data)
+			if err != nil {
+				fmt.Println("Error saving", f+":", err)
+				return
+			}
+			untracked[f] = oid
+		}
+	}
+	if len(changedFiles(headTree, indexTree)) == 0 && len(changedFiles(headTree, workTree)) == 0 && len(untracked) == 0 {
+		fmt.Println("No local changes to save")
+		return
+	}
+
+	branch := currentBranch()
+	if branch == "" {
+		branch = "(no branch)"
+	}
+	onto := shortID(head) + " " + commitSubject(head)
+	if message == "" {
+		message = "WIP on " + branch + ": " + onto
+	} else {
+		message = "On " + branch + ": " + message
+	}
+	stash := commitEntry{Parents: []string{head}, Message: message, Files: workTree}
+	if err := fillIdentity(&stash); err != nil {
+		fmt.Println(err)
+		return
+	}
+	indexCommit := stash
+	indexCommit.Message, indexCommit.Files = "index on "+branch+": "+onto, indexTree
+	if err := writeCommit(&indexCommit); err != nil {
+		fmt.Println("Error saving stash:", err)
+		return
+	}
+	stash.Parents = append(stash.Parents, indexCommit.ID)
+	if len(untracked) > 0 {
+		untrackedCommit := stash
+		untrackedCommit.Parents = nil
+		untrackedCommit.Message, untrackedCommit.Files = "untracked files on "+branch+": "+onto, untracked
+		if err := writeCommit(&untrackedCommit); err != nil {
+			fmt.Println("Error saving stash:", err)
+			return
+		}
+		stash.Parents = append(stash.Parents, untrackedCommit.ID)
+	}
+	if err := writeCommit(&stash); err != nil {
+		fmt.Println("Error saving stash:", err)
+		return
+	}
+	if err := updateRef(stashRef, stash.ID, message); err != nil {
+		fmt.Println("Error saving stash:", err)
+		return
+	}
+
+	// Put the stashed paths back the way HEAD has them.
+	index := readIndex()
+	from, to := map[string]string{}, map[string]string{}
+	for f := range tracked {
+		if !selected(f) {
+			continue
+		}
+		delete(index, f)
+		if oid, ok := workTree[f]; ok {
+			from[f] = oid
+		}
+		if oid, ok := headTree[f]; ok {
+			to[f] = oid
+		}
+	}
+	if err := checkoutTree(from, to); err != nil {
+		fmt.Println("Error resetting working tree:", err)
+		return
+	}
+	for f := range untracked {
+		os.Remove(f)
+	}
+	writeIndex(index)
+	fmt.Println("Saved working directory and index state", message)
+}
+
+func StashList() {
+	entries := readReflog(stashRef)
+	for n := len(entries) - 1; n >= 0; n-- {
+		fmt.Printf("stash@{%d}: %s\n", len(entries)-1-n, entries[n].Reason)
+	}
+}
+
+// StashShow summarises the changes a stash entry records against the commit
+// it was made on, or prints them as a patch.
+func StashShow(rev string, patch bool) {
+	_, c, err := loadStash(rev)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	base := commitTree(c.Parents[0])
+	if patch {
+		fmt.Print(treeDiff(base, c.Files))
+		return
+	}
+	for _, f := range changedFiles(base, c.Files) {
+		added, removed := diffStat(base[f], c.Files[f])
+		fmt.Printf(" %s | %d %s%s\n", f, added+removed, strings.Repeat("+", added), strings.Repeat("-", removed))
+	}
+}
+
+// StashApply merges a stash entry into the working tree. With restoreIndex
+// the changes that were staged when it was saved are staged again. It
+// reports whether the entry applied without conflicts.
+func StashApply(rev string, restoreIndex bool) bool {
+	name, c, err := loadStash(rev)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	base := commitTree(c.Parents[0])
+	headTree := commitTree(headCommitID())
+	ours := stagedTree()
+	merged, conflicts := mergeTrees(base, ours, c.Files, "Updated upstream", "Stashed changes")
+	if restoreIndex && len(conflicts) > 0 {
+		fmt.Println("Conflicts in index; try without --index")
+		return false
+	}
+	for _, f := range changedPaths(ours, merged, conflicts) {
+		if workingOid(f) != ours[f] {
+			fmt.Printf("Your local changes to %s would be overwritten\n", f)
+			return false
+		}
+	}
+	untracked := map[string]string{}
+	if len(c.Parents) > 2 {
+		untracked = commitTree(c.Parents[2])
+		for f, oid := range untracked {
+			if current := workingOid(f); current != "" && current != oid {
+				fmt.Println(f, "already exists, no checkout")
+				return false
+			}
+		}
+	}
+
+	if err := applyTreeChange(ours, merged); err != nil {
+		fmt.Println("Error applying stash:", err)
+		return false
+	}
+	for f, data := range conflicts {
+		ioutil.WriteFile(f, data, 0644)
+	}
+	if err := checkoutTree(nil, untracked); err != nil {
+		fmt.Println("Error restoring untracked files:", err)
+		return false
+	}
+
+	index := readIndex()
+	if restoreIndex {
+		indexTree := commitTree(c.Parents[1])
+		for _, f := range changedFiles(base, indexTree) {
+			oid, ok := indexTree[f]
+			switch {
+			case !ok && headTree[f] != "":
+				index[f] = nullID
+			case !ok || oid == headTree[f]:
+				delete(index, f)
+			default:
+				index[f] = oid
+			}
+		}
+	}
+	for f, oid := range c.Files {
+		// Files the stash adds are staged so they stay tracked.
+		if _, inBase := base[f]; !inBase && conflicts[f] == nil && oid != headTree[f] {
+			if _, tracked := ours[f]; !tracked {
+				index[f] = oid
+			}
+		}
+	}
+	writeIndex(index)
+
+	if len(conflicts) > 0 {
+		for _, f := range sortedKeys(conflicts) {
+			fmt.Println("CONFLICT (content): Merge conflict in", f)
+		}
+		fmt.Println("The stash entry is kept in case you need it again.")
+		return false
+	}
+	fmt.Println("Applied", name)
+	return true
+}
+
+// StashPop applies a stash entry and drops it if it applied cleanly.
+func StashPop(rev string, restoreIndex bool) {
+	if StashApply(rev, restoreIndex) {
+		StashDrop(rev)
+	}
+}
+
+// StashDrop removes one entry from the stash stack, keeping refs/stash at
+// the newest remaining entry.
+func StashDrop(rev string) {
+	n, err := stashIndex(rev)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	entries := readReflog(stashRef)
+	if n >= len(entries) {
+		fmt.Printf("stash@{%d} does not exist\n", n)
+		return
+	}
+	i := len(entries) - 1 - n
+	dropped := entries[i]
+	entries = append(entries[:i], entries[i+1:]...)
+	if len(entries) == 0 {
+		StashClear()
+	} else {
+		if err := writeReflog(stashRef, entries); err != nil {
+			fmt.Println("Error dropping stash:", err)
+			return
+		}
+		top := entries[len(entries)-1].New
+		if err := ioutil.WriteFile(filepath.Join(repoDir, stashRef), []byte(top+"\n"), 0644); err != nil {
+			fmt.Println("Error dropping stash:", err)
+			return
+		}
+	}
+	fmt.Printf("Dropped stash@{%d} (%s)\n", n, shortID(dropped.New))
+}
+
+// StashClear drops every stash entry.
+func StashClear() {
+	os.Remove(filepath.Join(repoDir, stashRef))
+	os.Remove(reflogPath(stashRef))
+}
@@ -0,0 +1,263 @@
+package regit
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStashIndex(t *testing.T) {
+	tests := []struct {
+		rev     string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"3", 3, false},
+		{"stash@{0}", 0, false},
+		{"stash@{12}", 12, false},
+		{"stash@{-1}", 0, true},
+		{"-1", 0, true},
+		{"stash@{x}", 0, true},
+		{"master", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := stashIndex(tt.rev)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("stashIndex(%q) error = %v, want error %v", tt.rev, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("stashIndex(%q) = %d, want %d", tt.rev, got, tt.want)
+		}
+	}
+}
+
+func TestStashPushUntracked(t *testing.T) {
+	tests := []struct {
+		name                      string
+		untracked, ignored        bool
+		wantRemoved, wantRemained []string
+	}{
+		{
+			name:         "tracked changes only",
+			wantRemained: []string{"new.txt", "debug.log", "build/out"},
+		},
+		{
+			name:         "untracked",
+			untracked:    true,
+			wantRemoved:  []string{"new.txt"},
+			wantRemained: []string{"debug.log", "build/out"},
+		},
+		{
+			name:        "all",
+			untracked:   true,
+			ignored:     true,
+			wantRemoved: []string{"new.txt", "debug.log", "build/out"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			commitFiles(t, "base", map[string]string{"f": "a\n", ".gitignore": "*.log\nbuild/\n"})
+			writeFiles(t, map[string]string{"f": "b\n", "new.txt": "new\n", "debug.log": "log\n", "build/out": "out\n"})
+
+			captureOutput(t, func() { StashPush("", nil, tt.untracked, tt.ignored) })
+			if data, _ := ioutil.ReadFile("f"); string(data) != "a\n" {
+				t.Errorf("f is %q after push, want it reset", data)
+			}
+			for _, f := range tt.wantRemoved {
+				if _, err := os.Stat(f); err == nil {
+					t.Errorf("%s was not stashed", f)
+				}
+			}
+			for _, f := range tt.wantRemained {
+				if _, err := os.Stat(f); err != nil {
+					t.Errorf("%s was removed", f)
+				}
+			}
+
+			captureOutput(t, func() { StashPop("", false) })
+			want := map[string]string{"f": "b\n", "new.txt": "new\n", "debug.log": "log\n", "build/out": "out\n"}
+			got := map[string]string{}
+			for f := range want {
+				data, _ := ioutil.ReadFile(f)
+				got[f] = string(data)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("after pop the files are %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// stashThree makes three stash entries changing f to "one", "two" and
+// "three" in turn, so stash@{0} holds "three".
+func stashThree(t *testing.T) {
+	t.Helper()
+	testRepo(t)
+	commitFiles(t, "base", map[string]string{"f": "base\n"})
+	for _, msg := range []string{"one", "two", "three"} {
+		writeFiles(t, map[string]string{"f": msg + "\n"})
+		captureOutput(t, func() { StashPush(msg, nil, false, false) })
+	}
+}
+
+func TestStashList(t *testing.T) {
+	stashThree(t)
+	want := "stash@{0}: On master: three\nstash@{1}: On master: two\nstash@{2}: On master: one\n"
+	if got := captureOutput(t, StashList); got != want {
+		t.Errorf("stash list is\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStashApplyAndDrop(t *testing.T) {
+	tests := []struct {
+		name     string
+		run      func()
+		wantF    string
+		wantList []string
+		wantOut  string
+	}{
+		{
+			name:     "pop newest",
+			run:      func() { StashPop("", false) },
+			wantF:    "three\n",
+			wantList: []string{"two", "one"},
+			wantOut:  "Dropped stash@{0}",
+		},
+		{
+			name:     "apply keeps the entry",
+			run:      func() { StashApply("stash@{1}", false) },
+			wantF:    "two\n",
+			wantList: []string{"three", "two", "one"},
+			wantOut:  "Applied stash@{1}",
+		},
+		{
+			name:     "pop by index",
+			run:      func() { StashPop("2", false) },
+			wantF:    "one\n",
+			wantList: []string{"three", "two"},
+		},
+		{
+			name:     "drop middle",
+			run:      func() { StashDrop("stash@{1}") },
+			wantF:    "base\n",
+			wantList: []string{"three", "one"},
+		},
+		{
+			name:     "drop missing",
+			run:      func() { StashDrop("stash@{3}") },
+			wantF:    "base\n",
+			wantList: []string{"three", "two", "one"},
+			wantOut:  "stash@{3} does not exist",
+		},
+		{
+			name:     "apply missing",
+			run:      func() { StashApply("stash@{5}", false) },
+			wantF:    "base\n",
+			wantList: []string{"three", "two", "one"},
+			wantOut:  "stash@{5} does not exist",
+		},
+		{
+			name:    "clear",
+			run:     StashClear,
+			wantF:   "base\n",
+			wantOut: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stashThree(t)
+			out := captureOutput(t, tt.run)
+			if !strings.Contains(out, tt.wantOut) {
+				t.Errorf("output does not mention %q:\n%s", tt.wantOut, out)
+			}
+			if data, _ := ioutil.ReadFile("f"); string(data) != tt.wantF {
+				t.Errorf("f is %q, want %q", data, tt.wantF)
+			}
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(captureOutput(t, StashList)), "\n") {
+				if _, msg, ok := strings.Cut(line, ": On master: "); ok {
+					got = append(got, msg)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.wantList) {
+				t.Errorf("stash list is %q, want %q", got, tt.wantList)
+			}
+		})
+	}
+}
+
+func TestStashPushPaths(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "base", map[string]string{"a": "1\n", "dir/b": "1\n", "c": "1\n"})
+	writeFiles(t, map[string]string{"a": "2\n", "dir/b": "2\n", "c": "2\n"})
+
+	captureOutput(t, func() { StashPush("", []string{"a", "dir"}, false, false) })
+	want := map[string]string{"a": "1\n", "dir/b": "1\n", "c": "2\n"}
+	for f, data := range want {
+		if got, _ := ioutil.ReadFile(f); string(got) != data {
+			t.Errorf("%s is %q after push, want %q", f, got, data)
+		}
+	}
+	if out := captureOutput(t, func() { StashShow("", false) }); !strings.Contains(out, " a | 2 +-") || !strings.Contains(out, " dir/b | 2 +-") || strings.Contains(out, " c ") {
+		t.Errorf("stash show lists\n%s\nwant only a and dir/b", out)
+	}
+}
+
+func TestStashApplyIndex(t *testing.T) {
+	tests := []struct {
+		name         string
+		restoreIndex bool
+		wantStaged   bool
+	}{
+		{"working tree only", false, false},
+		{"with --index", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			commitFiles(t, "base", map[string]string{"f": "1\n", "g": "1\n"})
+			writeFiles(t, map[string]string{"f": "staged\n", "g": "unstaged\n"})
+			captureOutput(t, func() { Add("f") })
+			captureOutput(t, func() { StashPush("", nil, false, false) })
+			if len(changedFiles(commitTree(headCommitID()), stagedTree())) != 0 {
+				t.Fatal("push left staged changes behind")
+			}
+
+			captureOutput(t, func() { StashPop("", tt.restoreIndex) })
+			for f, data := range map[string]string{"f": "staged\n", "g": "unstaged\n"} {
+				if got, _ := ioutil.ReadFile(f); string(got) != data {
+					t.Errorf("%s is %q, want %q", f, got, data)
+				}
+			}
+			staged := changedFiles(commitTree(headCommitID()), stagedTree())
+			if got := reflect.DeepEqual(staged, []string{"f"}); got != tt.wantStaged {
+				t.Errorf("staged files are %q, want f staged = %v", staged, tt.wantStaged)
+			}
+		})
+	}
+}
+
+func TestStashApplyConflict(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "base", map[string]string{"f": "1\n"})
+	writeFiles(t, map[string]string{"f": "stashed\n"})
+	captureOutput(t, func() { StashPush("", nil, false, false) })
+	commitFiles(t, "change f", map[string]string{"f": "committed\n"})
+
+	out := captureOutput(t, func() { StashPop("", false) })
+	if !strings.Contains(out, "CONFLICT (content): Merge conflict in f") {
+		t.Errorf("pop did not report the conflict:\n%s", out)
+	}
+	if data, _ := ioutil.ReadFile("f"); !strings.Contains(string(data), "<<<<<<< Updated upstream") {
+		t.Errorf("f has no conflict markers:\n%s", data)
+	}
+	if list := captureOutput(t, StashList); !strings.HasPrefix(list, "stash@{0}: WIP on master") {
+		t.Errorf("the conflicted entry was dropped; stash list:\n%s", list)
+	}
+}
@@ -0,0 +1,131 @@
+package regit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StatsOptions selects the commits Stats reports on, as for log. Top is how
+// many of the most often changed files to list.
+type StatsOptions struct {
+	Revs         []string
+	All          bool
+	Since, Until string
+	Paths        []string
+	Top          int
+}
+
+// authorStats is one author's share of the selected history.
+type authorStats struct {
+	Name           string
+	Commits        int
+	Added, Removed int
+}
+
+// statsBar draws n out of max as a bar of at most 40 "#".
+func statsBar(n, max int) string {
+	if max == 0 {
+		return ""
+	}
+	return strings.Repeat("#", (n*40+max-1)/max)
+}
+
+// Stats reports, for the selected commits, the lines each author added
+// and removed, when in the week and day (in the author's time zone) they
+// were committed, and the files changed most often. Merges count as commits
+// but their changes are left to the commits they merge.
+func Stats(opts StatsOptions) {
+	commits, err := queryLog(LogOptions{
+		Revs: opts.Revs, All: opts.All, Since: opts.Since, Until: opts.Until,
+		Paths: opts.Paths,
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if len(commits) == 0 {
+		fmt.Println("No commits selected")
+		return
+	}
+	match := pathMatcher(opts.Paths)
+	authors := map[string]*authorStats{}
+	var weekdays [7]int
+	var hours [24]int
+	changes := map[string]int{}
+	for _, c := range commits {
+		name := commitAuthor(c).String()
+		a := authors[name]
+		if a == nil {
+			a = &authorStats{Name: name}
+			authors[name] = a
+		}
+		a.Commits++
+		if date, err := time.Parse(time.RFC3339, c.Date); err == nil {
+			weekdays[date.Weekday()]++
+			hours[date.Hour()]++
+		}
+		if len(c.Parents) > 1 {
+			continue
+		}
+		parent := map[string]string{}
+		if len(c.Parents) == 1 {
+			parent = commitTree(c.Parents[0])
+		}
+		for _, f := range changedFiles(parent, c.Files) {
+			if !match(f) {
+				continue
+			}
+			added, removed := diffStat(parent[f], c.Files[f])
+			a.Added += added
+			a.Removed += removed
+			changes[f]++
+		}
+	}
+
+	var list []*authorStats
+	for _, a := range authors {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Commits != list[j].Commits {
+			return list[i].Commits > list[j].Commits
+		}
+		return list[i].Name < list[j].Name
+	})
+	fmt.Println("Authors:")
+	fmt.Printf("  %7s %8s %8s  %s\n", "commits", "added", "removed", "author")
+	for _, a := range list {
+		fmt.Printf("  %7d %8s %8s  %s\n", a.Commits, fmt.Sprintf("+%d", a.Added), fmt.Sprintf("-%d", a.Removed), a.Name)
+	}
+
+	fmt.Println("\nCommits by weekday:")
+	most := 0
+	for _, n := range weekdays {
+		most = max(most, n)
+	}
+	for i := 1; i <= 7; i++ {
+		day := time.Weekday(i % 7)
+		fmt.Println(strings.TrimRight(fmt.Sprintf("  %s %5d %s", day.String()[:3], weekdays[day], statsBar(weekdays[day], most)), " "))
+	}
+
+	fmt.Println("\nCommits by hour:")
+	most = 0
+	for _, n := range hours {
+		most = max(most, n)
+	}
+	for h, n := range hours {
+		fmt.Println(strings.TrimRight(fmt.Sprintf("  %02d  %5d %s", h, n, statsBar(n, most)), " "))
+	}
+
+	files := sortedKeys(changes)
+	sort.SliceStable(files, func(i, j int) bool { return changes[files[i]] > changes[files[j]] })
+	if opts.Top > 0 && len(files) > opts.Top {
+		files = files[:opts.Top]
+	}
+	fmt.Println("\nMost changed files:")
+	for _, f := range files {
+		fmt.Printf("  %5d  %s\n", changes[f], f)
+	}
+}
@@ -0,0 +1,148 @@
+package regit
+
+import (
+	"strings"
+	"testing"
+)
+
+// statsHistory makes three commits with known line changes, authored on a
+// Monday, a Tuesday (in UTC+2) and a Saturday (in UTC-5).
+func statsHistory(t *testing.T) {
+	t.Helper()
+	testRepo(t)
+	for _, c := range []struct {
+		message, author, date string
+		files                 map[string]string
+	}{
+		{"one", "Alice <alice@example.com>", "2024-01-01T09:30:00Z", map[string]string{"a": "1\n2\n3\n"}},
+		{"two", "Bob <bob@example.com>", "2024-01-02T14:00:00+02:00", map[string]string{"a": "1\nTWO\n3\n", "b": "x\n"}},
+		{"three", "Alice <alice@example.com>", "2024-01-06T09:00:00-05:00", map[string]string{"a": "1\nTWO\n"}},
+	} {
+		writeFiles(t, c.files)
+		out := captureOutput(t, func() {
+			for f := range c.files {
+				Add(f)
+			}
+			Commit(c.message, nil, CommitOptions{Author: c.author, Date: c.date})
+		})
+		if commitSubject(headCommitID()) != c.message {
+			t.Fatalf("commit %q was not made:\n%s", c.message, out)
+		}
+	}
+}
+
+func TestStatsBar(t *testing.T) {
+	tests := []struct {
+		n, max int
+		want   int
+	}{
+		{0, 0, 0},
+		{0, 5, 0},
+		{5, 5, 40},
+		{1, 3, 14},
+		{1, 1000, 1},
+	}
+	for _, tt := range tests {
+		if got := statsBar(tt.n, tt.max); got != strings.Repeat("#", tt.want) {
+			t.Errorf("statsBar(%d, %d) = %q, want %d #", tt.n, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	statsHistory(t)
+	bar := strings.Repeat("#", 40)
+	tests := []struct {
+		name    string
+		opts    StatsOptions
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "whole history",
+			want: []string{
+				"Authors:\n  commits    added  removed  author\n" +
+					"        2       +3       -1  Alice <alice@example.com>\n" +
+					"        1       +2       -1  Bob <bob@example.com>\n",
+				"\nCommits by weekday:\n  Mon     1 " + bar + "\n  Tue     1 " + bar + "\n  Wed     0\n",
+				"  Sat     1 " + bar + "\n  Sun     0\n",
+				"  09      2 " + bar + "\n",
+				"  14      1 " + strings.Repeat("#", 20) + "\n",
+				"\nMost changed files:\n      3  a\n      1  b\n",
+			},
+		},
+		{
+			name:    "top",
+			opts:    StatsOptions{Top: 1},
+			want:    []string{"\nMost changed files:\n      3  a\n"},
+			notWant: []string{"  b\n"},
+		},
+		{
+			name: "paths",
+			opts: StatsOptions{Paths: []string{"b"}},
+			want: []string{
+				"        1       +1       -0  Bob <bob@example.com>\n",
+				"\nMost changed files:\n      1  b\n",
+			},
+			notWant: []string{"Alice"},
+		},
+		{
+			name: "range",
+			opts: StatsOptions{Revs: []string{"HEAD~1..HEAD"}},
+			want: []string{
+				"        1       +0       -1  Alice <alice@example.com>\n",
+				"  Sat     1 " + bar + "\n",
+			},
+			notWant: []string{"Bob"},
+		},
+		{
+			name: "nothing selected",
+			opts: StatsOptions{Until: "2000-01-01"},
+			want: []string{"No commits selected\n"},
+		},
+		{
+			name: "bad revision",
+			opts: StatsOptions{Revs: []string{"nope"}},
+			want: []string{"unknown revision: nope\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureOutput(t, func() { Stats(tt.opts) })
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("Stats output lacks %q:\n%s", w, got)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("Stats output has %q:\n%s", w, got)
+				}
+			}
+		})
+	}
+}
+
+func TestStatsSkipsMergeChanges(t *testing.T) {
+	testRepo(t)
+	base := commitFiles(t, "base", map[string]string{"f": "1\n"})
+	side := commitFiles(t, "side", map[string]string{"g": "1\n2\n"})
+	merge := commitEntry{Parents: []string{base, side}, Message: "merge", Files: commitTree(side)}
+	merge.Files["h"] = merge.Files["g"]
+	if err := fillIdentity(&merge); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCommit(&merge); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateRef(headRef(), merge.ID, "merge"); err != nil {
+		t.Fatal(err)
+	}
+	got := captureOutput(t, func() { Stats(StatsOptions{}) })
+	if !strings.Contains(got, "        3       +3       -0  Test User <test@example.com>\n") {
+		t.Errorf("merge changes were counted:\n%s", got)
+	}
+	if strings.Contains(got, "  h\n") {
+		t.Errorf("a file only the merge added is listed:\n%s", got)
+	}
+}
@@ -0,0 +1,280 @@
+package regit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A lightweight tag is a ref under refs/tags holding a commit ID. An
+// annotated tag's ref holds the ID of a tag object instead, stored with the
+// other objects:
+//
+//	object <commit id>
+//	type commit
+//	tag <name>
+//	tagger Name <email> <unix time> <zone>
+//	signature ed25519 <public key> <signature>   (signed tags only)
+//
+//	<message>
+type tagObject struct {
+	ID        string
+	Object    string
+	Name      string
+	Tagger    string
+	Time      time.Time
+	Signature string
+	Message   string
+}
+
+type TagOptions struct {
+	Message  string
+	Annotate bool
+	Force    bool
+	Sign     bool
+}
+
+const tagDateFormat = "Mon Jan 2 15:04:05 2006 -0700"
+
+func formatTag(t tagObject) string {
+	header := fmt.Sprintf("object %s\ntype commit\ntag %s\ntagger %s %d %s\n",
+		t.Object, t.Name, t.Tagger, t.Time.Unix(), t.Time.Format("-0700"))
+	if t.Signature != "" {
+		header += "signature " + t.Signature + "\n"
+	}
+	return header + "\n" + t.Message + "\n"
+}
+
+// readTag loads the tag object oid, reporting false if oid is not one.
+func readTag(oid string) (tagObject, bool) {
+	data, err := readObject(oid)
+	if err != nil || !strings.HasPrefix(string(data), "object ") {
+		return tagObject{}, false
+	}
+	header, message, _ := strings.Cut(string(data), "\n\n")
+	t := tagObject{ID: oid, Message: strings.TrimRight(message, "\n")}
+	for _, line := range strings.Split(header, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "object":
+			t.Object = value
+		case "tag":
+			t.Name = value
+		case "signature":
+			t.Signature = value
+		case "tagger":
+			fields := strings.Fields(value)
+			if len(fields) < 3 {
+				return tagObject{}, false
+			}
+			unix, _ := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+			t.Time = time.Unix(unix, 0)
+			if zone, err := time.Parse("-0700", fields[len(fields)-1]); err == nil {
+				t.Time = t.Time.In(zone.Location())
+			}
+			t.Tagger = strings.Join(fields[:len(fields)-2], " ")
+		}
+	}
+	if !isObjectID(t.Object) || t.Name == "" {
+		return tagObject{}, false
+	}
+	return t, true
+}
+
+// peelTag returns the commit an annotated tag object points at, or id
+// unchanged when it is not a tag object.
+func peelTag(id string) string {
+	if t, ok := readTag(id); ok {
+		return t.Object
+	}
+	return id
+}
+
+func validTagName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "-") || strings.HasSuffix(name, "/") || strings.Contains(name, "..") || strings.Contains(name, "@{") {
+		return false
+	}
+	return !strings.ContainsAny(name, " \t~^:?*[\\")
+}
+
+// tagNames lists every tag, including ones nested in directories such as
+// release/v1.
+func tagNames() []string {
+	var names []string
+	filepath.Walk(tagsDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			name, _ := filepath.Rel(tagsDir, path)
+			names = append(names, filepath.ToSlash(name))
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names
+}
+
+func TagExists(name string) bool {
+	return isObjectID(readRef("refs/tags/" + name))
+}
+
+// Tag creates a tag at rev. A message (or Annotate, which asks for one in
+// the editor) makes an annotated tag; signing one implies it.
+func Tag(name, rev string, opts TagOptions) {
+	if !validTagName(name) {
+		fmt.Println("Invalid tag name:", name)
+		return
+	}
+	if TagExists(name) && !opts.Force {
+		fmt.Println("Tag already exists:", name)
+		return
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	id, err := resolveRevision(rev)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if _, ok := loadCommit(id); !ok {
+		fmt.Println(rev, "is not a commit")
+		return
+	}
+	sign := opts.Sign || (opts.Annotate || opts.Message != "") && configBool("tag.sign", false)
+	message := opts.Message
+	if (opts.Annotate || sign) && message == "" {
+		message, err = editMessage("\n# Write a message for tag:\n#   " + name + "\n# Lines starting with '#' will be ignored.")
+		if err != nil {
+			fmt.Println("Error creating tag:", err)
+			return
+		}
+	}
+	target := id
+	if message != "" {
+		tagger, err := roleIdentity("COMMITTER")
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		t := tagObject{Object: id, Name: name, Tagger: tagger.String(), Time: time.Now(), Message: strings.TrimSpace(message)}
+		if sign {
+			if t.Signature, err = signPayload(formatTag(t)); err != nil {
+				fmt.Println("Error signing tag:", err)
+				return
+			}
+		}
+		if target, err = writeObject([]byte(formatTag(t))); err != nil {
+			fmt.Println("Error creating tag:", err)
+			return
+		}
+	}
+	path := filepath.Join(tagsDir, name)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := ioutil.WriteFile(path, []byte(target+"\n"), 0644); err != nil {
+		fmt.Println("Error creating tag:", name)
+		return
+	}
+	fmt.Println("Created tag:", name)
+}
+
+// ListTags prints the tags matching any of patterns (all when none are
+// given). With lines > 0 each name is followed by that many lines of its
+// annotation, or of the commit message for a lightweight tag.
+func ListTags(patterns []string, lines int) {
+	for _, name := range tagNames() {
+		matched := len(patterns) == 0
+		for _, p := range patterns {
+			if ok, _ := filepath.Match(p, name); ok {
+				matched = true
+			}
+		}
+		if !matched {
+			continue
+		}
+		if lines <= 0 {
+			fmt.Println(name)
+			continue
+		}
+		id := readRef("refs/tags/" + name)
+		message := ""
+		if t, ok := readTag(id); ok {
+			message = t.Message
+		} else if c, ok := loadCommit(id); ok {
+			message = c.Message
+		}
+		text := strings.Split(message, "\n")
+		if len(text) > lines {
+			text = text[:lines]
+		}
+		for len(text) > 1 && strings.TrimSpace(text[len(text)-1]) == "" {
+			text = text[:len(text)-1]
+		}
+		fmt.Printf("%-15s %s\n", name, strings.Join(text, "\n                "))
+	}
+}
+
+// DeleteTag removes the tag name. Names a tag could not have are refused,
+// so they cannot reach refs outside refs/tags.
+func DeleteTag(name string) {
+	if !validTagName(name) {
+		fmt.Println("Invalid tag name:", name)
+		return
+	}
+	if !TagExists(name) {
+		fmt.Printf("tag '%s' not found\n", name)
+		return
+	}
+	id := readRef("refs/tags/" + name)
+	if err := os.Remove(filepath.Join(tagsDir, name)); err != nil {
+		fmt.Println("Error deleting tag:", name)
+		return
+	}
+	for dir := filepath.Dir(filepath.Join(tagsDir, name)); dir != filepath.Clean(tagsDir); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	fmt.Printf("Deleted tag '%s' (was %s)\n", name, shortID(id))
+}
+
+// ShowTag prints an annotated tag's header and message followed by the
+// commit it points at.
+func ShowTag(name string) {
+	id := readRef("refs/tags/" + name)
+	if !isObjectID(id) {
+		fmt.Println("Tag not found:", name)
+		return
+	}
+	if t, ok := readTag(id); ok {
+		fmt.Println("tag", t.Name)
+		fmt.Println("Tagger:", t.Tagger)
+		fmt.Println("Date:  ", t.Time.Format(tagDateFormat))
+		fmt.Println()
+		fmt.Println(t.Message)
+		fmt.Println()
+		id = t.Object
+	}
+	c, ok := loadCommit(id)
+	if !ok {
+		fmt.Println("Tag", name, "does not point at a commit")
+		return
+	}
+	fmt.Println("commit", c.ID)
+	fmt.Println("Date:", c.Date)
+	fmt.Println()
+	for _, line := range strings.Split(c.Message, "\n") {
+		fmt.Println("    " + line)
+	}
+	var parentTree map[string]string
+	if len(c.Parents) > 0 {
+		parentTree = commitTree(c.Parents[0])
+	}
+	if diff := treeDiff(parentTree, c.Files); diff != "" {
+		fmt.Println()
+		fmt.Print(diff)
+	}
+}
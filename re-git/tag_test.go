@@ -0,0 +1,219 @@
+package regit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadTag(t *testing.T) {
+	testRepo(t)
+	commit := commitFiles(t, "base", map[string]string{"f": "1\n"})
+	when := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("", 2*3600))
+	tests := []struct {
+		name   string
+		data   string
+		want   tagObject
+		wantOK bool
+	}{
+		{
+			name:   "annotated",
+			data:   formatTag(tagObject{Object: commit, Name: "v1", Tagger: "A U Thor <a@example.com>", Time: when, Message: "Release 1\n\nNotes."}),
+			want:   tagObject{Object: commit, Name: "v1", Tagger: "A U Thor <a@example.com>", Time: when, Message: "Release 1\n\nNotes."},
+			wantOK: true,
+		},
+		{
+			name:   "signed",
+			data:   formatTag(tagObject{Object: commit, Name: "v2", Tagger: "T <t@x>", Time: when, Signature: "ed25519 key sig", Message: "two"}),
+			want:   tagObject{Object: commit, Name: "v2", Tagger: "T <t@x>", Time: when, Signature: "ed25519 key sig", Message: "two"},
+			wantOK: true,
+		},
+		{name: "file contents", data: "hello\n"},
+		{name: "bad object id", data: "object nope\ntype commit\ntag v1\ntagger T <t@x> 0 +0000\n\nmsg\n"},
+		{name: "no name", data: "object " + commit + "\ntype commit\ntagger T <t@x> 0 +0000\n\nmsg\n"},
+		{name: "short tagger", data: "object " + commit + "\ntype commit\ntag v1\ntagger 0 +0000\n\nmsg\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oid, err := writeObject([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := readTag(oid)
+			if ok != tt.wantOK {
+				t.Fatalf("readTag ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			tt.want.ID = oid
+			if !got.Time.Equal(tt.want.Time) || got.Time.Format("-0700") != "+0200" {
+				t.Errorf("time is %v, want %v", got.Time, tt.want.Time)
+			}
+			got.Time = tt.want.Time
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readTag = %+v, want %+v", got, tt.want)
+			}
+			if peelTag(oid) != commit {
+				t.Errorf("peelTag did not return the tagged commit")
+			}
+		})
+	}
+}
+
+func TestValidTagName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"v1.0", true},
+		{"release/v1", true},
+		{"", false},
+		{"-v1", false},
+		{"dir/", false},
+		{"a..b", false},
+		{"v1@{0}", false},
+		{"with space", false},
+		{"v1~1", false},
+		{"v1^", false},
+		{"a:b", false},
+		{"v?", false},
+		{"v*", false},
+		{"v[1]", false},
+		{`a\b`, false},
+	}
+	for _, tt := range tests {
+		if got := validTagName(tt.name); got != tt.want {
+			t.Errorf("validTagName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTag(t *testing.T) {
+	tests := []struct {
+		name          string
+		tag, rev      string
+		opts          TagOptions
+		wantOut       string
+		wantAnnotated bool
+		wantTarget    string // subject of the tagged commit
+	}{
+		{name: "lightweight", tag: "v1", wantOut: "Created tag: v1", wantTarget: "second"},
+		{name: "at a revision", tag: "v1", rev: "HEAD~1", wantOut: "Created tag: v1", wantTarget: "first"},
+		{name: "nested", tag: "release/v1", wantOut: "Created tag: release/v1", wantTarget: "second"},
+		{name: "annotated", tag: "v1", opts: TagOptions{Message: "Release"}, wantOut: "Created tag: v1", wantAnnotated: true, wantTarget: "second"},
+		{name: "exists", tag: "old", wantOut: "Tag already exists: old", wantTarget: "first"},
+		{name: "force", tag: "old", opts: TagOptions{Force: true}, wantOut: "Created tag: old", wantTarget: "second"},
+		{name: "invalid name", tag: "a..b", wantOut: "Invalid tag name: a..b"},
+		{name: "bad revision", tag: "v1", rev: "nope", wantOut: "nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			commitFiles(t, "first", map[string]string{"f": "1\n"})
+			captureOutput(t, func() { Tag("old", "", TagOptions{}) })
+			commitFiles(t, "second", map[string]string{"f": "2\n"})
+
+			out := captureOutput(t, func() { Tag(tt.tag, tt.rev, tt.opts) })
+			if !strings.Contains(out, tt.wantOut) {
+				t.Errorf("output is %q, want it to mention %q", out, tt.wantOut)
+			}
+			if tt.wantTarget == "" {
+				if TagExists(tt.tag) {
+					t.Errorf("tag %s was created", tt.tag)
+				}
+				return
+			}
+			ref := readRef("refs/tags/" + tt.tag)
+			tag, annotated := readTag(ref)
+			if annotated != tt.wantAnnotated {
+				t.Errorf("annotated = %v, want %v", annotated, tt.wantAnnotated)
+			}
+			if annotated && (tag.Name != tt.tag || tag.Message != tt.opts.Message || tag.Tagger != "Test User <test@example.com>") {
+				t.Errorf("tag object is %+v", tag)
+			}
+			id, err := resolveRevision(tt.tag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := commitSubject(id); got != tt.wantTarget {
+				t.Errorf("%s resolves to %q, want %q", tt.tag, got, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestListTags(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "first", map[string]string{"f": "1\n"})
+	for _, name := range []string{"v1.0", "v1.1", "release/v2"} {
+		captureOutput(t, func() { Tag(name, "", TagOptions{}) })
+	}
+	captureOutput(t, func() { Tag("v2.0", "", TagOptions{Message: "Two\nsecond line\nthird line"}) })
+
+	tests := []struct {
+		name     string
+		patterns []string
+		lines    int
+		want     string
+	}{
+		{name: "all", want: "release/v2\nv1.0\nv1.1\nv2.0\n"},
+		{name: "pattern", patterns: []string{"v1.*"}, want: "v1.0\nv1.1\n"},
+		{name: "several patterns", patterns: []string{"v2*", "release/*"}, want: "release/v2\nv2.0\n"},
+		{name: "no match", patterns: []string{"x*"}, want: ""},
+		{
+			name:     "one line",
+			patterns: []string{"v1.0", "v2.0"},
+			lines:    1,
+			want:     "v1.0            first\nv2.0            Two\n",
+		},
+		{
+			name:     "annotation lines",
+			patterns: []string{"v2.0"},
+			lines:    2,
+			want:     "v2.0            Two\n                second line\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := captureOutput(t, func() { ListTags(tt.patterns, tt.lines) }); got != tt.want {
+				t.Errorf("ListTags printed\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteTag(t *testing.T) {
+	testRepo(t)
+	commitFiles(t, "first", map[string]string{"f": "1\n"})
+	captureOutput(t, func() { Tag("release/v1", "", TagOptions{}) })
+
+	out := captureOutput(t, func() { DeleteTag("release/v1") })
+	if !strings.HasPrefix(out, "Deleted tag 'release/v1' (was ") {
+		t.Errorf("DeleteTag printed %q", out)
+	}
+	if TagExists("release/v1") {
+		t.Error("tag still exists")
+	}
+	if _, err := os.Stat(filepath.Join(tagsDir, "release")); err == nil {
+		t.Error("empty tag directory was left behind")
+	}
+
+	captureOutput(t, func() { Tag("release/v2", "", TagOptions{}) })
+	for _, tt := range []struct{ name, want string }{
+		{"missing", "tag 'missing' not found\n"},
+		{"release", "tag 'release' not found\n"},
+		{"../heads/master", "Invalid tag name: ../heads/master\n"},
+		{"release/../../heads/master", "Invalid tag name: release/../../heads/master\n"},
+	} {
+		if out := captureOutput(t, func() { DeleteTag(tt.name) }); out != tt.want {
+			t.Errorf("DeleteTag(%q) printed %q, want %q", tt.name, out, tt.want)
+		}
+	}
+	if readRef("refs/heads/master") == "" || !TagExists("release/v2") {
+		t.Error("deleting a bad tag name removed another ref")
+	}
+}
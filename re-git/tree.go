@@ -0,0 +1,163 @@
+package regit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A tree maps file paths to blob object IDs. Every commit records the full
+// tree; the index only holds staged changes on top of HEAD's tree, with
+// nullID marking a staged deletion.
+
+func hashObject(data []byte) string {
+	hash := sha1.Sum(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func writeObject(data []byte) (string, error) {
+	oid := hashObject(data)
+	err := ioutil.WriteFile(filepath.Join(objectsDir, oid), data, 0644)
+	return oid, err
+}
+
+func readObject(oid string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(objectsDir, oid))
+}
+
+func commitTree(id string) map[string]string {
+	tree := map[string]string{}
+	if c, ok := loadCommit(id); ok {
+		for f, oid := range c.Files {
+			tree[f] = oid
+		}
+	}
+	return tree
+}
+
+func readIndex() map[string]string {
+	index := map[string]string{}
+	data, _ := ioutil.ReadFile(indexFile)
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.Split(line, " ")
+		if len(parts) == 2 {
+			index[parts[0]] = parts[1]
+		}
+	}
+	return index
+}
+
+func writeIndex(index map[string]string) error {
+	files := make([]string, 0, len(index))
+	for f := range index {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	var b strings.Builder
+	for _, f := range files {
+		b.WriteString(f + " " + index[f] + "\n")
+	}
+	return ioutil.WriteFile(indexFile, []byte(b.String()), 0644)
+}
+
+// stagedTree is the tree the next commit would record: HEAD's tree with the
+// index applied.
+func stagedTree() map[string]string {
+	tree := commitTree(headCommitID())
+	for f, oid := range readIndex() {
+		if oid == nullID {
+			delete(tree, f)
+		} else {
+			tree[f] = oid
+		}
+	}
+	return tree
+}
+
+// indexFor returns the index that stages tree on top of base.
+func indexFor(base, tree map[string]string) map[string]string {
+	index := map[string]string{}
+	for f, oid := range tree {
+		if base[f] != oid {
+			index[f] = oid
+		}
+	}
+	for f := range base {
+		if _, ok := tree[f]; !ok {
+			index[f] = nullID
+		}
+	}
+	return index
+}
+
+// workingOid hashes the working copy of file without storing it, returning
+// "" if the file does not exist.
+func workingOid(file string) string {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return hashObject(data)
+}
+
+// checkoutTree makes the working tree match to, removing files that were
+// tracked in from but are not part of to.
+func checkoutTree(from, to map[string]string) error {
+	for f := range from {
+		if _, ok := to[f]; !ok {
+			os.Remove(f)
+		}
+	}
+	for f, oid := range to {
+		if workingOid(f) == oid {
+			continue
+		}
+		data, err := readObject(oid)
+		if err != nil {
+			return err
+		}
+		if dir := filepath.Dir(f); dir != "." {
+			os.MkdirAll(dir, 0755)
+		}
+		if err := ioutil.WriteFile(f, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTreeChange updates only the working files whose blob differs between
+// from and to, leaving everything else (including local edits) alone.
+func applyTreeChange(from, to map[string]string) error {
+	changedFrom, changedTo := map[string]string{}, map[string]string{}
+	for _, f := range changedFiles(from, to) {
+		if oid, ok := from[f]; ok {
+			changedFrom[f] = oid
+		}
+		if oid, ok := to[f]; ok {
+			changedTo[f] = oid
+		}
+	}
+	return checkoutTree(changedFrom, changedTo)
+}
+
+// localChanges lists tracked files whose working copy differs from HEAD,
+// plus everything staged.
+func localChanges() []string {
+	var files []string
+	index := readIndex()
+	for f := range index {
+		files = append(files, f)
+	}
+	for f, oid := range commitTree(headCommitID()) {
+		if _, staged := index[f]; !staged && workingOid(f) != oid {
+			files = append(files, f)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
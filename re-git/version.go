@@ -3,38 +3,20 @@ package regit
 import (
 	"fmt"
 	"io/ioutil"
-	"os"
-	"path/filepath"
-	"strings"
 )
 
 func GetFileVersion(file string, commitIdx int) {
-	log, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		fmt.Println("Error reading log")
-		return
-	}
-	entries := strings.Split(string(log), "---\n")
-	if commitIdx < 0 || commitIdx >= len(entries)-1 {
+	c, ok := commitAt(commitIdx)
+	if !ok {
 		fmt.Println("Invalid commit index")
 		return
 	}
-	entry := entries[commitIdx]
-	lines := strings.Split(entry, "\n")
-	var oid string
-	for _, line := range lines {
-		parts := strings.Split(line, " ")
-		if len(parts) == 2 && parts[0] == file {
-			oid = parts[1]
-			break
-		}
-	}
-	if oid == "" {
+	oid, ok := c.Files[file]
+	if !ok {
 		fmt.Println("File not found in commit")
 		return
 	}
-	objPath := filepath.Join(objectsDir, oid)
-	data, err := ioutil.ReadFile(objPath)
+	data, err := readObject(oid)
 	if err != nil {
 		fmt.Println("Object not found")
 		return
@@ -43,32 +25,17 @@ func GetFileVersion(file string, commitIdx int) {
 }
 
 func RestoreFileFromCommit(file string, commitIdx int) {
-	log, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		fmt.Println("Error reading log")
-		return
-	}
-	entries := strings.Split(string(log), "---\n")
-	if commitIdx < 0 || commitIdx >= len(entries)-1 {
+	c, ok := commitAt(commitIdx)
+	if !ok {
 		fmt.Println("Invalid commit index")
 		return
 	}
-	entry := entries[commitIdx]
-	lines := strings.Split(entry, "\n")
-	var oid string
-	for _, line := range lines {
-		parts := strings.Split(line, " ")
-		if len(parts) == 2 && parts[0] == file {
-			oid = parts[1]
-			break
-		}
-	}
-	if oid == "" {
+	oid, ok := c.Files[file]
+	if !ok {
 		fmt.Println("File not found in commit")
 		return
 	}
-	objPath := filepath.Join(objectsDir, oid)
-	data, err := ioutil.ReadFile(objPath)
+	data, err := readObject(oid)
 	if err != nil {
 		fmt.Println("Object not found")
 		return
@@ -81,50 +48,44 @@ func RestoreFileFromCommit(file string, commitIdx int) {
 	fmt.Printf("Restored %s from commit %d\n", file, commitIdx)
 }
 
-func Revert(commitIdx int) {
-	log, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		fmt.Println("Error reading log")
-		return
+// Revert creates, for each revision or range, a commit undoing the changes
+// it introduced, newest first. With noCommit the changes are only applied to
+// the index and working tree.
+func Revert(revs []string, noCommit bool) {
+	var ids []string
+	for _, rev := range revs {
+		commits, err := resolveCommits(rev)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		ids = append(ids, commits...)
 	}
-	entries := strings.Split(string(log), "---\n")
-	if commitIdx < 0 || commitIdx >= len(entries)-1 {
-		fmt.Println("Invalid commit index")
+	if len(ids) == 0 {
+		fmt.Println("Nothing to revert")
 		return
 	}
-	entry := entries[commitIdx]
-	lines := strings.Split(entry, "\n")
-	for _, line := range lines {
-		parts := strings.Split(line, " ")
-		if len(parts) == 2 {
-			os.Remove(parts[0])
-		}
-	}
-	fmt.Println("Reverted commit", commitIdx)
+	startSequencer("revert", ids, sequencerOptions{NoCommit: noCommit})
 }
 
-func CherryPick(commitIdx int) {
-	log, err := ioutil.ReadFile(logFile)
-	if err != nil {
-		fmt.Println("Error reading log")
-		return
+// CherryPick applies the changes introduced by each revision or range on top
+// of HEAD, oldest first, creating a new commit for each. With recordOrigin
+// the message notes which commit was picked.
+func CherryPick(revs []string, noCommit, recordOrigin bool) {
+	var ids []string
+	for _, rev := range revs {
+		commits, err := resolveCommits(rev)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		for i := len(commits) - 1; i >= 0; i-- {
+			ids = append(ids, commits[i])
+		}
 	}
-	entries := strings.Split(string(log), "---\n")
-	if commitIdx < 0 || commitIdx >= len(entries)-1 {
-		fmt.Println("Invalid commit index")
+	if len(ids) == 0 {
+		fmt.Println("Nothing to cherry-pick")
 		return
 	}
-	entry := entries[commitIdx]
-	lines := strings.Split(entry, "\n")
-	for _, line := range lines {
-		parts := strings.Split(line, " ")
-		if len(parts) == 2 {
-			objPath := filepath.Join(objectsDir, parts[1])
-			data, err := ioutil.ReadFile(objPath)
-			if err == nil {
-				ioutil.WriteFile(parts[0], data, 0644)
-			}
-		}
-	}
-	fmt.Println("Cherry-picked commit", commitIdx)
+	startSequencer("pick", ids, sequencerOptions{NoCommit: noCommit, RecordOrigin: recordOrigin})
 }